@@ -0,0 +1,112 @@
+// Command apikey mints, revokes and lists the API keys of the HTTP server and shows
+// the audit log of which key triggered which scrape or upload.
+//
+//	go run ./cmd/apikey mint -name frontend -scopes read
+//	go run ./cmd/apikey mint -name ops -scopes read,scrape,upload
+//	go run ./cmd/apikey revoke -id 3
+//	go run ./cmd/apikey list
+//	go run ./cmd/apikey audit -limit 20
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"job_scraper/config"
+	"job_scraper/scraper/auth"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: apikey <mint|revoke|list|audit> [flags]")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	db, err := config.InitializeDatabase()
+	if err != nil {
+		log.Fatalf("❌ Failed to initialize the database: %v", err)
+	}
+	defer db.Close()
+
+	cmd, args := os.Args[1], os.Args[2:]
+	switch cmd {
+	case "mint":
+		fs := flag.NewFlagSet("mint", flag.ExitOnError)
+		name := fs.String("name", "", "who or what the key is for")
+		rawScopes := fs.String("scopes", auth.ScopeRead, "comma-separated scopes: read, scrape, upload, admin")
+		fs.Parse(args)
+		if *name == "" {
+			log.Fatal("❌ -name is required")
+		}
+		scopes, err := auth.ParseScopes(*rawScopes)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		token, key, err := auth.Mint(db, *name, scopes)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		fmt.Printf("🔑 Minted key %d for %q with scopes %s\n", key.ID, key.Name, strings.Join(key.Scopes, ","))
+		fmt.Println("   Store it now, it cannot be shown again:")
+		fmt.Println(token)
+
+	case "revoke":
+		fs := flag.NewFlagSet("revoke", flag.ExitOnError)
+		id := fs.Int64("id", 0, "id of the key to revoke (see list)")
+		fs.Parse(args)
+		if *id <= 0 {
+			log.Fatal("❌ -id is required")
+		}
+		if err := auth.Revoke(db, *id); err != nil {
+			log.Fatalf("❌ Failed to revoke key %d: %v", *id, err)
+		}
+		fmt.Printf("🗑️ Revoked key %d\n", *id)
+
+	case "list":
+		keys, err := auth.List(db)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME\tPREFIX\tSCOPES\tCREATED\tLAST USED\tREVOKED")
+		for _, k := range keys {
+			fmt.Fprintf(tw, "%d\t%s\t%s…\t%s\t%s\t%s\t%s\n",
+				k.ID, k.Name, k.Prefix, strings.Join(k.Scopes, ","), k.CreatedAt, orDash(k.LastUsedAt), orDash(k.RevokedAt))
+		}
+		tw.Flush()
+
+	case "audit":
+		fs := flag.NewFlagSet("audit", flag.ExitOnError)
+		limit := fs.Int("limit", 50, "entries to show, newest first")
+		fs.Parse(args)
+		entries, err := auth.AuditLog(db, *limit)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "TIME\tKEY\tSCOPE\tREQUEST\tSTATUS\tFROM")
+		for _, e := range entries {
+			fmt.Fprintf(tw, "%s\t%s (%d)\t%s\t%s %s\t%d\t%s\n",
+				e.CreatedAt, e.KeyName, e.KeyID, e.Scope, e.Method, e.Path, e.Status, e.RemoteAddr)
+		}
+		tw.Flush()
+
+	default:
+		usage()
+	}
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
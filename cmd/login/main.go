@@ -0,0 +1,107 @@
+// Command login opens a visible browser on a site's profile so a person can log in
+// once. When the session check passes it exports the cookies next to the profile,
+// after which the server can scrape with BROWSER_HEADLESS=true on the same profile
+// directory, or on a fresh one that only has the cookies file copied over.
+// Stop the server first: a profile can only be open in one browser at a time.
+//
+//	go run ./cmd/login -site linkedin
+//	go run ./cmd/login -site xing -timeout 15m
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/joho/godotenv"
+
+	"job_scraper/scraper/browser"
+)
+
+func main() {
+	name := flag.String("site", "linkedin", "site to log in to: linkedin or xing")
+	timeout := flag.Duration("timeout", 10*time.Minute, "how long to wait for the login to finish")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("⚠️ No .env file found, using process environment")
+	}
+
+	site, ok := browser.Sites[*name]
+	if !ok {
+		log.Fatalf("❌ Unknown site %q; use linkedin or xing", *name)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	m := browser.Default()
+	defer m.Shutdown()
+
+	b, err := m.Acquire(ctx, site.Profile, browser.Options{Visible: true})
+	if err != nil {
+		log.Fatalf("❌ Failed to start Chromium: %v", err)
+	}
+	defer b.Release()
+	if !b.Owned() || b.Headless {
+		log.Printf("⚠️ Profile %s is already open in another browser; if no window appears, stop the server and retry", site.Profile)
+	}
+
+	allocatorCtx, cancelAllocator := chromedp.NewRemoteAllocator(ctx, b.DebugURL())
+	defer cancelAllocator()
+	tabCtx, cancelTab := chromedp.NewContext(allocatorCtx)
+	defer cancelTab()
+
+	state, _, err := browser.CheckSession(tabCtx, site)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if state != browser.SessionValid {
+		if err := chromedp.Run(tabCtx, chromedp.Navigate(site.LoginURL)); err != nil {
+			log.Fatalf("❌ Failed to open the %s login: %v", site.Name, err)
+		}
+		fmt.Printf("🔑 Log in to %s in the browser window, including any security check; waiting up to %s\n", site.Name, *timeout)
+		if err := waitForLogin(tabCtx, site); err != nil {
+			log.Fatalf("❌ Login did not finish: %v", err)
+		}
+	}
+
+	// Confirm on the check page, the same way a run will
+	if state, landed, err := browser.CheckSession(tabCtx, site); err != nil || state != browser.SessionValid {
+		log.Fatalf("❌ %s session check failed: state %s on %s (%v)", site.Name, state, landed, err)
+	}
+
+	path := m.CookiesPath(site.Profile)
+	n, err := browser.SaveCookies(tabCtx, path)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	fmt.Printf("✅ Logged in to %s; profile %s and %d cookies saved to %s\n", site.Name, m.ProfileDir(site.Profile), n, path)
+}
+
+// waitForLogin polls the page the person is on until it is no longer a login or
+// checkpoint page
+func waitForLogin(ctx context.Context, site browser.Site) error {
+	for {
+		var at string
+		if err := chromedp.Run(ctx, chromedp.Location(&at)); err != nil {
+			return err
+		}
+		if site.Classify(at) == browser.SessionValid {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Command summarizer drains pending job descriptions through the LLM providers,
+// independently of the scraping server and its browser sessions.
+//
+//	go run ./cmd/summarizer -workers 4 -rate 60
+//	go run ./cmd/summarizer -poll 30s   # keep running and pick up new descriptions
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/joho/godotenv"
+
+	"job_scraper/config"
+	"job_scraper/scraper/skills"
+	"job_scraper/scraper/summary"
+)
+
+func main() {
+	opts := summary.DefaultOptions()
+	flag.IntVar(&opts.Workers, "workers", opts.Workers, "descriptions summarized concurrently")
+	flag.DurationVar(&opts.Timeout, "timeout", opts.Timeout, "timeout per description; claims older than this are taken over")
+	flag.IntVar(&opts.RatePerMinute, "rate", opts.RatePerMinute, "max LLM calls per minute across workers (0 = unlimited)")
+	flag.IntVar(&opts.MaxAttempts, "attempts", opts.MaxAttempts, "attempts before a description is marked failed")
+	flag.IntVar(&opts.BatchSize, "batch", opts.BatchSize, "rows fetched per query")
+	flag.DurationVar(&opts.PollInterval, "poll", 0, "poll for new descriptions at this interval instead of exiting when drained")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("⚠️ No .env file found, using process environment")
+	}
+
+	db, err := config.InitializeDatabase()
+	if err != nil {
+		log.Fatalf("❌ Failed to initialize the database: %v", err)
+	}
+	defer db.Close()
+
+	if err := skills.SeedTaxonomy(db); err != nil {
+		log.Fatalf("❌ Failed to seed skill taxonomy: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("🧠 Summarizer started with %d workers\n", opts.Workers)
+	stats, err := summary.RunPool(ctx, db, summary.DefaultSources(), opts)
+	if err != nil && err != context.Canceled {
+		log.Fatalf("❌ Summarizer stopped: %v", err)
+	}
+	fmt.Printf("✅ Done: %d summarized, %d retrying, %d failed\n", stats.Summarized, stats.Retrying, stats.Failed)
+}
@@ -0,0 +1,78 @@
+// Command uploader queues unsent jobs in the upload outbox and delivers the outbox to
+// the upload sinks, the same as POST /api/v1/uploads but without the server. With
+// -poll it keeps running, so deliveries that failed are retried until they succeed.
+//
+//	go run ./cmd/uploader -sinks mongo,file
+//	go run ./cmd/uploader -poll 5m
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"job_scraper/config"
+	"job_scraper/scraper"
+	"job_scraper/scraper/sink"
+)
+
+func main() {
+	sinks := flag.String("sinks", "", "comma-separated sinks (default UPLOAD_SINKS, or mongo)")
+	poll := flag.Duration("poll", 0, "run again at this interval instead of exiting after one upload")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("⚠️ No .env file found, using process environment")
+	}
+
+	names := sink.DefaultNames()
+	if *sinks != "" {
+		names = sink.ParseNames(*sinks, "")
+	}
+
+	db, err := config.InitializeDatabase()
+	if err != nil {
+		log.Fatalf("❌ Failed to initialize the database: %v", err)
+	}
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	opened, err := sink.FromEnv(ctx, names)
+	if err != nil {
+		log.Fatalf("❌ Failed to open upload sinks: %v", err)
+	}
+	defer sink.CloseAll(context.Background(), opened)
+
+	fmt.Printf("📤 Uploader started for %s\n", strings.Join(names, ", "))
+	for {
+		resp, err := scraper.Upload(ctx, db, opened)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("❌ Upload failed: %v", err)
+		}
+		for _, res := range resp.Sinks {
+			fmt.Printf("✅ %s: %d delivered, %d failed\n", res.Sink, res.Written, len(res.Failed))
+		}
+		if *poll <= 0 {
+			if err != nil {
+				os.Exit(1)
+			}
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(*poll):
+		}
+	}
+}
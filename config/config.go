@@ -10,7 +10,11 @@ import (
 
 // InitializeDatabase creates the SQLite database with LinkedIn and Xing tables
 func InitializeDatabase() (*sql.DB, error) {
-	dbFile := "JSE.db"
+	return InitializeDatabaseAt("JSE.db")
+}
+
+// InitializeDatabaseAt creates or migrates the database in dbFile
+func InitializeDatabaseAt(dbFile string) (*sql.DB, error) {
 	dbExists := fileExists(dbFile)
 
 	db, err := sql.Open("sqlite3", dbFile)
@@ -55,7 +59,10 @@ func InitializeDatabase() (*sql.DB, error) {
 			posted_date TEXT,
 			link TEXT UNIQUE,  
 			processed BOOLEAN,
-			sent BOOLEAN
+			sent BOOLEAN,
+			scraped_at TIMESTAMP,
+			processed_at TIMESTAMP,
+			updated_at TIMESTAMP
 		);`
 
 		createXingJobsTable := `
@@ -66,9 +73,14 @@ func InitializeDatabase() (*sql.DB, error) {
 			company TEXT,
 			location TEXT,
 			posted_date TEXT,
+			employment_type TEXT,
+			salary TEXT,
 			link TEXT UNIQUE,  
 			processed BOOLEAN,
-			sent BOOLEAN
+			sent BOOLEAN,
+			scraped_at TIMESTAMP,
+			processed_at TIMESTAMP,
+			updated_at TIMESTAMP
 		);`
 
 		createLinkedInJobApplicationLinksTable := `
@@ -76,6 +88,7 @@ func InitializeDatabase() (*sql.DB, error) {
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			job_id TEXT,
 			job_link TEXT UNIQUE,
+			updated_at TIMESTAMP,
 			FOREIGN KEY (job_id) REFERENCES linkedin_jobs(id) ON DELETE CASCADE
 		);`
 
@@ -84,6 +97,7 @@ func InitializeDatabase() (*sql.DB, error) {
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			job_id TEXT,
 			job_link TEXT,
+			updated_at TIMESTAMP,
 			UNIQUE(job_id, job_link),
 			FOREIGN KEY (job_id) REFERENCES xing_jobs(id) ON DELETE CASCADE
 		);`
@@ -96,6 +110,13 @@ func InitializeDatabase() (*sql.DB, error) {
 			job_description TEXT,
 			job_type TEXT,
 			skills TEXT,
+			raw_description TEXT,
+			summary_status TEXT NOT NULL DEFAULT 'done',
+			summary_attempts INTEGER NOT NULL DEFAULT 0,
+			summary_error TEXT,
+			summary_claimed_at TIMESTAMP,
+			summarized_at TIMESTAMP,
+			updated_at TIMESTAMP,
 			FOREIGN KEY (job_id) REFERENCES linkedin_job_application_links(id) ON DELETE CASCADE,
 			FOREIGN KEY (job_link) REFERENCES linkedin_job_application_links(job_link) ON DELETE CASCADE
 		);`
@@ -108,6 +129,13 @@ func InitializeDatabase() (*sql.DB, error) {
 			job_description TEXT,
 			job_type TEXT,
 			skills TEXT,
+			raw_description TEXT,
+			summary_status TEXT NOT NULL DEFAULT 'done',
+			summary_attempts INTEGER NOT NULL DEFAULT 0,
+			summary_error TEXT,
+			summary_claimed_at TIMESTAMP,
+			summarized_at TIMESTAMP,
+			updated_at TIMESTAMP,
 			FOREIGN KEY (job_id) REFERENCES xing_job_application_links(id) ON DELETE CASCADE,
 			FOREIGN KEY (job_link) REFERENCES xing_job_application_links(job_link) ON DELETE CASCADE
 		);`
@@ -127,6 +155,9 @@ func InitializeDatabase() (*sql.DB, error) {
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		job_id TEXT,
 		job_link TEXT UNIQUE,
+		reason TEXT,
+		failure_count INTEGER NOT NULL DEFAULT 1,
+		failed_at TIMESTAMP,
 		FOREIGN KEY (job_id) REFERENCES linkedin_jobs(id) ON DELETE CASCADE
 	);`
 
@@ -135,10 +166,163 @@ func InitializeDatabase() (*sql.DB, error) {
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		job_id TEXT,
 		job_link TEXT UNIQUE,
+		reason TEXT,
+		failure_count INTEGER NOT NULL DEFAULT 1,
+		failed_at TIMESTAMP,
 		FOREIGN KEY (job_id) REFERENCES xing_jobs(id) ON DELETE CASCADE
 	);`
 
+	// Summaries keyed by description content, shared across job IDs and sources
+	createSummaryCacheTable := `
+	CREATE TABLE IF NOT EXISTS summary_cache (
+		description_hash TEXT NOT NULL,
+		provider TEXT NOT NULL,
+		model TEXT NOT NULL,
+		prompt_version TEXT NOT NULL,
+		summary TEXT NOT NULL,
+		hit_count INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		last_hit_at TIMESTAMP,
+		PRIMARY KEY (description_hash, provider, model, prompt_version)
+	);`
+
+	// Canonical skills, the aliases that map onto them and the per-job links
+	createSkillsTable := `
+	CREATE TABLE IF NOT EXISTS skills (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE,
+		category TEXT NOT NULL
+	);`
+
+	createSkillAliasesTable := `
+	CREATE TABLE IF NOT EXISTS skill_aliases (
+		alias TEXT PRIMARY KEY,
+		skill_id INTEGER NOT NULL,
+		FOREIGN KEY (skill_id) REFERENCES skills(id) ON DELETE CASCADE
+	);`
+
+	createJobSkillsTable := `
+	CREATE TABLE IF NOT EXISTS job_skills (
+		source TEXT NOT NULL,
+		job_id TEXT NOT NULL,
+		skill_id INTEGER NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (source, job_id, skill_id),
+		FOREIGN KEY (skill_id) REFERENCES skills(id) ON DELETE CASCADE
+	);`
+
+	// API keys are stored as sha256 hashes; the plaintext is shown once when minted
+	createAPIKeysTable := `
+	CREATE TABLE IF NOT EXISTS api_keys (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		prefix TEXT NOT NULL,
+		key_hash TEXT NOT NULL UNIQUE,
+		scopes TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		last_used_at TIMESTAMP,
+		revoked_at TIMESTAMP
+	);`
+
+	// Which key triggered which scrape, upload or admin call
+	createAPIAuditLogTable := `
+	CREATE TABLE IF NOT EXISTS api_audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		key_id INTEGER NOT NULL,
+		key_name TEXT NOT NULL,
+		scope TEXT NOT NULL,
+		method TEXT NOT NULL,
+		path TEXT NOT NULL,
+		status INTEGER NOT NULL,
+		remote_addr TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Jobs queued for upload, written in the same transaction that marks them sent,
+	// and their delivery state per sink
+	createUploadOutboxTable := `
+	CREATE TABLE IF NOT EXISTS upload_outbox (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		source TEXT NOT NULL,
+		job_row_id TEXT NOT NULL,
+		idempotency_key TEXT NOT NULL UNIQUE,
+		payload TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	createUploadDeliveriesTable := `
+	CREATE TABLE IF NOT EXISTS upload_deliveries (
+		outbox_id INTEGER NOT NULL,
+		sink TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending',
+		event TEXT NOT NULL DEFAULT 'created',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT,
+		updated_at TIMESTAMP,
+		delivered_at TIMESTAMP,
+		PRIMARY KEY (outbox_id, sink),
+		FOREIGN KEY (outbox_id) REFERENCES upload_outbox(id) ON DELETE CASCADE
+	);`
+
+	// Content hash of every job as last queued per sink, and how far each sink has
+	// read the change feed (the updated_at columns kept by the change-tracking triggers).
+	// job_id is the jobs table row id.
+	createUploadStateTable := `
+	CREATE TABLE IF NOT EXISTS upload_state (
+		sink TEXT NOT NULL,
+		source TEXT NOT NULL,
+		job_id TEXT NOT NULL,
+		content_hash TEXT NOT NULL,
+		queued_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (sink, source, job_id)
+	);`
+
+	createUploadWatermarksTable := `
+	CREATE TABLE IF NOT EXISTS upload_watermarks (
+		sink TEXT NOT NULL,
+		source TEXT NOT NULL,
+		changed_at TEXT NOT NULL,
+		PRIMARY KEY (sink, source)
+	);`
+
+	// A source whose scrape hit an authwall, checkpoint or rate limit; runs stay paused
+	// until resume_after, or until a session check passes when it is NULL
+	createScrapePausesTable := `
+	CREATE TABLE IF NOT EXISTS scrape_pauses (
+		source TEXT PRIMARY KEY,
+		state TEXT NOT NULL,
+		url TEXT,
+		job_id TEXT,
+		paused_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		resume_after TIMESTAMP
+	);`
 
+	createScrapeEventsTable := `
+	CREATE TABLE IF NOT EXISTS scrape_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		source TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		state TEXT,
+		url TEXT,
+		job_id TEXT,
+		message TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// What each selector field matched in the last run that looked for it; broken means
+	// a required field matched nothing on any page of that run
+	createSelectorHealthTable := `
+	CREATE TABLE IF NOT EXISTS selector_health (
+		source TEXT NOT NULL,
+		field TEXT NOT NULL,
+		version INTEGER NOT NULL,
+		selector TEXT,
+		matches INTEGER NOT NULL,
+		pages INTEGER NOT NULL,
+		broken BOOLEAN NOT NULL DEFAULT FALSE,
+		checked_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (source, field)
+	);`
 
 	// Execute table creation queries
 	for _, query := range []string{
@@ -150,16 +334,261 @@ func InitializeDatabase() (*sql.DB, error) {
 		createXingJobApplicationLinksTable,
 		createLinkedInJobDescTable,
 		createXingJobDescTable,
+		createSummaryCacheTable,
+		createSkillsTable,
+		createSkillAliasesTable,
+		createJobSkillsTable,
+		`CREATE INDEX IF NOT EXISTS idx_job_skills_skill ON job_skills (skill_id, source);`,
+		createAPIKeysTable,
+		createAPIAuditLogTable,
+		createUploadOutboxTable,
+		createUploadDeliveriesTable,
+		`CREATE INDEX IF NOT EXISTS idx_upload_deliveries_pending ON upload_deliveries (sink, status, outbox_id);`,
+		`CREATE INDEX IF NOT EXISTS idx_upload_outbox_job ON upload_outbox (source, job_row_id);`,
+		createUploadStateTable,
+		createUploadWatermarksTable,
+		createScrapePausesTable,
+		createScrapeEventsTable,
+		createSelectorHealthTable,
 	} {
 		if _, err = db.Exec(query); err != nil {
 			return nil, fmt.Errorf("❌ Failed to create table: %v", err)
 		}
 	}
 
+	// Columns added after the first release; CREATE TABLE IF NOT EXISTS leaves old DBs untouched
+	columnMigrations := []struct {
+		table, column, definition string
+	}{
+		{"linkedin_jobs", "scraped_at", "TIMESTAMP"},
+		{"linkedin_jobs", "processed_at", "TIMESTAMP"},
+		{"xing_jobs", "scraped_at", "TIMESTAMP"},
+		{"xing_jobs", "processed_at", "TIMESTAMP"},
+		{"linkedin_job_description", "raw_description", "TEXT"},
+		{"linkedin_job_description", "summary_status", "TEXT NOT NULL DEFAULT 'done'"},
+		{"linkedin_job_description", "summary_attempts", "INTEGER NOT NULL DEFAULT 0"},
+		{"linkedin_job_description", "summary_error", "TEXT"},
+		{"linkedin_job_description", "summarized_at", "TIMESTAMP"},
+		{"linkedin_job_description", "summary_claimed_at", "TIMESTAMP"},
+		{"xing_job_description", "raw_description", "TEXT"},
+		{"xing_job_description", "summary_status", "TEXT NOT NULL DEFAULT 'done'"},
+		{"xing_job_description", "summary_attempts", "INTEGER NOT NULL DEFAULT 0"},
+		{"xing_job_description", "summary_error", "TEXT"},
+		{"xing_job_description", "summarized_at", "TIMESTAMP"},
+		{"xing_job_description", "summary_claimed_at", "TIMESTAMP"},
+		{"linkedin_failed_jobs", "reason", "TEXT"},
+		{"linkedin_failed_jobs", "failure_count", "INTEGER NOT NULL DEFAULT 1"},
+		{"linkedin_failed_jobs", "failed_at", "TIMESTAMP"},
+		{"xing_failed_jobs", "reason", "TEXT"},
+		{"xing_failed_jobs", "failure_count", "INTEGER NOT NULL DEFAULT 1"},
+		{"xing_failed_jobs", "failed_at", "TIMESTAMP"},
+		{"linkedin_jobs", "updated_at", "TIMESTAMP"},
+		{"xing_jobs", "updated_at", "TIMESTAMP"},
+		{"xing_jobs", "employment_type", "TEXT"},
+		{"xing_jobs", "salary", "TEXT"},
+		{"linkedin_job_application_links", "updated_at", "TIMESTAMP"},
+		{"xing_job_application_links", "updated_at", "TIMESTAMP"},
+		{"linkedin_job_description", "updated_at", "TIMESTAMP"},
+		{"xing_job_description", "updated_at", "TIMESTAMP"},
+		{"upload_deliveries", "event", "TEXT NOT NULL DEFAULT 'created'"},
+	}
+	for _, m := range columnMigrations {
+		if err := addColumnIfMissing(db, m.table, m.column, m.definition); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := initChangeTracking(db); err != nil {
+		return nil, err
+	}
+
+	// Cross-source views, recreated on every start so they follow the table columns
+	views := map[string]string{
+		"all_jobs": `
+		SELECT 'LinkedIn' AS source, id, jobid, title, company, location, posted_date, link, processed, sent,
+			scraped_at, processed_at
+		FROM linkedin_jobs
+		UNION ALL
+		SELECT 'Xing' AS source, id, jobid, title, company, location, posted_date, link, processed, sent,
+			scraped_at, processed_at
+		FROM xing_jobs`,
+
+		"all_job_descriptions": `
+		SELECT 'LinkedIn' AS source, id, job_id, job_link, job_description, job_type, skills,
+			raw_description, summary_status, summarized_at
+		FROM linkedin_job_description
+		UNION ALL
+		SELECT 'Xing' AS source, id, job_id, job_link, job_description, job_type, skills,
+			raw_description, summary_status, summarized_at
+		FROM xing_job_description`,
+	}
+	for name, body := range views {
+		if _, err := db.Exec(fmt.Sprintf("DROP VIEW IF EXISTS %s;", name)); err != nil {
+			return nil, fmt.Errorf("❌ Failed to drop view %s: %v", name, err)
+		}
+		if _, err := db.Exec(fmt.Sprintf("CREATE VIEW %s AS %s;", name, body)); err != nil {
+			return nil, fmt.Errorf("❌ Failed to create view %s: %v", name, err)
+		}
+	}
+
+	// Full-text search is off by default: it needs a build with -tags sqlite_fts5
+	if err := initSearchIndex(db); err != nil {
+		fmt.Printf("⚠️ Full-text search disabled: %v\n", err)
+	}
+
 	fmt.Println("✅ Database initialized successfully with separate LinkedIn & Xing tables")
 	return db, nil
 }
 
+// changeTracked lists the columns whose changes matter to an upload; the triggers
+// stamp updated_at when a row is inserted or one of them is written
+var changeTracked = []struct{ table, columns string }{
+	{"linkedin_jobs", "title, company, location, posted_date, link"},
+	{"xing_jobs", "title, company, location, posted_date, employment_type, salary, link"},
+	{"linkedin_job_application_links", "job_link"},
+	{"xing_job_application_links", "job_link"},
+	{"linkedin_job_description", "job_description, job_type, skills"},
+	{"xing_job_description", "job_description, job_type, skills"},
+}
+
+// initChangeTracking creates the updated_at triggers. Timestamps carry milliseconds
+// so the upload watermark can tell apart changes made in the same second. A trigger
+// whose definition differs, e.g. from before a column was added to changeTracked, is
+// replaced.
+func initChangeTracking(db *sql.DB) error {
+	for _, t := range changeTracked {
+		touch := fmt.Sprintf(`UPDATE %s SET updated_at = strftime('%%Y-%%m-%%d %%H:%%M:%%f', 'now') WHERE rowid = NEW.rowid;`, t.table)
+		triggers := map[string]string{
+			t.table + "_touch_insert": fmt.Sprintf(`AFTER INSERT ON %s BEGIN %s END`, t.table, touch),
+			t.table + "_touch_update": fmt.Sprintf(`AFTER UPDATE OF %s ON %s BEGIN %s END`, t.columns, t.table, touch),
+		}
+		for name, body := range triggers {
+			create := fmt.Sprintf("CREATE TRIGGER %s %s", name, body)
+			var existing string
+			err := db.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'trigger' AND name = ?`, name).Scan(&existing)
+			if err == nil && existing == create {
+				continue
+			}
+			if err != nil && err != sql.ErrNoRows {
+				return fmt.Errorf("❌ Failed to read trigger %s: %v", name, err)
+			}
+			if _, err := db.Exec(fmt.Sprintf("DROP TRIGGER IF EXISTS %s;", name)); err != nil {
+				return fmt.Errorf("❌ Failed to drop trigger %s: %v", name, err)
+			}
+			if _, err := db.Exec(create + ";"); err != nil {
+				return fmt.Errorf("❌ Failed to create trigger %s: %v", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// searchSources maps each source to its listing and description tables for the FTS index
+var searchSources = []struct{ name, jobs, descriptions string }{
+	{"LinkedIn", "linkedin_jobs", "linkedin_job_description"},
+	{"Xing", "xing_jobs", "xing_job_description"},
+}
+
+// initSearchIndex creates the jobs_fts table and the triggers that keep it in sync
+// with the listing and description tables, and fills it on first creation
+func initSearchIndex(db *sql.DB) error {
+	var existing int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'jobs_fts'`).Scan(&existing); err != nil {
+		return err
+	}
+
+	_, err := db.Exec(`
+	CREATE VIRTUAL TABLE IF NOT EXISTS jobs_fts USING fts5(
+		source UNINDEXED,
+		job_id UNINDEXED,
+		title,
+		company,
+		location,
+		description,
+		skills,
+		tokenize = 'unicode61 remove_diacritics 2'
+	);`)
+	if err != nil {
+		return fmt.Errorf("failed to create jobs_fts (is the binary built with -tags sqlite_fts5?): %v", err)
+	}
+
+	for _, src := range searchSources {
+		// Re-indexes one job from its listing row and description
+		reindex := func(idExpr string) string {
+			return fmt.Sprintf(`
+				DELETE FROM jobs_fts WHERE source = '%[1]s' AND job_id = %[4]s;
+				INSERT INTO jobs_fts (source, job_id, title, company, location, description, skills)
+				SELECT '%[1]s', j.id, j.title, j.company, j.location,
+					COALESCE(d.job_description, '') || ' ' || COALESCE(d.raw_description, ''),
+					COALESCE(d.skills, '')
+				FROM %[2]s j
+				LEFT JOIN %[3]s d ON d.job_id = j.id
+				WHERE j.id = %[4]s;`, src.name, src.jobs, src.descriptions, idExpr)
+		}
+
+		triggers := map[string]string{
+			src.jobs + "_fts_insert":         fmt.Sprintf(`AFTER INSERT ON %s BEGIN %s END`, src.jobs, reindex("NEW.id")),
+			src.jobs + "_fts_update":         fmt.Sprintf(`AFTER UPDATE OF title, company, location ON %s BEGIN %s END`, src.jobs, reindex("NEW.id")),
+			src.jobs + "_fts_delete":         fmt.Sprintf(`AFTER DELETE ON %s BEGIN DELETE FROM jobs_fts WHERE source = '%s' AND job_id = OLD.id; END`, src.jobs, src.name),
+			src.descriptions + "_fts_insert": fmt.Sprintf(`AFTER INSERT ON %s BEGIN %s END`, src.descriptions, reindex("NEW.job_id")),
+			src.descriptions + "_fts_update": fmt.Sprintf(`AFTER UPDATE OF job_description, raw_description, skills ON %s BEGIN %s END`, src.descriptions, reindex("NEW.job_id")),
+			src.descriptions + "_fts_delete": fmt.Sprintf(`AFTER DELETE ON %s BEGIN %s END`, src.descriptions, reindex("OLD.job_id")),
+		}
+		for name, body := range triggers {
+			if _, err := db.Exec(fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s %s;", name, body)); err != nil {
+				return fmt.Errorf("failed to create trigger %s: %v", name, err)
+			}
+		}
+
+		if existing == 0 {
+			_, err := db.Exec(fmt.Sprintf(`
+				INSERT INTO jobs_fts (source, job_id, title, company, location, description, skills)
+				SELECT '%[1]s', j.id, j.title, j.company, j.location,
+					COALESCE(d.job_description, '') || ' ' || COALESCE(d.raw_description, ''),
+					COALESCE(d.skills, '')
+				FROM %[2]s j
+				LEFT JOIN %[3]s d ON d.job_id = j.id;`, src.name, src.jobs, src.descriptions))
+			if err != nil {
+				return fmt.Errorf("failed to build search index for %s: %v", src.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// addColumnIfMissing adds a column to an existing table unless it is already there
+func addColumnIfMissing(db *sql.DB, table, column, definition string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s);", table))
+	if err != nil {
+		return fmt.Errorf("❌ Failed to inspect table %s: %v", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name, typ string
+			notNull   bool
+			dflt      sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			return fmt.Errorf("❌ Failed to inspect table %s: %v", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	rows.Close()
+
+	alterQuery := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", table, column, definition)
+	if _, err := db.Exec(alterQuery); err != nil {
+		return fmt.Errorf("❌ Failed to add column %s.%s: %v", table, column, err)
+	}
+	fmt.Printf("🧱 Added column: %s.%s\n", table, column)
+	return nil
+}
+
 // fileExists checks if the DB file exists
 func fileExists(path string) bool {
 	_, err := os.Stat(path)
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestChangeTrackingFollowsTrackedColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := InitializeDatabaseAt(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A database from before employment_type and salary were tracked
+	for _, stmt := range []string{
+		`DROP TRIGGER xing_jobs_touch_update`,
+		`CREATE TRIGGER IF NOT EXISTS xing_jobs_touch_update AFTER UPDATE OF title, company, location, posted_date, link ON xing_jobs
+			BEGIN UPDATE xing_jobs SET updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE rowid = NEW.rowid; END`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+
+	// Opening it replaces the trigger once; opening it again keeps the new one
+	var definitions []string
+	for run := 0; run < 2; run++ {
+		if db, err = InitializeDatabaseAt(path); err != nil {
+			t.Fatal(err)
+		}
+		var definition string
+		if err := db.QueryRow(`SELECT sql FROM sqlite_master WHERE name = 'xing_jobs_touch_update'`).Scan(&definition); err != nil {
+			t.Fatal(err)
+		}
+		definitions = append(definitions, definition)
+		if run == 0 {
+			db.Close()
+		}
+	}
+	if definitions[0] != definitions[1] {
+		t.Errorf("trigger changed on the second open:\n%s\n%s", definitions[0], definitions[1])
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`INSERT INTO xing_jobs (id, jobid, title, link) VALUES ('x1', 'x1', 'Go Developer', 'https://example.com/x1')`); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		update  string
+		touched bool
+	}{
+		{`UPDATE xing_jobs SET salary = '60.000 €'`, true},
+		{`UPDATE xing_jobs SET employment_type = 'Full-time'`, true},
+		{`UPDATE xing_jobs SET title = 'Go Engineer'`, true},
+		{`UPDATE xing_jobs SET processed = 1, sent = 1`, false},
+	} {
+		if _, err := db.Exec(`UPDATE xing_jobs SET updated_at = '2024-01-01 00:00:00.000'`); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec(tc.update); err != nil {
+			t.Fatal(err)
+		}
+		var updatedAt string
+		if err := db.QueryRow(`SELECT CAST(updated_at AS TEXT) FROM xing_jobs`).Scan(&updatedAt); err != nil {
+			t.Fatal(err)
+		}
+		if touched := updatedAt != "2024-01-01 00:00:00.000"; touched != tc.touched {
+			t.Errorf("%s: updated_at = %s, want touched %v", tc.update, updatedAt, tc.touched)
+		}
+	}
+}
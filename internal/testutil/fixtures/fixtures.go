@@ -0,0 +1,253 @@
+// Package fixtures replays recorded LinkedIn and Xing pages to the scrapers, so their
+// tests drive headless Chromium through the real scraping code against saved HTML
+// instead of the live sites, and check what ends up in a temporary database. It is
+// only imported from tests.
+//
+// Pages live in the testdata directory of the scraper package. A page that names the
+// live URL it was recorded from is fetched again, without a login and with its
+// scripts stripped, when the tests run with FIXTURES_RECORD=1:
+//
+//	go test ./scraper/Linkedin ./scraper/Xing
+//	FIXTURES_RECORD=1 go test ./scraper/Linkedin -run Fixture
+//
+// A re-recorded page usually has other jobs in it than the one before, so expect
+// to update the rows the tests look for. Tests skip when no Chromium is found;
+// CHROMIUM_PATH points at one.
+package fixtures
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+
+	"job_scraper/config"
+	"job_scraper/scraper/browser"
+	"job_scraper/scraper/pacing"
+	"job_scraper/scraper/rotation"
+	"job_scraper/scraper/selectors"
+)
+
+// ServerURL in a page is replaced by the URL of the server that replays it, so
+// hand-written pages can link to each other
+const ServerURL = "__SERVER__"
+
+// Page is one recorded page
+type Page struct {
+	Path    string // where the server replays it, e.g. /jobs/search; the query is ignored
+	File    string // under testdata
+	LiveURL string // what recording fetches; empty for pages written by hand
+}
+
+// Server replays pages and remembers which paths were requested
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []string
+}
+
+// Serve starts a server for pages, re-recording them first with FIXTURES_RECORD=1.
+// ctx is a chromedp context from Browser; it is only used for recording.
+func Serve(t *testing.T, ctx context.Context, pages ...Page) *Server {
+	t.Helper()
+	s := &Server{}
+	mux := http.NewServeMux()
+	record := os.Getenv("FIXTURES_RECORD") == "1"
+	for _, p := range pages {
+		path := filepath.Join("testdata", p.File)
+		if record && p.LiveURL != "" {
+			if err := recordPage(ctx, p.LiveURL, path); err != nil {
+				t.Fatalf("recording %s: %v", p.LiveURL, err)
+			}
+			t.Logf("recorded %s to %s", p.LiveURL, path)
+		}
+		body, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("fixture %s: %v", p.File, err)
+		}
+		mux.HandleFunc(p.Path, func(w http.ResponseWriter, r *http.Request) {
+			s.mu.Lock()
+			s.requests = append(s.requests, r.URL.Path)
+			s.mu.Unlock()
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(strings.ReplaceAll(string(body), ServerURL, s.URL)))
+		})
+	}
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Requests returns the paths requested so far
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+var scripts = regexp.MustCompile(`(?is)<script\b.*?</script>`)
+
+// recordPage saves the rendered page at liveURL once it stops changing. Scripts are
+// dropped, so the replay neither calls home nor re-renders the page.
+func recordPage(ctx context.Context, liveURL, path string) error {
+	tab, cancel := chromedp.NewContext(ctx)
+	defer cancel()
+	tab, cancelTimeout := context.WithTimeout(tab, time.Minute)
+	defer cancelTimeout()
+
+	if err := chromedp.Run(tab, chromedp.Navigate(liveURL), chromedp.WaitReady("body", chromedp.ByQuery)); err != nil {
+		return err
+	}
+	var html string
+	for last := -1; ; {
+		if err := chromedp.Run(tab, chromedp.OuterHTML("html", &html, chromedp.ByQuery)); err != nil {
+			return err
+		}
+		if len(html) == last {
+			break
+		}
+		last = len(html)
+		select {
+		case <-tab.Done():
+			return tab.Err()
+		case <-time.After(2 * time.Second):
+		}
+	}
+
+	html = "<!DOCTYPE html>\n" + scripts.ReplaceAllString(html, "")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(html), 0o644)
+}
+
+// Browser starts a headless Chromium for the test and returns a chromedp context on
+// its first tab, or skips the test when no Chromium is installed
+func Browser(t *testing.T) context.Context {
+	t.Helper()
+	path, err := browser.FindChromium(os.Getenv("CHROMIUM_PATH"))
+	if err != nil {
+		t.Skipf("fixture tests need Chromium: %v", err)
+	}
+
+	m := browser.NewManager(browser.Config{
+		ExecPath:     path,
+		ProfilesDir:  t.TempDir(),
+		StartTimeout: 30 * time.Second,
+		Headless:     true,
+		Flags:        []string{"--no-sandbox", "--disable-gpu"},
+	})
+	t.Cleanup(m.Shutdown)
+	b, err := m.Acquire(context.Background(), "fixtures", browser.Options{})
+	if err != nil {
+		t.Fatalf("starting Chromium: %v", err)
+	}
+	t.Cleanup(b.Release)
+
+	allocatorCtx, cancelAllocator := chromedp.NewRemoteAllocator(context.Background(), b.DebugURL())
+	t.Cleanup(cancelAllocator)
+	ctx, cancel := chromedp.NewContext(allocatorCtx)
+	t.Cleanup(cancel)
+	if err := chromedp.Run(ctx); err != nil {
+		t.Fatalf("connecting to Chromium: %v", err)
+	}
+	return ctx
+}
+
+// DB is a fresh database with the full schema
+func DB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := config.InitializeDatabaseAt(filepath.Join(t.TempDir(), "fixtures.db"))
+	if err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// Isolate keeps the environment from leaking into a scraper test: the source's pacer
+// barely waits, listings go direct and the bundled selectors are used. The
+// process-wide pacer, rotation and selectors are dropped before the test and after
+// it, so they are built again from the settings in force.
+func Isolate(t *testing.T, source string) {
+	prefix := "PACING_" + strings.ToUpper(source) + "_"
+	for name, value := range map[string]string{
+		"RATE": "60000", "BURST": "100", "WAIT_TIMEOUT": "10s",
+		// Long enough for the application tab to open after the apply click
+		"MIN_DELAY": "300ms", "MAX_DELAY": "400ms",
+	} {
+		t.Setenv(prefix+name, value)
+	}
+	t.Setenv("SCRAPE_PROXIES", "")
+	t.Setenv("SCRAPE_PROFILES_FILE", "")
+	t.Setenv("SELECTORS_FILE", "")
+
+	reset := func() {
+		pacing.Reset(source)
+		rotation.Reset()
+		selectors.Reset()
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+// Count runs a COUNT(*) query
+func Count(t *testing.T, db *sql.DB, query string, args ...interface{}) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return n
+}
+
+// Rows reads a query whose columns are all text into one string per row, with the
+// columns joined by " | "
+func Rows(t *testing.T, db *sql.DB, query string, args ...interface{}) []string {
+	t.Helper()
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	defer rows.Close()
+	cols, _ := rows.Columns()
+	var out []string
+	for rows.Next() {
+		values := make([]sql.NullString, len(cols))
+		ptrs := make([]interface{}, len(cols))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		parts := make([]string, len(cols))
+		for i, v := range values {
+			parts[i] = v.String
+		}
+		out = append(out, strings.Join(parts, " | "))
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return out
+}
+
+// Equal fails the test when got and want differ, listing both
+func Equal(t *testing.T, what string, got, want []string) {
+	t.Helper()
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("%s:\n got  %q\n want %q", what, got, want)
+	}
+}
@@ -0,0 +1,105 @@
+package fixtures
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"job_scraper/scraper/pacing"
+	"job_scraper/scraper/rotation"
+	"job_scraper/scraper/selectors"
+)
+
+func TestServeReplaysPagesWithTheServerURL(t *testing.T) {
+	t.Setenv("FIXTURES_RECORD", "")
+	srv := Serve(t, context.Background(),
+		Page{Path: "/links", File: "links.html", LiveURL: "https://example.com/links"},
+		Page{Path: "/jobs/view/1", File: "links.html"},
+	)
+
+	for _, tc := range []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/links", http.StatusOK, `<a href="` + srv.URL + `/next">next</a>`},
+		{"/links?page=2", http.StatusOK, `<a href="` + srv.URL + `/next">next</a>`},
+		{"/jobs/view/1", http.StatusOK, `<a href="` + srv.URL + `/next">next</a>`},
+		{"/jobs/view/2", http.StatusNotFound, ""},
+		{"/next", http.StatusNotFound, ""},
+	} {
+		resp, err := http.Get(srv.URL + tc.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tc.status {
+			t.Errorf("GET %s = %d, want %d", tc.path, resp.StatusCode, tc.status)
+			continue
+		}
+		want := "<!DOCTYPE html>\n<html><body>" + tc.body + "</body></html>\n"
+		if tc.status == http.StatusOK && string(body) != want {
+			t.Errorf("GET %s = %s, want %s", tc.path, body, want)
+		}
+	}
+
+	// Only the paths of replayed pages are remembered, without their query
+	Equal(t, "requests", srv.Requests(), []string{"/links", "/links", "/jobs/view/1"})
+}
+
+func TestRows(t *testing.T) {
+	db := DB(t)
+	for _, stmt := range []string{
+		`INSERT INTO xing_jobs (id, jobid, title, company, link) VALUES ('b', 'b', 'Data Engineer', NULL, 'https://example.com/b')`,
+		`INSERT INTO xing_jobs (id, jobid, title, company, link) VALUES ('a', 'a', 'Go Developer', 'ACME', 'https://example.com/a')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// NULLs read as empty strings
+	Equal(t, "rows", Rows(t, db, `SELECT id, title, company FROM xing_jobs ORDER BY id`),
+		[]string{"a | Go Developer | ACME", "b | Data Engineer | "})
+	Equal(t, "no rows", Rows(t, db, `SELECT id FROM xing_jobs WHERE id = ?`, "c"), nil)
+	if n := Count(t, db, `SELECT COUNT(*) FROM xing_jobs WHERE company IS NULL`); n != 1 {
+		t.Errorf("count = %d, want 1", n)
+	}
+}
+
+func TestIsolateResetsProcessWideState(t *testing.T) {
+	t.Setenv("PACING_XING_RATE", "1")
+	t.Setenv("SCRAPE_PROXIES", "http://proxy.example:8080")
+	pacing.Reset("Xing")
+	rotation.Reset()
+	selectors.Reset()
+	t.Cleanup(func() {
+		pacing.Reset("Xing")
+		rotation.Reset()
+		selectors.Reset()
+	})
+	before := selectors.Default()
+
+	t.Run("isolated", func(t *testing.T) {
+		Isolate(t, "Xing")
+		if rate := pacing.For("Xing").Config().Rate; rate != 60000 {
+			t.Errorf("pacer rate = %v, want 60000", rate)
+		}
+		if n := len(rotation.Default().Proxies()); n != 0 {
+			t.Errorf("rotation has %d proxies, want none", n)
+		}
+		if selectors.Default() == before {
+			t.Error("selectors were not loaded again")
+		}
+	})
+
+	// The settings of the surrounding test apply again afterwards
+	if rate := pacing.For("xing").Config().Rate; rate != 1 {
+		t.Errorf("pacer rate after the test = %v, want 1", rate)
+	}
+	if n := len(rotation.Default().Proxies()); n != 1 {
+		t.Errorf("rotation has %d proxies after the test, want 1", n)
+	}
+}
@@ -12,9 +12,10 @@ import (
 	"time"
 
 	"job_scraper/config"
-	"job_scraper/scraper"
-	"job_scraper/scraper/Linkedin"
-	"job_scraper/scraper/Xing"
+	"job_scraper/scraper/api"
+	"job_scraper/scraper/browser"
+	"job_scraper/scraper/middleware"
+	"job_scraper/scraper/skills"
 
 )
 
@@ -27,72 +28,35 @@ func main() {
 	}
 	defer db.Close() // Ensure the database is closed when the program exits
 
+	// Load the skill alias dictionary and normalize skills stored before it existed
+	if err := skills.SeedTaxonomy(db); err != nil {
+		log.Fatalf("❌ Failed to seed skill taxonomy: %v", err)
+	}
+	if n, err := skills.Backfill(db); err != nil {
+		log.Printf("⚠️ Skill backfill failed: %v", err)
+	} else if n > 0 {
+		log.Printf("🏷️ Normalized skills for %d existing jobs", n)
+	}
+
 	// Set up a channel to listen for an interrupt signal (Ctrl+C)
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
-	// Create a new HTTP request multiplexer (mux)
-	mux := http.NewServeMux()
-
-	mux.HandleFunc("/uploadjobs", func(w http.ResponseWriter, r *http.Request) {
-		scraper.UploadHandler(w, r, db) // Fetch and display job listings from LinkedIn and Xing
-	})
-	
-
-
-
-
-	// Define the routes and their handlers
-	mux.HandleFunc("/joblistings", func(w http.ResponseWriter, r *http.Request) {
-		scraper.JobListingsHandler(w, r, db) 
-	})
-	
-	mux.HandleFunc("/loginlinkedin", func(w http.ResponseWriter, r *http.Request) {
-		Linkedin.LoginLinkedInHandler(db,w,r)
-	})
-
-	
-
-
-
-	mux.HandleFunc("/loginxing", func(w http.ResponseWriter, r *http.Request) {
-		Xing.LoginXingHandler(db,w,r)
-	})
-	mux.HandleFunc("/viewxingjobs", func(w http.ResponseWriter, r *http.Request) {
-		Xing.ViewXingJobs(db,w,r)
-	})
-	mux.HandleFunc("/viewxingdesc", func(w http.ResponseWriter, r *http.Request) {
-		Xing.ViewXingJobDescriptions(db,w,r)
-	})
-
-	
-
-
-	mux.HandleFunc("/viewlinkedmetadata", func(w http.ResponseWriter, r *http.Request) {
-		scraper.ViewJobsHandler(w, r, db)
-	})
-
-	mux.HandleFunc("/viewlinkedinjobs", func(w http.ResponseWriter, r *http.Request) {
-		Linkedin.ViewLinkedInJobs(db, w, r)
-	})
-
-	mux.HandleFunc("/viewlinkedindesc", func(w http.ResponseWriter, r *http.Request) {
-		Linkedin.ViewLinkedInJobDescriptions(db, w, r)
-	})
-
-
-	/*
-	mux.HandleFunc("/viewlinkedinfailedjobs", func(w http.ResponseWriter, r *http.Request) {
-		Linkedin.ViewLinkedInFailedJobs(db, w, r)
-	})
-		*/
-
-	//mux.HandleFunc("/uploaddb", func(w http.ResponseWriter, r *http.Request) {
-	//	Linkedin.PostDBHandler(w, r, db)  // Pass the db to the handler
-	//})
-
-	// Enable CORS support
-	handler := enableCors(mux)
+	// Routes: /api/v1 resources plus the deprecated verb-style aliases
+	router := api.NewRouter(db)
+
+	// Middleware chain, outermost first; settings come from the environment (see middleware.ConfigFromEnv)
+	cfg := middleware.ConfigFromEnv()
+	handler := middleware.Chain(router,
+		middleware.RequestID,
+		middleware.Logger,
+		api.JSONErrors,
+		middleware.Recover,
+		middleware.CORS(cfg),
+		middleware.RateLimit(cfg.RatePerSecond, cfg.RateBurst),
+		middleware.MaxBody(cfg.MaxBodyBytes),
+		middleware.Gzip(cfg.Gzip),
+	)
 
 	// Define the server port
 	port := ":8000"
@@ -121,32 +85,10 @@ func main() {
 		log.Fatalf("❌ Server shutdown failed: %v", err)
 	}
 
-	// Terminate the browser (chromedp or any other process you are running)
+	// Stop the browsers this server started; browsers it attached to keep running
 	fmt.Println("🖥️ Terminating browser...")
-	if err := scraper.TerminateBrowser(); err != nil {
-		log.Printf("❌ Failed to terminate the browser: %v", err)
-	} else {
-		fmt.Println("✅ Browser terminated successfully.")
-	}
+	browser.Default().Shutdown()
+	fmt.Println("✅ Browser terminated successfully.")
 
 	fmt.Println("✅ Server shut down gracefully.")
 }
-
-// enableCors adds CORS headers to allow cross-origin requests
-func enableCors(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		allowedOrigin := "http://localhost:3000" // Frontend server URL or adjust if needed
-		w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
-		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-
-		// Respond to OPTIONS pre-flight request
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusNoContent)
-			return
-		}
-
-		// Serve the request to the next handler
-		next.ServeHTTP(w, r)
-	})
-}
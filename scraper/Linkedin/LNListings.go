@@ -3,13 +3,19 @@ package Linkedin
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
-	"time"
 	"strconv"
 
 	"github.com/chromedp/chromedp"
 	"github.com/google/uuid"
+
+	"job_scraper/scraper/browser"
+	"job_scraper/scraper/pacing"
+	"job_scraper/scraper/pause"
+	"job_scraper/scraper/rotation"
+	"job_scraper/scraper/selectors"
 )
 
 // Job struct
@@ -25,44 +31,37 @@ type Job struct {
 	Processed   bool   `json:"processed"`
 }
 
-// JobResponse struct for API response
-type JobResponse struct {
-	ID         string `json:"id"`
-	JobID       int64  `json:"jobId"` // Added jobId
-	Title      string `json:"title"`
-	Company    string `json:"company"`
-	Location   string `json:"location"`
-	PostedDate string `json:"postedDate"`
-	Link       string `json:"link"`
-	Processed  bool   `json:"processed"`
-}
-
+// jobSearchURL is LinkedIn's guest job search; the fixture tests point it at recorded pages
+var jobSearchURL = "https://www.linkedin.com/jobs/search"
 
 // Utility function: Construct LinkedIn job search URL
 func constructSearchUrl(keywords, location, dateSincePosted string) string {
 	return fmt.Sprintf(
-		"https://www.linkedin.com/jobs/search?keywords=%s&location=%s&f_TPR=%s&position=1&pageNum=0",
+		"%s?keywords=%s&location=%s&f_TPR=%s&position=1&pageNum=0",
+		jobSearchURL,
 		strings.ReplaceAll(keywords, " ", "%20"),
 		strings.ReplaceAll(location, " ", "%20"),
 		dateSincePosted,
 	)
 }
 
-// Utility function: Set up Chromedp context
-func setupChromedpContext() (context.Context, context.CancelFunc) {
-	opts := append(chromedp.DefaultExecAllocatorOptions[:],
-		chromedp.Flag("headless", false), // Disable headless mode (optional)
-		chromedp.Flag("executable-path", "/snap/bin/chromium"),
-		chromedp.Flag("window-size", "800,600"),
-	)
+// Utility function: Set up a Chromedp context on the LinkedIn browser
+func setupChromedpContext(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	b, err := browser.Default().Acquire(ctx, site.Profile, browser.Options{
+		Flags: []string{"--window-size=800,600"},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
 
-	allocatorCtx, allocatorCancel := chromedp.NewExecAllocator(context.Background(), opts...)
-	ctx, ctxCancel := chromedp.NewContext(allocatorCtx)
+	allocatorCtx, allocatorCancel := chromedp.NewRemoteAllocator(context.Background(), b.DebugURL())
+	chromeCtx, ctxCancel := chromedp.NewContext(allocatorCtx)
 
-	return ctx, func() {
+	return chromeCtx, func() {
 		ctxCancel()
 		allocatorCancel()
-	}
+		b.Release()
+	}, nil
 }
 
 
@@ -72,8 +71,8 @@ func insertJobIfNotExists(db *sql.DB, job Job) error {
 
     // Attempt to insert the job into the database
     _, err := db.Exec(`
-        INSERT INTO linkedin_jobs (id, jobid, title, company, location, posted_date, link, processed)
-        VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+        INSERT INTO linkedin_jobs (id, jobid, title, company, location, posted_date, link, processed, scraped_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`,
         uuid.New().String(), strconv.FormatInt(job.JobID, 10), job.Title, job.Company, job.Location, job.PostedDate, job.Link, false,
     )
 
@@ -115,33 +114,64 @@ func extractJobID(link string) int64 {
 	return num
 }
 
+// listingPulls are the values read from every search result, keyed like Job
+var listingPulls = []selectors.Pull{
+	{Key: "link", Field: selectors.ListingLink, Prop: "href"},
+	{Key: "company", Field: selectors.ListingCompany},
+	{Key: "location", Field: selectors.ListingLocation},
+	{Key: "postedDate", Field: selectors.ListingPosted, Attr: "datetime"},
+	{Key: "isEasyApply", Field: selectors.ListingEasyApply, Exists: true},
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "Unknown"
+	}
+	return s
+}
+
 // Fetch and store jobs in SQLite
 func fetchAndStoreJobs(ctx context.Context, db *sql.DB, jobTitles []string, location, dateSincePosted string) error {
+	pacer := pacing.For(site.Name)
+	rotator := rotation.Default()
+	run := selectors.NewRun(site.Name)
+	defer run.Finish(db)
 	for _, title := range jobTitles {
 		searchURL := constructSearchUrl(title, location, dateSincePosted)
 		var jobs []Job
 
-		err := chromedp.Run(ctx,
-			chromedp.Navigate(searchURL),
-			chromedp.Sleep(5*time.Second),
-			chromedp.WaitVisible(`.jobs-search__results-list`, chromedp.ByQuery),
-			chromedp.Evaluate(`Array.from(document.querySelectorAll('.jobs-search__results-list li')).map(el => ({
-				title: "`+title+`",
-				link: el.querySelector('.base-card__full-link')?.href || '',
-				company: el.querySelector('.base-search-card__subtitle')?.innerText.trim() || 'Unknown',
-				location: el.querySelector('.job-search-card__location')?.innerText.trim() || 'Unknown',
-				postedDate: el.querySelector('time')?.getAttribute('datetime') || 'Unknown',
-				isEasyApply: el.querySelector('.jobs-apply-button--top-card') !== null
-			}))`, &jobs),
-		)
+		// Each search runs in its own tab under the next proxy and browser identity
+		err := rotator.Scrape(ctx, func(ctx context.Context) error {
+			return chromedp.Run(ctx,
+				pacer.Navigate(searchURL),
+				browser.ExpectNormalPage(site),
+				run.Wait(selectors.ListingCard, pacer.WaitVisible),
+				pacer.Think(),
+				run.Extract(selectors.ListingCard, listingPulls, &jobs),
+			)
+		})
+		if errors.Is(err, rotation.ErrNoHealthyProxy) {
+			return err
+		}
 
+		if pe, ok := browser.AsPageError(err); ok && pe.State.Blocking() {
+			// Every remaining title would hit the same wall
+			pacer.Record(pacing.Blocked)
+			return err
+		}
 		if err != nil {
+			pacer.Record(pacing.Failure)
 			fmt.Printf("❌ Failed to fetch jobs for %s: %v\n", title, err)
 			continue
 		}
+		pacer.Record(pacing.Success)
 
 		count := 0
 		for _, job := range jobs {
+			job.Title = title
+			job.Company = orUnknown(job.Company)
+			job.Location = orUnknown(job.Location)
+			job.PostedDate = orUnknown(job.PostedDate)
 			if job.Link != "" && !job.IsEasyApply {
 				// Extract jobid from the link
 				job.JobID = extractJobID(job.Link)
@@ -168,8 +198,15 @@ func fetchAndStoreJobs(ctx context.Context, db *sql.DB, jobTitles []string, loca
 
 // LinkedinJobListingsHandler handles job scraping and storing for LinkedIn.
 func LinkedinJobListingsHandler(ctx context.Context, db *sql.DB) error {
+	if err := pause.Check(db, site.Name); err != nil {
+		return err
+	}
+
 	// Set up a chromedp context with cancel
-	chromeCtx, cancel := setupChromedpContext()
+	chromeCtx, cancel, err := setupChromedpContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start Chrome: %w", err)
+	}
 	defer cancel()
 
 	jobTitles := []string{
@@ -339,7 +376,13 @@ func LinkedinJobListingsHandler(ctx context.Context, db *sql.DB) error {
 	dateSincePosted := ""
 
 	// Perform scraping and store in DB
-	return fetchAndStoreJobs(chromeCtx, db, jobTitles, location, dateSincePosted)
+	err = fetchAndStoreJobs(chromeCtx, db, jobTitles, location, dateSincePosted)
+	if pe, ok := browser.AsPageError(err); ok && pe.State.Blocking() {
+		if perr := pause.BlockPage(db, pe, ""); perr != nil {
+			fmt.Printf("❌ %v\n", perr)
+		}
+	}
+	return err
 }
 
 
@@ -13,18 +13,6 @@ import (
 	"github.com/chromedp/cdproto/target"
 )
 
-// StopChrome closes the Chromium browser process
-func StopChrome() {
-	if chromeCmd != nil {
-		fmt.Println("🛑 Closing Chromium...")
-		if err := chromeCmd.Process.Kill(); err != nil {
-			fmt.Printf("⚠️ Failed to close Chromium: %v\n", err)
-		} else {
-			fmt.Println("✅ Chromium closed successfully.")
-		}
-	}
-}
-
 
 
 func isLinkedInRelated(rawURL string) bool {
@@ -186,11 +174,15 @@ type FailedJob struct {
 	JobLink string `json:"job_link"` // Matches job_link TEXT
 }
 
-// StoreFailedJob stores a failed job in the database
+// StoreFailedJob stores a failed job in the database; repeated failures of a link bump its count
 func StoreFailedJob(db *sql.DB, jobID, jobLink, reason string) error {
 	_, err := db.Exec(`
-        INSERT INTO linkedin_failed_jobs (job_id, job_link, reason) 
-        VALUES (?, ?, ?)`, jobID, jobLink, reason,
+        INSERT INTO linkedin_failed_jobs (job_id, job_link, reason, failed_at) 
+        VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+        ON CONFLICT (job_link) DO UPDATE SET
+            reason = excluded.reason,
+            failure_count = failure_count + 1,
+            failed_at = excluded.failed_at`, jobID, jobLink, reason,
 	)
 	if err != nil {
 		log.Printf("❌ Failed to store failed job in DB: %v\n", err)
@@ -0,0 +1,177 @@
+package Linkedin
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+
+	"job_scraper/internal/testutil/fixtures"
+	"job_scraper/scraper/browser"
+	"job_scraper/scraper/selectors"
+)
+
+// Job pages are trimmed by hand: an ad does not stay online long enough to re-record
+var pages = []fixtures.Page{
+	{Path: "/jobs/search", File: "search.html", LiveURL: "https://www.linkedin.com/jobs/search?keywords=Backend%20Engineer&location=Berlin%2C%20Germany"},
+	{Path: "/jobs/view/backend-engineer-at-acme-3912345678", File: "job.html"},
+	{Path: "/jobs/view/senior-go-developer-at-gamma-3923456789", File: "expired.html"},
+	{Path: "/jobs/view/rate-limited-3934567890", File: "rate_limited.html"},
+	{Path: "/careers/acme/backend-engineer", File: "apply.html"},
+}
+
+// replay starts Chromium and the fixture server and points the search at it
+func replay(t *testing.T) (context.Context, *fixtures.Server) {
+	fixtures.Isolate(t, site.Name)
+	ctx := fixtures.Browser(t)
+	srv := fixtures.Serve(t, ctx, pages...)
+
+	live := jobSearchURL
+	jobSearchURL = srv.URL + "/jobs/search"
+	t.Cleanup(func() { jobSearchURL = live })
+	return ctx, srv
+}
+
+// openJob runs a job the way LoginLinkedInHandler does: in a tab of its own, with
+// the tabs open before it remembered so the application tab can be told apart
+func openJob(t *testing.T, ctx context.Context, db *sql.DB, jobID, link string) error {
+	t.Helper()
+	if _, err := db.Exec(`INSERT INTO linkedin_jobs (id, jobid, title, link, processed) VALUES (?, ?, 'Fixture', ?, FALSE)`,
+		jobID, jobID, link); err != nil {
+		t.Fatal(err)
+	}
+
+	jobCtx, cancel := chromedp.NewContext(ctx)
+	defer cancel()
+	jobCtx, cancelTimeout := context.WithTimeout(jobCtx, 30*time.Second)
+	defer cancelTimeout()
+	if err := chromedp.Run(jobCtx); err != nil {
+		t.Fatal(err)
+	}
+	tabs, err := chromedp.Targets(jobCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing := make(map[target.ID]struct{})
+	for _, tab := range tabs {
+		existing[tab.TargetID] = struct{}{}
+	}
+
+	run := selectors.NewRun(site.Name)
+	defer run.Finish(db)
+	if err := navigateAndClickApply(jobCtx, db, run, jobID, link); err != nil {
+		return err
+	}
+	if err := captureAndCloseNewTab(jobCtx, db, jobID, existing); err != nil {
+		t.Fatalf("capture: %v", err)
+	}
+	return nil
+}
+
+func TestFixtureListingsStoreSearchResults(t *testing.T) {
+	ctx, srv := replay(t)
+	db := fixtures.DB(t)
+
+	if err := fetchAndStoreJobs(ctx, db, []string{"Backend Engineer"}, "Berlin, Germany", ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.Requests(); len(got) != 1 || got[0] != "/jobs/search" {
+		t.Errorf("requests = %v, want one search", got)
+	}
+
+	// The Easy Apply card and the promoted card without a link are skipped; the second
+	// posted date only matches through a fallback selector
+	fixtures.Equal(t, "linkedin_jobs", fixtures.Rows(t, db, `
+		SELECT jobid, title, company, location, posted_date, link, processed FROM linkedin_jobs ORDER BY jobid`), []string{
+		"3912345678 | Backend Engineer | ACME GmbH | Berlin, Berlin, Germany | 2025-05-02 | https://de.linkedin.com/jobs/view/backend-engineer-at-acme-3912345678?refId=a1&trackingId=b2 | false",
+		"3923456789 | Backend Engineer | Gamma Systems | Berlin, Berlin, Germany | 2025-05-08 | https://de.linkedin.com/jobs/view/senior-go-developer-at-gamma-3923456789?refId=c3&trackingId=d4 | false",
+	})
+
+	fixtures.Equal(t, "selector health", fixtures.Rows(t, db, `
+		SELECT field, matches, pages, broken FROM selector_health WHERE source = 'LinkedIn' ORDER BY field`), []string{
+		"listing.card | 4 | 1 | false",
+		"listing.company | 3 | 1 | false",
+		"listing.easy_apply | 1 | 1 | false",
+		"listing.link | 3 | 1 | false",
+		"listing.location | 3 | 1 | false",
+		"listing.posted | 3 | 1 | false",
+	})
+}
+
+func TestFixtureDetailStoresDescriptionAndApplicationLink(t *testing.T) {
+	ctx, srv := replay(t)
+	db := fixtures.DB(t)
+	link := srv.URL + "/jobs/view/backend-engineer-at-acme-3912345678"
+
+	if err := openJob(t, ctx, db, "job-1", link); err != nil {
+		t.Fatal(err)
+	}
+
+	fixtures.Equal(t, "linkedin_job_description", fixtures.Rows(t, db, `
+		SELECT job_id, job_link, raw_description, summary_status FROM linkedin_job_description`), []string{
+		"job-1 | " + link + " | About the job We build the payment backend for 2,000 shops. You will design Go services, own their PostgreSQL schemas and run them on Kubernetes. 3+ years of Go Hybrid, two days a week in Berlin | pending",
+	})
+	fixtures.Equal(t, "processed", fixtures.Rows(t, db, `SELECT processed FROM linkedin_jobs WHERE id = 'job-1'`), []string{"true"})
+	fixtures.Equal(t, "application links", fixtures.Rows(t, db, `
+		SELECT job_id, job_link FROM linkedin_job_application_links`), []string{
+		"job-1 | " + srv.URL + "/careers/acme/backend-engineer",
+	})
+	if n := fixtures.Count(t, db, `SELECT COUNT(*) FROM linkedin_failed_jobs`); n != 0 {
+		t.Errorf("%d failed jobs recorded for a job that went through", n)
+	}
+
+	// The application tab is closed again
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		tabs, err := chromedp.Targets(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		open := false
+		for _, tab := range tabs {
+			open = open || strings.Contains(tab.URL, "/careers/")
+		}
+		if !open {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the application tab is still open")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func TestFixtureExpiredJobIsRecordedAndNotRetried(t *testing.T) {
+	ctx, srv := replay(t)
+	db := fixtures.DB(t)
+	link := srv.URL + "/jobs/view/senior-go-developer-at-gamma-3923456789"
+
+	err := openJob(t, ctx, db, "job-2", link)
+	if pe, ok := browser.AsPageError(err); !ok || pe.State != browser.PageExpired {
+		t.Fatalf("openJob = %v, want an expired page", err)
+	}
+	fixtures.Equal(t, "linkedin_failed_jobs", fixtures.Rows(t, db, `
+		SELECT job_id, job_link, reason FROM linkedin_failed_jobs`), []string{"job-2 | " + link + " | Job expired"})
+	fixtures.Equal(t, "processed", fixtures.Rows(t, db, `SELECT processed FROM linkedin_jobs WHERE id = 'job-2'`), []string{"true"})
+	if n := fixtures.Count(t, db, `SELECT COUNT(*) FROM linkedin_job_description`); n != 0 {
+		t.Errorf("an expired job stored %d descriptions", n)
+	}
+}
+
+func TestFixtureRateLimitRecordsNothingAgainstTheJob(t *testing.T) {
+	ctx, srv := replay(t)
+	db := fixtures.DB(t)
+
+	err := openJob(t, ctx, db, "job-3", srv.URL+"/jobs/view/rate-limited-3934567890")
+	if pe, ok := browser.AsPageError(err); !ok || pe.State != browser.PageRateLimited {
+		t.Fatalf("openJob = %v, want a rate-limit page", err)
+	}
+	if n := fixtures.Count(t, db, `SELECT COUNT(*) FROM linkedin_failed_jobs`); n != 0 {
+		t.Errorf("a rate limit recorded %d failed jobs", n)
+	}
+	fixtures.Equal(t, "processed", fixtures.Rows(t, db, `SELECT processed FROM linkedin_jobs WHERE id = 'job-3'`), []string{"false"})
+}
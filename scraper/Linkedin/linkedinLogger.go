@@ -7,8 +7,6 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"os/exec"
-	"runtime"
 	//"strconv"
 	"time"
 	//"errors"
@@ -16,36 +14,35 @@ import (
 	"github.com/chromedp/cdproto/target"
 	"github.com/chromedp/chromedp"
 	"strings"
+
+	"job_scraper/scraper/browser"
+	"job_scraper/scraper/listquery"
+	"job_scraper/scraper/pacing"
+	"job_scraper/scraper/pause"
+	"job_scraper/scraper/selectors"
+	"job_scraper/scraper/skills"
 )
 
 
 
 
 
-var chromeCmd *exec.Cmd // Global variable to track the process
+// site is the browser profile and session the LinkedIn scrapers share, so the
+// session logged in there is used for listings and details alike
+var site = browser.LinkedIn
+
 func LoginLinkedInHandler(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 	fmt.Println("🚀 Starting LinkedIn job application automation...")
 
-	// Start Chrome with remote debugging
-	linkedInURL := "https://www.linkedin.com/"
-	chromePath := "chromium"
-
-	chromeCmd := exec.Command(chromePath, "--remote-debugging-port=9222", "--profile-directory=Profile 2", linkedInURL)
-
-	if runtime.GOOS != "linux" {
-		http.Error(w, "Unsupported OS", http.StatusInternalServerError)
-		return
-	}
-
-	err := chromeCmd.Start()
+	// Start or attach to the LinkedIn browser with remote debugging
+	b, err := browser.Default().Acquire(r.Context(), site.Profile, browser.Options{})
 	if err != nil {
 		log.Printf("❌ Failed to start Chromium: %v\n", err)
 		http.Error(w, "Failed to start Chromium", http.StatusInternalServerError)
 		return
 	}
-
+	defer b.Release()
 	fmt.Println("✅ Chrome launched successfully.")
-	time.Sleep(5 * time.Second)
 
 	// Load job links from the database
 	jobLinks, err := LoadJobLinksFromDB(db)
@@ -65,17 +62,43 @@ func LoginLinkedInHandler(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 	log.Printf("📊 Total job links loaded: %d\n", totalLinks)
 
 	// Create ChromeDP allocator context
-	allocatorCtx, cancelAllocator := chromedp.NewRemoteAllocator(context.Background(), "http://localhost:9222")
+	allocatorCtx, cancelAllocator := chromedp.NewRemoteAllocator(context.Background(), b.DebugURL())
 	defer cancelAllocator()
 
 	// Create root ChromeDP context
-	_, cancelCtx := chromedp.NewContext(allocatorCtx)
+	rootCtx, cancelCtx := chromedp.NewContext(allocatorCtx)
 	defer cancelCtx()
+
+	// Details need a logged-in session and no pause; stop before any job is marked or failed
+	if err := pause.Start(rootCtx, db, browser.Default(), site); err != nil {
+		log.Printf("❌ %v\n", err)
+		status := http.StatusInternalServerError
+		if pause.IsPaused(err) {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
 	
+	// A wall (authwall, checkpoint, rate limit) pauses the whole run: every later job
+	// would hit it too, and none of them is at fault
+	var blocked *browser.PageError
+	var blockedJob string
+	processed := 0
+	pacer := pacing.For(site.Name)
+	selRun := selectors.NewRun(site.Name)
+	defer selRun.Finish(db)
+
+run:
 	for title, jobs := range jobLinks {
 		fmt.Printf("📌 Processing jobs for: %s\n", title)
 	
 		for _, job := range jobs {
+			// A client that went away ends the run; the jobs left stay unprocessed
+			if blocked != nil || r.Context().Err() != nil {
+				break run
+			}
+			processed++
 			func() {
 				defer func() {
 					if r := recover(); r != nil {
@@ -98,6 +121,11 @@ func LoginLinkedInHandler(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 					// Optional: return here if you want to skip on failure
 				}
 	
+				// Wait for a navigation token before the job's timeout starts ticking
+				if err := pacer.Wait(r.Context(), job.Link); err != nil {
+					return
+				}
+
 				// 🎯 Create new Chrome context
 				jobCtxBase, cancelBase := chromedp.NewContext(allocatorCtx)
 				defer cancelBase()
@@ -118,7 +146,20 @@ func LoginLinkedInHandler(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 					existingTabs[t.TargetID] = struct{}{}
 				}
 	
-				if err := navigateAndClickApply(jobCtx, db, jobIDStr, job.Link); err != nil {
+				err = navigateAndClickApply(jobCtx, db, selRun, jobIDStr, job.Link)
+				pe, isPage := browser.AsPageError(err)
+				switch {
+				case isPage && pe.State.Blocking():
+					pacer.Record(pacing.Blocked)
+					blocked, blockedJob = pe, jobIDStr
+					return
+				case err != nil && !isPage:
+					pacer.Record(pacing.Failure)
+				default:
+					pacer.Record(pacing.Success) // expired ads included: the site answered normally
+				}
+
+				if err != nil {
 					log.Printf("⚠️ Skipping job %s - navigation/apply failed: %v\n", jobIDStr, err)
 				} else {
 					if err := captureAndCloseNewTab(jobCtx, db, jobIDStr, existingTabs); err != nil {
@@ -134,12 +175,12 @@ func LoginLinkedInHandler(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 	
 	
 
-	// Stop Chromium process
-	fmt.Println("🛑 Closing Chromium...")
-	if err := chromeCmd.Process.Kill(); err != nil {
-		fmt.Printf("⚠️ Failed to close Chromium: %v\n", err)
-	} else {
-		fmt.Println("✅ Chromium closed successfully.")
+	if blocked != nil {
+		if err := pause.BlockPage(db, blocked, blockedJob); err != nil {
+			log.Printf("❌ %v\n", err)
+		}
+		http.Error(w, fmt.Sprintf("LinkedIn run paused after %d of %d jobs: %v", processed-1, totalLinks, blocked), http.StatusConflict)
+		return
 	}
 
 	// Send success response
@@ -151,27 +192,63 @@ func LoginLinkedInHandler(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 
 // Job represents a job listing
 
+// linkListSpec drives the list params of ViewLinkedInJobs
+var linkListSpec = listquery.Spec{
+	From: "linkedin_job_application_links l LEFT JOIN linkedin_jobs j ON j.id = l.job_id",
+	Sorts: map[string]string{
+		"id":       "l.id",
+		"title":    "COALESCE(j.title, '')",
+		"company":  "COALESCE(j.company, '')",
+		"location": "COALESCE(j.location, '')",
+	},
+	DefaultSort: "id",
+	TieBreaker:  "l.id",
+	Source:      "'LinkedIn'",
+	Company:     "j.company",
+	Location:    "j.location",
+	Date:        "date(j.processed_at)",
+	Statuses: map[string]string{
+		"sent":   "j.sent = 1",
+		"unsent": "COALESCE(j.sent, 0) = 0",
+	},
+}
+
 func ViewLinkedInJobs(db *sql.DB, w http.ResponseWriter, r *http.Request) {
-	rows, err := db.Query("SELECT id, job_id, job_link FROM linkedin_job_application_links")
+	params, err := listquery.Parse(r, linkListSpec)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	total, err := params.Count(db, linkListSpec)
+	if err != nil {
+		http.Error(w, "Failed to count jobs", http.StatusInternalServerError)
+		return
+	}
+
+	query, args := params.Query(linkListSpec, "l.id, l.job_id, l.job_link")
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		http.Error(w, "Failed to fetch jobs", http.StatusInternalServerError)
 		return
 	}
 	defer rows.Close()
 
-	var jobs []Joblinks
+	page := params.NewPage()
+	jobs := []Joblinks{}
 	for rows.Next() {
 		var job Joblinks
-		if err := rows.Scan(&job.ID, &job.JobID, &job.Link); err != nil {
+		ok, err := page.Scan(rows, &job.ID, &job.JobID, &job.Link)
+		if err != nil {
 			http.Error(w, "Failed to scan job", http.StatusInternalServerError)
 			return
 		}
-		jobs = append(jobs, job)
+		if ok {
+			jobs = append(jobs, job)
+		}
 	}
 
-	// Convert to JSON and return response
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(jobs)
+	listquery.WriteJSON(w, page.Envelope(jobs, total))
 }
 
 func ViewLinkedInFailedJobs(db *sql.DB, w http.ResponseWriter, r *http.Request) {
@@ -206,23 +283,60 @@ type JobDescription struct {
 	Skills      []string `json:"skills"`
 }
 
+// descriptionListSpec drives the list params of ViewLinkedInJobDescriptions
+var descriptionListSpec = listquery.Spec{
+	From: "linkedin_job_description d LEFT JOIN linkedin_jobs j ON j.id = d.job_id",
+	Sorts: map[string]string{
+		"id":            "d.id",
+		"summarized_at": "COALESCE(d.summarized_at, '')",
+		"company":       "COALESCE(j.company, '')",
+		"location":      "COALESCE(j.location, '')",
+	},
+	DefaultSort: "id",
+	TieBreaker:  "d.id",
+	Source:      "'LinkedIn'",
+	Company:     "j.company",
+	Location:    "j.location",
+	Date:        "COALESCE(date(d.summarized_at), date(j.processed_at))",
+	Statuses: map[string]string{
+		"pending": "d.summary_status = 'pending'",
+		"running": "d.summary_status = 'running'",
+		"done":    "d.summary_status = 'done'",
+		"failed":  "d.summary_status = 'failed'",
+	},
+}
+
 func ViewLinkedInJobDescriptions(db *sql.DB, w http.ResponseWriter, r *http.Request) {
-	rows, err := db.Query(`
-		SELECT id, job_id, job_link, job_description, job_type, skills 
-		FROM linkedin_job_description
-	`)
+	params, err := listquery.Parse(r, descriptionListSpec)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	total, err := params.Count(db, descriptionListSpec)
+	if err != nil {
+		http.Error(w, "Failed to count job descriptions", http.StatusInternalServerError)
+		return
+	}
+
+	query, args := params.Query(descriptionListSpec, `
+		d.id, d.job_id, d.job_link, COALESCE(d.job_description, ''), COALESCE(d.job_type, ''), COALESCE(d.skills, '')`)
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		http.Error(w, "Failed to fetch job descriptions", http.StatusInternalServerError)
 		return
 	}
 	defer rows.Close()
 
-	var jobs []JobDescription
+	page := params.NewPage()
+	jobs := []JobDescription{}
+	rawSkills := make(map[string]string)
+	var jobIDs []string
 	for rows.Next() {
 		var job JobDescription
 		var skillsStr string
 
-		err := rows.Scan(
+		ok, err := page.Scan(rows,
 			&job.ID,
 			&job.JobID,
 			&job.JobLink,
@@ -234,15 +348,29 @@ func ViewLinkedInJobDescriptions(db *sql.DB, w http.ResponseWriter, r *http.Requ
 			http.Error(w, "Failed to scan job description", http.StatusInternalServerError)
 			return
 		}
-
-		// Convert comma-separated skills into slice
-		if skillsStr != "" {
-			job.Skills = strings.Split(skillsStr, ", ")
+		if !ok {
+			continue
 		}
 
+		rawSkills[job.JobID] = skillsStr
+		jobIDs = append(jobIDs, job.JobID)
 		jobs = append(jobs, job)
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(jobs)
+	normalized, err := skills.NamesByJob(db, "LinkedIn", jobIDs)
+	if err != nil {
+		http.Error(w, "Failed to fetch job skills", http.StatusInternalServerError)
+		return
+	}
+
+	// Prefer canonical skills; fall back to the raw comma-separated column
+	for i := range jobs {
+		if names, ok := normalized[jobs[i].JobID]; ok {
+			jobs[i].Skills = names
+		} else if raw := rawSkills[jobs[i].JobID]; raw != "" {
+			jobs[i].Skills = strings.Split(raw, ", ")
+		}
+	}
+
+	listquery.WriteJSON(w, page.Envelope(jobs, total))
 }
@@ -4,17 +4,16 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"time"
 	"database/sql"
 	"strings"
 	//"path/filepath"
 	 "regexp"
 	"github.com/chromedp/chromedp"
-	"bytes"
-	"encoding/json"
-	"io"
-	"net/http"
 	"github.com/joho/godotenv"
+
+	"job_scraper/scraper/browser"
+	"job_scraper/scraper/pacing"
+	"job_scraper/scraper/selectors"
 )
 
 
@@ -22,7 +21,8 @@ import (
 func init() {
 	err := godotenv.Load()
 	if err != nil {
-		log.Fatal("❌ Error loading .env file")
+		// Not fatal: the environment may come from the process (tests, containers)
+		log.Printf("⚠️ No .env file loaded, using the process environment: %v", err)
 	}
 }
 func cleanJobDescription(raw string) string {
@@ -48,12 +48,23 @@ func cleanJobDescription(raw string) string {
     return result
 }
 
-func navigateAndClickApply(ctx context.Context, db *sql.DB, jobID string, jobLink string) error {
+func navigateAndClickApply(ctx context.Context, db *sql.DB, run *selectors.Run, jobID string, jobLink string) error {
 	// 1. Navigate to the job posting
+	pacer := pacing.For(site.Name)
 	err := chromedp.Run(ctx,
-		chromedp.Navigate(jobLink),
-		chromedp.Sleep(5*time.Second),
+		chromedp.Navigate(jobLink), // paced by the caller, outside the job timeout
+		browser.ExpectNormalPage(site),
 	)
+	if pe, ok := browser.AsPageError(err); ok {
+		if pe.State.Blocking() {
+			// The run pauses; nothing is recorded against this job
+			return err
+		}
+		log.Printf("🗑️ Job %s is no longer available: %s\n", jobID, pe.URL)
+		StoreFailedJob(db, jobID, jobLink, "Job expired")
+		markExpired(db, jobID)
+		return err
+	}
 	if err != nil {
 		log.Printf("❌ Failed to navigate to job: %s -> %v\n", jobID, err)
 		StoreFailedJob(db, jobID, jobLink, "Navigation failed")
@@ -63,7 +74,8 @@ func navigateAndClickApply(ctx context.Context, db *sql.DB, jobID string, jobLin
 	// 2. Extract raw job description
 	var rawDescription string
 	err = chromedp.Run(ctx,
-		chromedp.Text(`#job-details`, &rawDescription, chromedp.NodeVisible, chromedp.ByID),
+		run.Wait(selectors.DetailDescription, pacer.WaitVisible),
+		run.Text(selectors.DetailDescription, &rawDescription),
 	)
 	if err != nil || strings.TrimSpace(rawDescription) == "" {
 		log.Printf("❌ Failed to extract job description for jobID %s: %v\n", jobID, err)
@@ -75,26 +87,18 @@ func navigateAndClickApply(ctx context.Context, db *sql.DB, jobID string, jobLin
 	cleanedDescription := cleanJobDescription(rawDescription)
 	// log.Printf("📄 Cleaned Description:\n%s\n", cleanedDescription)
 
-	// 3. Use Hugging Face to summarize and structure the description
-	summary, err := extractStructuredSummary(cleanedDescription)
-	if err != nil {
-		log.Printf("⚠️ Failed to summarize job description for jobID %s: %v\n", jobID, err)
-	}
-
-	log.Printf("📦 Summary:\n%s\n", summary)
-
-	
-	// 4. Store the job description (structured or raw)
-	err = storeJobDescription(db, jobID, jobLink, strings.TrimSpace(summary))
+	// 3. Store the raw description; the summarizer pool structures it later
+	err = storeRawDescription(db, jobID, jobLink, cleanedDescription)
 	if err != nil {
 		log.Printf("❌ Failed to store job description for jobID %s: %v\n", jobID, err)
 		return err
 	}
 	
-	// 5. Attempt to click Apply button AFTER extraction
+	// 4. Attempt to click Apply button AFTER extraction
 	err = chromedp.Run(ctx,
-		chromedp.Click(`div.jobs-apply-button--top-card button`, chromedp.NodeVisible),
-		chromedp.Sleep(3*time.Second),
+		pacer.Think(),
+		run.Click(selectors.DetailApply),
+		pacer.Think(), // let the application tab open
 	)
 	if err != nil {
 		log.Printf("⚠️ Apply button not found for jobID %s: %v\n", jobID, err)
@@ -105,192 +109,43 @@ func navigateAndClickApply(ctx context.Context, db *sql.DB, jobID string, jobLin
 	return nil
 }
 
-func extractStructuredSummary(jobDescription string) (string, error) {
-	output, err := callOllamaAPI(jobDescription)
-	if err != nil || output == "" {
-		log.Printf("⚠️ Ollama call failed: %v\n", err)
-		return "", err
-	}
-
-	// log.Printf("📤 Ollama Raw Output:\n%s\n", output)
-
-	start := strings.Index(output, "{")
-	end := strings.LastIndex(output, "}")
-	if start != -1 && end != -1 && end > start {
-		jsonPart := output[start : end+1]
-		// log.Printf("🧪 Extracted JSON:\n%s\n", jsonPart)
-		return jsonPart, nil
-	}
-
-
-	// Manual fallback
-	summary := FlexibleJobSummary{
-		JobType:     extractAfter(output, "Job Type:", "\n"),
-		Skills:      splitSkills(extractAfter(output, "Skills Required:", "\n")),
-		Description: extractAfter(output, "Description:", ""),
-	}
-
-	jsonBytes, err := json.Marshal(summary)
+// markExpired keeps an expired job from being opened again; linkedin_failed_jobs says why
+func markExpired(db *sql.DB, jobID string) {
+	_, err := db.Exec(`UPDATE linkedin_jobs SET processed = TRUE, processed_at = CURRENT_TIMESTAMP WHERE id = ?`, jobID)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal fallback JSON: %v", err)
+		log.Printf("❌ Failed to mark expired job %s as processed: %v\n", jobID, err)
 	}
- 
-	return string(jsonBytes), nil
 }
 
-func callOllamaAPI(jobDescription string) (string, error) {
-	prompt := fmt.Sprintf(`
-	Extract and return the following from this job posting as JSON:
-	{
-	  "job_type": "One word like Remote, On-site, or Hybrid",
-	  "skills": ["List at least 5 key technical skills or tools"],
-	  "description": "Professional summary of the role in full sentences(20 lines or 500 words)"
-	}
-	
-	Only return valid JSON. No extra text.
-	
-	Job posting:
-	"%s"
-	`, jobDescription)
-	
-
-
-	payload := map[string]interface{}{
-		"model":  "mistral",
-		"prompt": prompt,
-		"stream": false,
-	}
-
-	payloadBytes, err := json.Marshal(payload)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request payload: %v", err)
-	}
-
-	req, err := http.NewRequest("POST", "http://localhost:11434/api/generate", bytes.NewBuffer(payloadBytes))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %v", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("ollama request failed: %v", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+// Store the cleaned description for the summarizer pool, mark it pending and the job processed.
+// A re-scrape with unchanged text keeps the existing summary; changed text starts over with
+// fresh attempts, even after earlier ones failed.
+func storeRawDescription(db *sql.DB, jobID, jobLink, description string) error {
+	insertQuery := `
+		INSERT INTO linkedin_job_description 
+		(job_id, job_link, raw_description, summary_status) 
+		VALUES (?, ?, ?, 'pending')
+		ON CONFLICT (job_id) DO UPDATE SET
+			raw_description = excluded.raw_description,
+			summary_status = CASE
+				WHEN raw_description IS excluded.raw_description THEN summary_status
+				ELSE 'pending'
+			END,
+			summary_attempts = CASE
+				WHEN raw_description IS excluded.raw_description THEN summary_attempts
+				ELSE 0
+			END
+	`
+	_, err := db.Exec(insertQuery, jobID, jobLink, description)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %v", err)
+		return fmt.Errorf("failed to insert job description: %v", err)
 	}
 
-	var response OllamaResponse
-	err = json.Unmarshal(body, &response)
+	// The detail page has been scraped; processed_at feeds the time-to-process stats
+	_, err = db.Exec(`UPDATE linkedin_jobs SET processed = TRUE, processed_at = CURRENT_TIMESTAMP WHERE id = ?`, jobID)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse Ollama response: %v", err)
-	}
-
-	return response.Response, nil
-}
-
-
-
-// Struct to hold the API response from Ollama
-type OllamaResponse struct {
-	Response string `json:"response"`
-}
-
-// Struct for the job summary details
-type FlexibleJobSummary struct {
-	JobType     string   `json:"job_type"`
-	Skills      []string `json:"skills"`
-	Description string   `json:"description"`
-}
-
-// Unmarshal the JSON response from Ollama
-func (f *FlexibleJobSummary) UnmarshalJSON(data []byte) error {
-	type Alias FlexibleJobSummary
-	aux := &struct {
-		Skills interface{} `json:"skills"`
-		*Alias
-	}{
-		Alias: (*Alias)(f),
-	}
-
-	if err := json.Unmarshal(data, &aux); err != nil {
-		return err
-	}
-
-	// Normalize the `skills` field
-	switch v := aux.Skills.(type) {
-	case []interface{}:
-		for _, s := range v {
-			if str, ok := s.(string); ok {
-				f.Skills = append(f.Skills, str)
-			}
-		}
-	case map[string]interface{}:
-		for k := range v {
-			f.Skills = append(f.Skills, k)
-		}
-	case string:
-		f.Skills = []string{v}
-	default:
-		f.Skills = []string{}
+		return fmt.Errorf("failed to mark job as processed: %v", err)
 	}
 
 	return nil
 }
-
-func extractAfter(text, key, end string) string {
-	idx := strings.Index(text, key)
-	if idx == -1 {
-		return ""
-	}
-	start := idx + len(key)
-	if end == "" {
-		return strings.TrimSpace(text[start:])
-	}
-	endIdx := strings.Index(text[start:], end)
-	if endIdx == -1 {
-		return strings.TrimSpace(text[start:])
-	}
-	return strings.TrimSpace(text[start : start+endIdx])
-}
-
-func splitSkills(raw string) []string {
-	raw = strings.ReplaceAll(raw, "•", "")
-	parts := strings.Split(raw, ",")
-	var skills []string
-	for _, s := range parts {
-		skill := strings.TrimSpace(s)
-		if skill != "" {
-			skills = append(skills, skill)
-		}
-	}
-	return skills
-}
-// Store the summary as the job description and mark job as processed
-func storeJobDescription(db *sql.DB, jobID, jobLink, summary string) error {
-	var parsed FlexibleJobSummary
-	err := json.Unmarshal([]byte(summary), &parsed)
-	if err != nil {
-		log.Printf("❌ Unmarshal failed for summary:\n%s\n", summary)
-		return fmt.Errorf("failed to parse structured summary: %v", err)
-	}
-
-	skillsCSV := strings.Join(parsed.Skills, ", ")
-
-	insertQuery := `
-		INSERT OR IGNORE INTO linkedin_job_description 
-		(job_id, job_link, job_description, job_type, skills) 
-		VALUES (?, ?, ?, ?, ?)
-	`
-	_, err = db.Exec(insertQuery, jobID, jobLink, parsed.Description, parsed.JobType, skillsCSV)
-	if err != nil {
-		return fmt.Errorf("failed to insert job description: %v", err)
-	}
-
-	return nil
-}
\ No newline at end of file
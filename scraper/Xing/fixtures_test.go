@@ -0,0 +1,218 @@
+package Xing
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+
+	"job_scraper/internal/testutil/fixtures"
+	"job_scraper/scraper/browser"
+	"job_scraper/scraper/selectors"
+)
+
+// Job pages are trimmed by hand: an ad does not stay online long enough to re-record
+var pages = []fixtures.Page{
+	{Path: "/jobs/search", File: "search.html", LiveURL: "https://www.xing.com/jobs/search?keywords=Backend%20Engineer&location=Berlin"},
+	{Path: "/jobs/berlin-backend-engineer-go-123456", File: "job.html"},
+	{Path: "/jobs/berlin-senior-backend-developer-234567", File: "expired.html"},
+	{Path: "/jobs/potsdam-platform-engineer-345678", File: "rate_limited.html"},
+	{Path: "/careers/nordlicht/backend-engineer", File: "apply.html"},
+}
+
+// replay starts Chromium and the fixture server and points the search at it
+func replay(t *testing.T) (context.Context, *fixtures.Server) {
+	fixtures.Isolate(t, site.Name)
+	ctx := fixtures.Browser(t)
+	srv := fixtures.Serve(t, ctx, pages...)
+
+	live := jobSearchURL
+	jobSearchURL = srv.URL + "/jobs/search"
+	t.Cleanup(func() { jobSearchURL = live })
+	return ctx, srv
+}
+
+// openJob runs a job the way LoginXingHandler does: in a tab of its own, with the
+// tabs open before it remembered so the application tab can be told apart
+func openJob(t *testing.T, ctx context.Context, db *sql.DB, jobID, link string) error {
+	t.Helper()
+	if _, err := db.Exec(`INSERT INTO xing_jobs (id, jobid, title, link, processed) VALUES (?, ?, 'Fixture', ?, FALSE)`,
+		jobID, jobID, link); err != nil {
+		t.Fatal(err)
+	}
+
+	jobCtx, cancel := chromedp.NewContext(ctx)
+	defer cancel()
+	jobCtx, cancelTimeout := context.WithTimeout(jobCtx, 30*time.Second)
+	defer cancelTimeout()
+	if err := chromedp.Run(jobCtx); err != nil {
+		t.Fatal(err)
+	}
+	tabs, err := chromedp.Targets(jobCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing := make(map[target.ID]struct{})
+	for _, tab := range tabs {
+		existing[tab.TargetID] = struct{}{}
+	}
+
+	run := selectors.NewRun(site.Name)
+	defer run.Finish(db)
+	if err := navigateAndClickApply(jobCtx, db, run, jobID, link); err != nil {
+		return err
+	}
+	if err := captureAndCloseNewTab(jobCtx, db, jobID, existing); err != nil {
+		t.Fatalf("capture: %v", err)
+	}
+	return nil
+}
+
+// listings scrapes the fixture search with limits and returns the stored jobs, with
+// the server URL cut from their links, and the selector health of the run. The
+// jobs with the known IDs are stored by an earlier run.
+func listings(t *testing.T, limits listingLimits, known ...string) ([]string, []string) {
+	ctx, srv := replay(t)
+	db := fixtures.DB(t)
+	for _, id := range known {
+		if _, err := db.Exec(`INSERT INTO xing_jobs (id, jobid, title, link, processed) VALUES (?, ?, 'Backend Engineer', ?, TRUE)`,
+			"earlier-"+id, id, "https://www.xing.com/jobs/earlier-"+id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := fetchAndStoreXingJobs(ctx, db, []string{"Backend Engineer"}, "Berlin", limits); err != nil {
+		t.Fatal(err)
+	}
+	// "Show more" appends results in place instead of loading another page
+	if got := srv.Requests(); len(got) != 1 || got[0] != "/jobs/search" {
+		t.Errorf("requests = %v, want one search", got)
+	}
+
+	jobs := fixtures.Rows(t, db, `
+		SELECT jobid, company, location, posted_date, employment_type, salary, link, processed FROM xing_jobs ORDER BY jobid`)
+	for i := range jobs {
+		jobs[i] = strings.ReplaceAll(jobs[i], srv.URL, "")
+	}
+	return jobs, fixtures.Rows(t, db, `
+		SELECT field, matches, pages, broken FROM selector_health WHERE source = 'Xing' ORDER BY field`)
+}
+
+var (
+	job123456 = "123456 | Nordlicht Payments GmbH | Berlin | 2025-05-06 | Vollzeit | €60.000 – €75.000 | /jobs/berlin-backend-engineer-go-123456 | false"
+	job234567 = "234567 | Kranich Logistik AG | Berlin | 2025-05-09 | Teilzeit |  | /jobs/berlin-senior-backend-developer-234567 | false"
+	job345678 = "345678 | Havel Cloud GmbH | Potsdam | 2025-04-28 | Vollzeit | €70.000 – €85.000 | /jobs/potsdam-platform-engineer-345678 | false"
+	job456789 = "456789 | Spree Software GmbH | Berlin | 2025-04-22 | Vollzeit |  | /jobs/berlin-go-developer-456789 | false"
+	job567890 = "567890 | Tegel Mobility GmbH | Berlin | 2025-04-18 | Werkstudent | €18 pro Stunde | /jobs/berlin-software-engineer-backend-567890 | false"
+	job678901 = "678901 | Wannsee Data GmbH | Berlin | 2025-04-15 | Vollzeit |  | /jobs/berlin-api-engineer-678901 | false"
+	job789012 = "789012 | Müggel Infra GmbH | Berlin | 2025-04-02 | Freiberuflich |  | /jobs/berlin-devops-engineer-789012 | false"
+)
+
+func TestFixtureListingsPageThroughAllResults(t *testing.T) {
+	jobs, health := listings(t, listingLimits{MaxResults: 50})
+
+	// The repeated sponsored result on the last page is a duplicate; the second
+	// teaser only matches through the fallback selectors
+	fixtures.Equal(t, "xing_jobs", jobs, []string{job123456, job234567, job345678, job456789, job567890, job678901, job789012})
+	fixtures.Equal(t, "selector health", health, []string{
+		"listing.card | 8 | 1 | false",
+		"listing.company | 8 | 1 | false",
+		"listing.employment_type | 8 | 1 | false",
+		"listing.link | 8 | 1 | false",
+		"listing.location | 8 | 1 | false",
+		"listing.posted | 8 | 1 | false",
+		"listing.salary | 4 | 1 | false",
+	})
+}
+
+func TestFixtureListingsStopAtTheCap(t *testing.T) {
+	jobs, health := listings(t, listingLimits{MaxResults: 4})
+
+	// The second page takes the new results past the cap, so the third is never loaded
+	fixtures.Equal(t, "xing_jobs", jobs, []string{job123456, job234567, job345678, job456789})
+	if len(health) == 0 || health[0] != "listing.card | 6 | 1 | false" {
+		t.Errorf("selector health = %q, want 6 teasers loaded", health)
+	}
+}
+
+func TestFixtureListingsCapCountsOnlyNewResults(t *testing.T) {
+	jobs, health := listings(t, listingLimits{MaxResults: 5}, "123456", "234567")
+
+	// Two of the six teasers on the first two pages are stored already, so the
+	// third page is loaded for the fifth new result
+	earlier := func(id string) string {
+		return id + " |  |  |  |  |  | https://www.xing.com/jobs/earlier-" + id + " | true"
+	}
+	fixtures.Equal(t, "xing_jobs", jobs, []string{earlier("123456"), earlier("234567"), job345678, job456789, job567890, job678901, job789012})
+	if len(health) == 0 || health[0] != "listing.card | 8 | 1 | false" {
+		t.Errorf("selector health = %q, want 8 teasers loaded", health)
+	}
+}
+
+func TestFixtureListingsStopAtTheDateCutoff(t *testing.T) {
+	jobs, health := listings(t, listingLimits{MaxResults: 50, Since: time.Date(2025, 4, 25, 12, 0, 0, 0, time.UTC)})
+
+	// Everything on the second page is older than the cutoff: it is skipped and
+	// paging stops there
+	fixtures.Equal(t, "xing_jobs", jobs, []string{job123456, job234567, job345678})
+	if len(health) == 0 || health[0] != "listing.card | 6 | 1 | false" {
+		t.Errorf("selector health = %q, want 6 teasers loaded", health)
+	}
+}
+
+func TestFixtureDetailStoresDescriptionAndApplicationLink(t *testing.T) {
+	ctx, srv := replay(t)
+	db := fixtures.DB(t)
+	link := srv.URL + "/jobs/berlin-backend-engineer-go-123456"
+
+	if err := openJob(t, ctx, db, "job-1", link); err != nil {
+		t.Fatal(err)
+	}
+
+	fixtures.Equal(t, "xing_job_description", fixtures.Rows(t, db, `
+		SELECT job_id, job_link, raw_description, summary_status FROM xing_job_description`), []string{
+		"job-1 | " + link + " | Wir bauen die Zahlungsplattform für 3.000 Händler. Du entwickelst Go-Services und betreibst sie auf Kubernetes. | pending",
+	})
+	fixtures.Equal(t, "processed", fixtures.Rows(t, db, `SELECT processed FROM xing_jobs WHERE id = 'job-1'`), []string{"true"})
+	fixtures.Equal(t, "application links", fixtures.Rows(t, db, `
+		SELECT job_id, job_link FROM xing_job_application_links`), []string{
+		"job-1 | " + srv.URL + "/careers/nordlicht/backend-engineer",
+	})
+	if n := fixtures.Count(t, db, `SELECT COUNT(*) FROM xing_failed_jobs`); n != 0 {
+		t.Errorf("%d failed jobs recorded for a job that went through", n)
+	}
+}
+
+func TestFixtureExpiredJobIsRecorded(t *testing.T) {
+	ctx, srv := replay(t)
+	db := fixtures.DB(t)
+	link := srv.URL + "/jobs/berlin-senior-backend-developer-234567"
+
+	err := openJob(t, ctx, db, "job-2", link)
+	if pe, ok := browser.AsPageError(err); !ok || pe.State != browser.PageExpired {
+		t.Fatalf("openJob = %v, want an expired page", err)
+	}
+	fixtures.Equal(t, "xing_failed_jobs", fixtures.Rows(t, db, `
+		SELECT job_id, job_link, reason FROM xing_failed_jobs`), []string{"job-2 | " + link + " | Job expired"})
+	if n := fixtures.Count(t, db, `SELECT COUNT(*) FROM xing_job_description`); n != 0 {
+		t.Errorf("an expired job stored %d descriptions", n)
+	}
+}
+
+func TestFixtureRateLimitRecordsNothingAgainstTheJob(t *testing.T) {
+	ctx, srv := replay(t)
+	db := fixtures.DB(t)
+
+	err := openJob(t, ctx, db, "job-3", srv.URL+"/jobs/potsdam-platform-engineer-345678")
+	if pe, ok := browser.AsPageError(err); !ok || pe.State != browser.PageRateLimited {
+		t.Fatalf("openJob = %v, want a rate-limit page", err)
+	}
+	if n := fixtures.Count(t, db, `SELECT COUNT(*) FROM xing_failed_jobs`); n != 0 {
+		t.Errorf("a rate limit recorded %d failed jobs", n)
+	}
+	fixtures.Equal(t, "processed", fixtures.Rows(t, db, `SELECT processed FROM xing_jobs WHERE id = 'job-3'`), []string{"false"})
+}
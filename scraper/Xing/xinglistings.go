@@ -3,80 +3,60 @@ package Xing
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"net/http"
 	"strings"
 	"time"
-	"runtime"
-	"os/exec"
 	"github.com/chromedp/chromedp"
 	"github.com/google/uuid"
 
+	"job_scraper/scraper/browser"
+	"job_scraper/scraper/pacing"
+	"job_scraper/scraper/pause"
+	"job_scraper/scraper/rotation"
+	"job_scraper/scraper/selectors"
+
 )
 // Job struct for both LinkedIn and Xing
 type Job struct {
-	UUID        string `json:"uuid"`
-	JobID       string `json:"jobId"` // Changed to string to match xing_jobs table
-	Title       string `json:"title"`
-	Company     string `json:"company"`
-	Location    string `json:"location"`
-	PostedDate  string `json:"postedDate"`
-	Link        string `json:"link"`
-	IsEasyApply bool   `json:"isEasyApply"` // Used only for LinkedIn
-	Processed   bool   `json:"processed"`
-}
-
-func StartChrome() (*exec.Cmd, error) {
-	chromePath := "chromium" // or use full path
-
-	if runtime.GOOS != "linux" {
-		return nil, fmt.Errorf("unsupported OS: %s", runtime.GOOS)
-	}
-
-	cmd := exec.Command(chromePath,
-		"--remote-debugging-port=9222",
-		"--profile-directory=Profile 6",
-		"--window-size=800,600", 
-		"https://www.xing.com/",
-	)
-
-	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start Chromium: %w", err)
-	}
-	return cmd, nil
-}
-
-func waitForChromeDebugPort(url string, timeout time.Duration) error {
-	deadline := time.Now().Add(timeout)
-	for time.Now().Before(deadline) {
-		resp, err := http.Get(url + "/json/version")
-		if err == nil && resp.StatusCode == 200 {
-			resp.Body.Close()
-			return nil
-		}
-		time.Sleep(500 * time.Millisecond)
-	}
-	return fmt.Errorf("chrome debugger not responding at %s", url)
+	UUID           string `json:"uuid"`
+	JobID          string `json:"jobId"` // Changed to string to match xing_jobs table
+	Title          string `json:"title"`
+	Company        string `json:"company"`
+	Location       string `json:"location"`
+	PostedDate     string `json:"postedDate"`
+	PostedText     string `json:"postedText"`     // the teaser's "vor 3 Tagen" when it has no date attribute
+	EmploymentType string `json:"employmentType"` // Vollzeit, Teilzeit, ...
+	Salary         string `json:"salary"`         // as shown on the teaser, when it shows one
+	Link           string `json:"link"`
+	IsEasyApply    bool   `json:"isEasyApply"` // Used only for LinkedIn
+	Processed      bool   `json:"processed"`
 }
 
+// jobSearchURL is Xing's job search; the fixture tests point it at recorded pages
+var jobSearchURL = "https://www.xing.com/jobs/search"
 
 func constructXingSearchURL(keywords, location string) string {
 	return fmt.Sprintf(
-		"https://www.xing.com/jobs/search?keywords=%s&location=%s",
+		"%s?keywords=%s&location=%s",
+		jobSearchURL,
 		strings.ReplaceAll(keywords, " ", "%20"),
 		strings.ReplaceAll(location, " ", "%20"),
 	)
 }
+// errDuplicateJob is returned for a job that is already stored
+var errDuplicateJob = errors.New("job already exists")
+
 func insertJobIfNotExists(db *sql.DB, job Job) error {
     _, err := db.Exec(`
-        INSERT INTO xing_jobs (id, jobid, title, company, location, posted_date, link, processed)
-        VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
-        uuid.New().String(), job.JobID, job.Title, job.Company, job.Location, job.PostedDate, job.Link, false,
+        INSERT INTO xing_jobs (id, jobid, title, company, location, posted_date, employment_type, salary, link, processed, scraped_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`,
+        uuid.New().String(), job.JobID, job.Title, job.Company, job.Location, job.PostedDate, job.EmploymentType, job.Salary, job.Link, false,
     )
 
     if err != nil {
         if strings.Contains(err.Error(), "UNIQUE constraint failed") {
-            return fmt.Errorf("❌ Job already exists: %v", err)
+            return fmt.Errorf("❌ %w: %v", errDuplicateJob, err)
         }
         return fmt.Errorf("❌ Failed to insert job: %v", err)
     }
@@ -85,6 +65,12 @@ func insertJobIfNotExists(db *sql.DB, job Job) error {
     return nil
 }
 
+// isStored reports whether a listing link, or the job ID in it, is already in xing_jobs
+func isStored(db *sql.DB, link string) bool {
+	var n int
+	err := db.QueryRow(`SELECT COUNT(*) FROM xing_jobs WHERE jobid = NULLIF(?, '') OR link = ?`, extractXingJobID(link), link).Scan(&n)
+	return err == nil && n > 0
+}
 
 func extractXingJobID(link string) string {
 	if link == "" {
@@ -108,96 +94,114 @@ func extractXingJobID(link string) string {
 	return lastPart
 }
 
-func fetchAndStoreXingJobs(ctx context.Context, db *sql.DB, jobTitles []string, location string) error {
+// listingPulls are the values read from every search result, keyed like Job
+var listingPulls = []selectors.Pull{
+	{Key: "link", Field: selectors.ListingLink, Prop: "href"},
+	{Key: "company", Field: selectors.ListingCompany},
+	{Key: "location", Field: selectors.ListingLocation},
+	{Key: "postedDate", Field: selectors.ListingPosted, Attr: "datetime"},
+	{Key: "postedText", Field: selectors.ListingPosted},
+	{Key: "employmentType", Field: selectors.ListingEmployment},
+	{Key: "salary", Field: selectors.ListingSalary},
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "Unknown"
+	}
+	return s
+}
+
+func fetchAndStoreXingJobs(ctx context.Context, db *sql.DB, jobTitles []string, location string, limits listingLimits) error {
+	pacer := pacing.For(site.Name)
+	rotator := rotation.Default()
+	run := selectors.NewRun(site.Name)
+	defer run.Finish(db)
 	for _, title := range jobTitles {
 		searchURL := constructXingSearchURL(title, location)
 		var jobs []Job
+		var stats titleStats
 
-		err := chromedp.Run(ctx,
-			chromedp.Navigate(searchURL),
-			chromedp.Sleep(4*time.Second), // Give the page some time to load
+		// Each search runs in its own tab under the next proxy and browser identity
+		err := rotator.Scrape(ctx, func(ctx context.Context) error {
+			stats = titleStats{}
+			return chromedp.Run(ctx,
+				pacer.Navigate(searchURL),
+				browser.ExpectNormalPage(site),
 
-			// Scroll to bottom to trigger lazy loading
-			chromedp.ActionFunc(func(ctx context.Context) error {
-				return chromedp.Evaluate(`window.scrollTo(0, document.body.scrollHeight);`, nil).Do(ctx)
-			}),
-			chromedp.Sleep(2*time.Second),
-			chromedp.ActionFunc(func(ctx context.Context) error {
-				return chromedp.Evaluate(`window.scrollTo(0, document.body.scrollHeight);`, nil).Do(ctx)
-			}),
-			chromedp.Sleep(2*time.Second),
-			chromedp.ActionFunc(func(ctx context.Context) error {
-				return chromedp.Evaluate(`window.scrollTo(0, document.body.scrollHeight);`, nil).Do(ctx)
-			}),
-			chromedp.Sleep(2*time.Second),
-			chromedp.ActionFunc(func(ctx context.Context) error {
-				return chromedp.Evaluate(`window.scrollTo(0, document.body.scrollHeight);`, nil).Do(ctx)
-			}),
-			chromedp.Sleep(2*time.Second),
-			chromedp.ActionFunc(func(ctx context.Context) error {
-				return chromedp.Evaluate(`window.scrollTo(0, document.body.scrollHeight);`, nil).Do(ctx)
-			}),
-			chromedp.Sleep(2*time.Second),
-			chromedp.Sleep(2*time.Second),
-			chromedp.ActionFunc(func(ctx context.Context) error {
-				return chromedp.Evaluate(`window.scrollTo(0, document.body.scrollHeight);`, nil).Do(ctx)
-			}),
-			chromedp.Sleep(2*time.Second),
-			// Wait for job listings to appear
-			chromedp.WaitVisible(`[data-testid="job-search-result"]`, chromedp.ByQuery),
-			
+				// Wait for job listings, then page through them with "show more"
+				run.Wait(selectors.ListingCard, pacer.WaitVisible),
+				loadResults(pacer, run, limits, &stats, func(link string) bool { return isStored(db, link) }),
 
-			// Extract job data
-			chromedp.Evaluate(`Array.from(document.querySelectorAll('[data-testid="job-search-result"]')).map(el => ({
-				title: "`+title+`",
-				link: 'https://www.xing.com' + (el.getAttribute('href') || ''),
-				company: el.querySelector('[data-xds="BodyCopy"].job-teaser-list-item-styles__Company-sc-4c7b5190-7')?.innerText.trim() || 'Unknown',
-				location: el.querySelector('[data-xds="BodyCopy"].job-teaser-list-item-styles__City-sc-4c7b5190-6')?.innerText.trim() || 'Unknown',
-				postedDate: '',
-				isEasyApply: false
-			}))`, &jobs),
-		)
+				// Extract job data
+				run.Extract(selectors.ListingCard, listingPulls, &jobs),
+			)
+		})
+		if errors.Is(err, rotation.ErrNoHealthyProxy) {
+			return err
+		}
 
+		if pe, ok := browser.AsPageError(err); ok && pe.State.Blocking() {
+			// Every remaining title would hit the same wall
+			pacer.Record(pacing.Blocked)
+			return err
+		}
 		if err != nil {
+			pacer.Record(pacing.Failure)
 			fmt.Printf("❌ Failed to fetch Xing jobs for %s: %v\n", title, err)
 			continue
 		}
+		pacer.Record(pacing.Success)
 
-		count := 0
+		stats.Results = len(jobs)
+		now := time.Now()
 		for _, job := range jobs {
-			if job.Link != "" {
-				job.JobID = extractXingJobID(job.Link)
-				if err := insertJobIfNotExists(db, job); err != nil {
+			job.Title = title
+			job.Company = orUnknown(job.Company)
+			job.Location = orUnknown(job.Location)
+			job.PostedDate = postedDate(job.PostedDate, job.PostedText, now)
+			if job.Link == "" {
+				continue
+			}
+			if limits.tooOld(job.PostedDate) {
+				stats.TooOld++
+				continue
+			}
+			job.JobID = extractXingJobID(job.Link)
+			if err := insertJobIfNotExists(db, job); err != nil {
+				if errors.Is(err, errDuplicateJob) {
+					stats.Duplicates++
+				} else {
 					fmt.Printf("⚠️ Insert error for job %s: %v\n", job.Link, err)
-					continue
-				}
-				count++
-				if count >= 2 {
-					break
 				}
+				continue
+			}
+			stats.Stored++
+			if stats.Stored >= limits.MaxResults {
+				break
 			}
 		}
-		fmt.Printf("✅ Stored %d new Xing jobs for %s (excluding duplicates)\n", count, title)
+		fmt.Printf("✅ Stored %d new Xing jobs for %s (%s)\n", stats.Stored, title, stats)
 	}
 	return nil
 }
 
 func XingJobListingsHandler(ctx context.Context, db *sql.DB) error {
-	// Start headless Chrome
-	cmd, err := StartChrome()
-	if err != nil {
-		return fmt.Errorf("failed to start Chrome: %w", err)
+	if err := pause.Check(db, site.Name); err != nil {
+		return err
 	}
-	defer cmd.Process.Kill()
 
-	// Wait for Chrome to be ready for remote debugging
-	err = waitForChromeDebugPort("http://localhost:9222", 10*time.Second)
+	// Start or attach to the Xing browser; Acquire waits until it answers on its debug port
+	b, err := browser.Default().Acquire(ctx, site.Profile, browser.Options{
+		Flags: []string{"--window-size=800,600"},
+	})
 	if err != nil {
-		return fmt.Errorf("chrome debugger not ready: %w", err)
+		return fmt.Errorf("failed to start Chrome: %w", err)
 	}
+	defer b.Release()
 
 	// Create ChromeDP context connected to the remote instance
-	allocatorCtx, cancel := chromedp.NewRemoteAllocator(ctx, "http://localhost:9222")
+	allocatorCtx, cancel := chromedp.NewRemoteAllocator(ctx, b.DebugURL())
 	defer cancel()
 
 	chromeCtx, cancelChrome := chromedp.NewContext(allocatorCtx)
@@ -371,6 +375,12 @@ func XingJobListingsHandler(ctx context.Context, db *sql.DB) error {
 	location := "Berlin, Germany"
 
 	// Fetch and store Xing jobs
-	return fetchAndStoreXingJobs(chromeCtx, db, jobTitles, location)
+	err = fetchAndStoreXingJobs(chromeCtx, db, jobTitles, location, limitsFromEnv())
+	if pe, ok := browser.AsPageError(err); ok && pe.State.Blocking() {
+		if perr := pause.BlockPage(db, pe, ""); perr != nil {
+			fmt.Printf("❌ %v\n", perr)
+		}
+	}
+	return err
 }
 
@@ -7,48 +7,34 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"os/exec"
-	"runtime"
 	"time"
 
 	"github.com/chromedp/chromedp"
 	"github.com/chromedp/cdproto/target"
+
+	"job_scraper/scraper/browser"
+	"job_scraper/scraper/listquery"
+	"job_scraper/scraper/pacing"
+	"job_scraper/scraper/pause"
+	"job_scraper/scraper/selectors"
 )
 
-// Global to track the Chromium process
-var chromeCmd *exec.Cmd
+// site is the browser profile and session the Xing scrapers share, so the
+// session logged in there is used for listings and details alike
+var site = browser.Xing
 
 // LoginXingHandler opens Xing with an authenticated profile and waits for main menu/dashboard
 func LoginXingHandler(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 	fmt.Println("🚀 Launching Xing via Chromium...")
 
-	const (
-		chromePath           = "chromium" // or "google-chrome"
-		remoteDebuggingPort  = "9222"
-		supportedOS          = "linux"
-		profileDir           = "Profile 2"
-		startupWaitDuration  = 5 * time.Second
-	)
-	XingUrl := "https://www.Xing.com/"
-	if runtime.GOOS != supportedOS {
-		http.Error(w, "Unsupported OS (only Linux is currently supported)", http.StatusInternalServerError)
-		return
-	}
-
-	// Start Chromium without any default URL
-	chromeCmd = exec.Command(chromePath,
-		"--remote-debugging-port="+remoteDebuggingPort,
-		"--profile-directory="+profileDir,
-		"--new-window",
-		XingUrl,
-	)
-	if err := chromeCmd.Start(); err != nil {
+	b, err := browser.Default().Acquire(r.Context(), site.Profile, browser.Options{})
+	if err != nil {
 		log.Printf("❌ Failed to start Chromium: %v\n", err)
 		http.Error(w, "Failed to start Chromium", http.StatusInternalServerError)
 		return
 	}
+	defer b.Release()
 	fmt.Println("✅ Chrome launched successfully.")
-	time.Sleep(startupWaitDuration)
 
 	// Load job links from the database
 	jobLinks, err := LoadJobLinksFromDB(db)
@@ -68,20 +54,44 @@ func LoginXingHandler(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 	log.Printf("📊 Total job links loaded: %d\n", totalLinks)
 
 	// Create a ChromeDP context
-	allocatorCtx, cancelAllocator := chromedp.NewRemoteAllocator(context.Background(), "http://localhost:"+remoteDebuggingPort)
+	allocatorCtx, cancelAllocator := chromedp.NewRemoteAllocator(context.Background(), b.DebugURL())
 	defer cancelAllocator()
 
 
-	_, cancelCtx := chromedp.NewContext(allocatorCtx)
+	rootCtx, cancelCtx := chromedp.NewContext(allocatorCtx)
 	defer cancelCtx()
-	
-	// Optional: check that browser is responsive
+
+	// Details need a logged-in session and no pause; stop before any job is marked or failed
+	if err := pause.Start(rootCtx, db, browser.Default(), site); err != nil {
+		log.Printf("❌ %v\n", err)
+		status := http.StatusInternalServerError
+		if pause.IsPaused(err) {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
 
 	// Process all job links
+	// A wall (authwall, checkpoint, rate limit) pauses the whole run: every later job
+	// would hit it too, and none of them is at fault
+	var blocked *browser.PageError
+	var blockedJob string
+	processed := 0
+	pacer := pacing.For(site.Name)
+	selRun := selectors.NewRun(site.Name)
+	defer selRun.Finish(db)
+
+run:
 	for title, jobs := range jobLinks {
 		fmt.Printf("📌 Processing jobs for: %s\n", title)
 	
 		for _, job := range jobs {
+			// A client that went away ends the run; the jobs left stay unprocessed
+			if blocked != nil || r.Context().Err() != nil {
+				break run
+			}
+			processed++
 			func() {
 				defer func() {
 					if r := recover(); r != nil {
@@ -94,7 +104,7 @@ func LoginXingHandler(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 	
 				// ✅ Mark job as "being processed" immediately
 				updateQuery := `
-					UPDATE linkedin_jobs 
+					UPDATE xing_jobs 
 					SET processed = TRUE 
 					WHERE id = ?
 				`
@@ -104,6 +114,11 @@ func LoginXingHandler(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 					// Optional: return here if you want to skip on failure
 				}
 	
+				// Wait for a navigation token before the job's timeout starts ticking
+				if err := pacer.Wait(r.Context(), job.Link); err != nil {
+					return
+				}
+
 				// 🎯 Create new Chrome context
 				jobCtxBase, cancelBase := chromedp.NewContext(allocatorCtx)
 				defer cancelBase()
@@ -124,7 +139,20 @@ func LoginXingHandler(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 					existingTabs[t.TargetID] = struct{}{}
 				}
 	
-				if err := navigateAndClickApply(jobCtx, db, jobIDStr, job.Link); err != nil {
+				err = navigateAndClickApply(jobCtx, db, selRun, jobIDStr, job.Link)
+				pe, isPage := browser.AsPageError(err)
+				switch {
+				case isPage && pe.State.Blocking():
+					pacer.Record(pacing.Blocked)
+					blocked, blockedJob = pe, jobIDStr
+					return
+				case err != nil && !isPage:
+					pacer.Record(pacing.Failure)
+				default:
+					pacer.Record(pacing.Success) // expired ads included: the site answered normally
+				}
+
+				if err != nil {
 					log.Printf("⚠️ Skipping job %s - navigation/apply failed: %v\n", jobIDStr, err)
 				} else {
 					if err := captureAndCloseNewTab(jobCtx, db, jobIDStr, existingTabs); err != nil {
@@ -138,12 +166,16 @@ func LoginXingHandler(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 	}
 	
 
-	// Stop Chromium process
-	fmt.Println("🛑 Closing Chromium...")
-	if err := chromeCmd.Process.Kill(); err != nil {
-		fmt.Printf("⚠️ Failed to close Chromium: %v\n", err)
-	} else {
-		fmt.Println("✅ Chromium closed successfully.")
+	if blocked != nil {
+		// The job was marked processed before it was opened; give it back to the next run
+		if _, err := db.Exec(`UPDATE xing_jobs SET processed = FALSE WHERE id = ?`, blockedJob); err != nil {
+			log.Printf("❌ Failed to reset job %s: %v\n", blockedJob, err)
+		}
+		if err := pause.BlockPage(db, blocked, blockedJob); err != nil {
+			log.Printf("❌ %v\n", err)
+		}
+		http.Error(w, fmt.Sprintf("Xing run paused after %d of %d jobs: %v", processed-1, totalLinks, blocked), http.StatusConflict)
+		return
 	}
 
 	// Send JSON response
@@ -159,26 +191,63 @@ type XingJobLinkDTO struct {
 	JobLink string `json:"job_link"`
 }
 
+// linkListSpec drives the list params of ViewXingJobs
+var linkListSpec = listquery.Spec{
+	From: "xing_job_application_links l LEFT JOIN xing_jobs j ON j.id = l.job_id",
+	Sorts: map[string]string{
+		"id":       "l.id",
+		"title":    "COALESCE(j.title, '')",
+		"company":  "COALESCE(j.company, '')",
+		"location": "COALESCE(j.location, '')",
+	},
+	DefaultSort: "id",
+	TieBreaker:  "l.id",
+	Source:      "'Xing'",
+	Company:     "j.company",
+	Location:    "j.location",
+	Date:        "date(j.processed_at)",
+	Statuses: map[string]string{
+		"sent":   "j.sent = 1",
+		"unsent": "COALESCE(j.sent, 0) = 0",
+	},
+}
+
 func ViewXingJobs(db *sql.DB, w http.ResponseWriter, r *http.Request) {
-	rows, err := db.Query("SELECT id, job_id, job_link FROM xing_job_application_links")
+	params, err := listquery.Parse(r, linkListSpec)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	total, err := params.Count(db, linkListSpec)
+	if err != nil {
+		http.Error(w, "Failed to count jobs", http.StatusInternalServerError)
+		return
+	}
+
+	query, args := params.Query(linkListSpec, "l.id, l.job_id, l.job_link")
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		http.Error(w, "Failed to fetch jobs", http.StatusInternalServerError)
 		return
 	}
 	defer rows.Close()
 
-	var jobs []XingJobLinkDTO
+	page := params.NewPage()
+	jobs := []XingJobLinkDTO{}
 	for rows.Next() {
 		var job XingJobLinkDTO
-		if err := rows.Scan(&job.ID, &job.JobID, &job.JobLink); err != nil {
+		ok, err := page.Scan(rows, &job.ID, &job.JobID, &job.JobLink)
+		if err != nil {
 			http.Error(w, "Failed to scan job", http.StatusInternalServerError)
 			return
 		}
-		jobs = append(jobs, job)
+		if ok {
+			jobs = append(jobs, job)
+		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(jobs)
+	listquery.WriteJSON(w, page.Envelope(jobs, total))
 }
 
 
@@ -192,24 +261,64 @@ type XingJobDescription struct {
 }
 
 
+// descriptionListSpec drives the list params of ViewXingJobDescriptions
+var descriptionListSpec = listquery.Spec{
+	From: "xing_job_description d LEFT JOIN xing_jobs j ON j.id = d.job_id",
+	Sorts: map[string]string{
+		"id":            "d.id",
+		"summarized_at": "COALESCE(d.summarized_at, '')",
+		"company":       "COALESCE(j.company, '')",
+		"location":      "COALESCE(j.location, '')",
+	},
+	DefaultSort: "id",
+	TieBreaker:  "d.id",
+	Source:      "'Xing'",
+	Company:     "j.company",
+	Location:    "j.location",
+	Date:        "COALESCE(date(d.summarized_at), date(j.processed_at))",
+	Statuses: map[string]string{
+		"pending": "d.summary_status = 'pending'",
+		"running": "d.summary_status = 'running'",
+		"done":    "d.summary_status = 'done'",
+		"failed":  "d.summary_status = 'failed'",
+	},
+}
+
 func ViewXingJobDescriptions(db *sql.DB, w http.ResponseWriter, r *http.Request) {
-	rows, err := db.Query("SELECT id, job_id, job_link, job_description, job_type, skills FROM xing_job_description")
+	params, err := listquery.Parse(r, descriptionListSpec)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	total, err := params.Count(db, descriptionListSpec)
+	if err != nil {
+		http.Error(w, "Failed to count job descriptions", http.StatusInternalServerError)
+		return
+	}
+
+	query, args := params.Query(descriptionListSpec, `
+		d.id, d.job_id, d.job_link, COALESCE(d.job_description, ''), COALESCE(d.job_type, ''), COALESCE(d.skills, '')`)
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		http.Error(w, "Failed to fetch job descriptions", http.StatusInternalServerError)
 		return
 	}
 	defer rows.Close()
 
-	var descriptions []XingJobDescription
+	page := params.NewPage()
+	descriptions := []XingJobDescription{}
 	for rows.Next() {
 		var desc XingJobDescription
-		if err := rows.Scan(&desc.ID, &desc.JobID, &desc.JobLink, &desc.JobDescription, &desc.JobType, &desc.Skills); err != nil {
+		ok, err := page.Scan(rows, &desc.ID, &desc.JobID, &desc.JobLink, &desc.JobDescription, &desc.JobType, &desc.Skills)
+		if err != nil {
 			http.Error(w, "Failed to scan job description", http.StatusInternalServerError)
 			return
 		}
-		descriptions = append(descriptions, desc)
+		if ok {
+			descriptions = append(descriptions, desc)
+		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(descriptions)
+	listquery.WriteJSON(w, page.Envelope(descriptions, total))
 }
@@ -0,0 +1,188 @@
+package Xing
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+
+	"job_scraper/scraper/pacing"
+	"job_scraper/scraper/selectors"
+)
+
+// listingLimits bound how far a search is paged through per title, from
+//
+//	XING_MAX_RESULTS  new results kept per title, default 50
+//	XING_MAX_AGE      skip results posted longer ago and stop paging once a whole
+//	                  page is older, e.g. 336h; default no cutoff
+type listingLimits struct {
+	MaxResults int
+	Since      time.Time // zero for no cutoff
+}
+
+const defaultMaxResults = 50
+
+func limitsFromEnv() listingLimits {
+	limits := listingLimits{MaxResults: defaultMaxResults}
+	if raw := os.Getenv("XING_MAX_RESULTS"); raw != "" {
+		if v, err := strconv.Atoi(raw); err == nil && v > 0 {
+			limits.MaxResults = v
+		} else {
+			log.Printf("⚠️ Ignoring invalid XING_MAX_RESULTS=%q", raw)
+		}
+	}
+	if raw := os.Getenv("XING_MAX_AGE"); raw != "" {
+		if v, err := time.ParseDuration(raw); err == nil && v > 0 {
+			limits.Since = time.Now().Add(-v)
+		} else {
+			log.Printf("⚠️ Ignoring invalid XING_MAX_AGE=%q", raw)
+		}
+	}
+	return limits
+}
+
+// tooOld reports whether a result posted on date (YYYY-MM-DD) is before the cutoff.
+// Results without a date are kept.
+func (l listingLimits) tooOld(date string) bool {
+	if l.Since.IsZero() || date == "" {
+		return false
+	}
+	posted, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return false
+	}
+	return posted.Before(l.Since.UTC().Truncate(24 * time.Hour))
+}
+
+// relativeAge matches the age a teaser shows instead of a date, in German or English
+var relativeAge = regexp.MustCompile(`(?i)(\d+)\s*(minute|minuten|min|stunde|stunden|hour|hours|tag|tagen|day|days|woche|wochen|week|weeks|monat|monaten|month|months)\b`)
+
+// postedDate turns a teaser's datetime attribute, or else its text ("vor 3 Tagen",
+// "Gestern", "2 weeks ago"), into YYYY-MM-DD like LinkedIn's posted dates. It is
+// empty when neither can be read.
+func postedDate(datetime, text string, now time.Time) string {
+	if datetime != "" {
+		for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+			if t, err := time.Parse(layout, datetime); err == nil {
+				return t.UTC().Format("2006-01-02")
+			}
+		}
+	}
+
+	text = strings.ToLower(strings.TrimSpace(text))
+	day := now.UTC()
+	switch {
+	case text == "":
+		return ""
+	case strings.Contains(text, "heute"), strings.Contains(text, "today"), strings.Contains(text, "gerade"), strings.Contains(text, "just now"):
+		return day.Format("2006-01-02")
+	case strings.Contains(text, "gestern"), strings.Contains(text, "yesterday"):
+		return day.AddDate(0, 0, -1).Format("2006-01-02")
+	}
+	m := relativeAge.FindStringSubmatch(text)
+	if m == nil {
+		return ""
+	}
+	n, _ := strconv.Atoi(m[1])
+	switch unit := strings.ToLower(m[2]); {
+	case strings.HasPrefix(unit, "tag"), strings.HasPrefix(unit, "day"):
+		day = day.AddDate(0, 0, -n)
+	case strings.HasPrefix(unit, "woche"), strings.HasPrefix(unit, "week"):
+		day = day.AddDate(0, 0, -7*n)
+	case strings.HasPrefix(unit, "monat"), strings.HasPrefix(unit, "month"):
+		day = day.AddDate(0, -n, 0)
+	case strings.HasPrefix(unit, "stunde"), strings.HasPrefix(unit, "hour"):
+		day = day.Add(-time.Duration(n) * time.Hour)
+	default:
+		day = day.Add(-time.Duration(n) * time.Minute)
+	}
+	return day.Format("2006-01-02")
+}
+
+// titleStats is what one title's search came to, logged once it is done
+type titleStats struct {
+	Results    int // teasers loaded
+	Pages      int
+	Stored     int
+	Duplicates int
+	TooOld     int
+	Stopped    string // why paging stopped
+}
+
+func (s titleStats) String() string {
+	return fmt.Sprintf("%d results on %d pages, %d duplicates, %d older than the cutoff; %s",
+		s.Results, s.Pages, s.Duplicates, s.TooOld, s.Stopped)
+}
+
+// teasersJS reads the link, posted date attribute and posted text of every teaser
+const teasersJS = `((cards, link, posted) => Array.from(document.querySelectorAll(cards)).map(el => {
+	const a = el.matches(link) ? el : el.querySelector(link);
+	const m = el.querySelector(posted);
+	return [a ? a.href : '', m ? m.getAttribute('datetime') || '' : '', m ? m.innerText.trim() : ''];
+}))(%q, %q, %q)`
+
+// loadResults clicks "show more" until the results worth keeping reach the cap, a
+// freshly loaded page holds nothing newer than the cutoff, or there is nothing more
+// to load. A result is worth keeping when it is within the cutoff and stored does
+// not know its link yet, so teasers seen in earlier runs do not use up the cap.
+func loadResults(pacer *pacing.Pacer, run *selectors.Run, limits listingLimits, stats *titleStats, stored func(link string) bool) chromedp.Action {
+	cards := run.Site().Any(selectors.ListingCard)
+	link := run.Site().Any(selectors.ListingLink)
+	posted := run.Site().Any(selectors.ListingPosted)
+	more := run.Site().Any(selectors.ListingMore)
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		seen := 0
+		kept := make(map[string]bool)
+		for stats.Pages = 1; ; stats.Pages++ {
+			var teasers [][3]string
+			if err := chromedp.Evaluate(fmt.Sprintf(teasersJS, cards, link, posted), &teasers).Do(ctx); err != nil {
+				return err
+			}
+			fresh := teasers[min(seen, len(teasers)):]
+			now := time.Now()
+			for _, t := range fresh {
+				if t[0] == "" || kept[t[0]] || limits.tooOld(postedDate(t[1], t[2], now)) || stored(t[0]) {
+					continue
+				}
+				kept[t[0]] = true
+			}
+			if len(kept) >= limits.MaxResults {
+				stats.Stopped = fmt.Sprintf("reached the cap of %d new results", limits.MaxResults)
+				return nil
+			}
+			if !limits.Since.IsZero() && len(fresh) > 0 && allTooOld(fresh, limits, now) {
+				stats.Stopped = "page older than the cutoff"
+				return nil
+			}
+			seen = len(teasers)
+
+			if more == "" {
+				stats.Stopped = "no more results"
+				return nil
+			}
+			var grew bool
+			if err := pacer.ClickForMore(more, cards, &grew).Do(ctx); err != nil {
+				return err
+			}
+			if !grew {
+				stats.Stopped = "no more results"
+				return nil
+			}
+		}
+	})
+}
+
+func allTooOld(teasers [][3]string, limits listingLimits, now time.Time) bool {
+	for _, t := range teasers {
+		if !limits.tooOld(postedDate(t[1], t[2], now)) {
+			return false
+		}
+	}
+	return true
+}
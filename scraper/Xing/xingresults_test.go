@@ -0,0 +1,54 @@
+package Xing
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPostedDate(t *testing.T) {
+	now := time.Date(2025, 5, 10, 8, 30, 0, 0, time.UTC)
+	for _, c := range []struct{ datetime, text, want string }{
+		{"2025-05-06T09:12:00Z", "vor 4 Tagen", "2025-05-06"},
+		{"2025-05-06T23:30:00-02:00", "", "2025-05-07"},
+		{"2025-05-06", "", "2025-05-06"},
+		{"", "vor 4 Tagen", "2025-05-06"},
+		{"", "vor 1 Tag", "2025-05-09"},
+		{"", "Vor 2 Wochen", "2025-04-26"},
+		{"", "vor 1 Monat", "2025-04-10"},
+		{"", "vor 9 Stunden", "2025-05-09"},
+		{"", "vor 5 Min.", "2025-05-10"},
+		{"", "Heute", "2025-05-10"},
+		{"", "Gestern", "2025-05-09"},
+		{"", "3 days ago", "2025-05-07"},
+		{"not a date", "Neu", ""},
+		{"", "", ""},
+	} {
+		if got := postedDate(c.datetime, c.text, now); got != c.want {
+			t.Errorf("postedDate(%q, %q) = %q, want %q", c.datetime, c.text, got, c.want)
+		}
+	}
+}
+
+func TestLimitsFromEnv(t *testing.T) {
+	t.Setenv("XING_MAX_RESULTS", "")
+	t.Setenv("XING_MAX_AGE", "")
+	if l := limitsFromEnv(); l.MaxResults != defaultMaxResults || !l.Since.IsZero() {
+		t.Errorf("defaults = %+v, want %d results and no cutoff", l, defaultMaxResults)
+	}
+
+	t.Setenv("XING_MAX_RESULTS", "120")
+	t.Setenv("XING_MAX_AGE", "336h")
+	l := limitsFromEnv()
+	if l.MaxResults != 120 || time.Since(l.Since).Round(time.Hour) != 336*time.Hour {
+		t.Errorf("limits = %+v, want 120 results and a two-week cutoff", l)
+	}
+	if !l.tooOld(time.Now().AddDate(0, 0, -15).Format("2006-01-02")) || l.tooOld(time.Now().AddDate(0, 0, -13).Format("2006-01-02")) || l.tooOld("") {
+		t.Error("the two-week cutoff is off")
+	}
+
+	t.Setenv("XING_MAX_RESULTS", "-1")
+	t.Setenv("XING_MAX_AGE", "2w")
+	if l := limitsFromEnv(); l.MaxResults != defaultMaxResults || !l.Since.IsZero() {
+		t.Errorf("invalid values = %+v, want the defaults", l)
+	}
+}
@@ -0,0 +1,28 @@
+package Xing
+
+import (
+	"testing"
+
+	"job_scraper/internal/testutil/fixtures"
+)
+
+func TestStoreRawDescriptionResetsAttemptsOnlyWhenTheTextChanges(t *testing.T) {
+	db := fixtures.DB(t)
+	if err := storeRawDescription(db, "job-1", "https://example.com/1", "Go developer"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`UPDATE xing_job_description SET summary_status = 'failed', summary_attempts = 3`); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct{ description, want string }{
+		{"Go developer", "failed | 3"},
+		{"Senior Go developer", "pending | 0"},
+	}
+	for _, s := range steps {
+		if err := storeRawDescription(db, "job-1", "https://example.com/1", s.description); err != nil {
+			t.Fatal(err)
+		}
+		fixtures.Equal(t, s.description, fixtures.Rows(t, db, `SELECT summary_status, summary_attempts FROM xing_job_description`), []string{s.want})
+	}
+}
@@ -10,12 +10,15 @@ import (
 	//"os/exec"
 	//"runtime"
 	//"strconv"
-	"time"
 	"strings"
 	"net/url"
 	
 	"github.com/chromedp/cdproto/target"
 	"github.com/chromedp/chromedp"
+
+	"job_scraper/scraper/browser"
+	"job_scraper/scraper/pacing"
+	"job_scraper/scraper/selectors"
 )
 
 
@@ -52,11 +55,15 @@ type FailedJob struct {
 	JobLink string `json:"job_link"` // Matches job_link TEXT
 }
 
-// StoreFailedJob stores a failed job in the database
+// StoreFailedJob stores a failed job in the database; repeated failures of a link bump its count
 func StoreFailedJob(db *sql.DB, jobID, jobLink, reason string) error {
 	_, err := db.Exec(`
-        INSERT INTO xing_failed_jobs (job_id, job_link, reason) 
-        VALUES (?, ?, ?)`, jobID, jobLink, reason,
+        INSERT INTO xing_failed_jobs (job_id, job_link, reason, failed_at) 
+        VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+        ON CONFLICT (job_link) DO UPDATE SET
+            reason = excluded.reason,
+            failure_count = failure_count + 1,
+            failed_at = excluded.failed_at`, jobID, jobLink, reason,
 	)
 	if err != nil {
 		log.Printf("❌ Failed to store failed job in DB: %v\n", err)
@@ -66,13 +73,23 @@ func StoreFailedJob(db *sql.DB, jobID, jobLink, reason string) error {
 	fmt.Printf("⚠️ Stored failed job: %s -> %s (Reason: %s)\n", jobID, jobLink, reason)
 	return nil
 }
-func navigateAndClickApply(ctx context.Context, db *sql.DB, jobID string, jobLink string) error {
+func navigateAndClickApply(ctx context.Context, db *sql.DB, run *selectors.Run, jobID string, jobLink string) error {
 	// Step 1: Navigate to the job page and wait for description container
+	pacer := pacing.For(site.Name)
 	err := chromedp.Run(ctx,
-		chromedp.Navigate(jobLink),
-		chromedp.WaitVisible(`div[class^='html-description__DescriptionContainer']`, chromedp.ByQuery),
-		chromedp.Sleep(2*time.Second),
+		chromedp.Navigate(jobLink), // paced by the caller, outside the job timeout
+		browser.ExpectNormalPage(site),
+		run.Wait(selectors.DetailDescription, pacer.WaitVisible),
 	)
+	if pe, ok := browser.AsPageError(err); ok {
+		if pe.State.Blocking() {
+			// The run pauses; nothing is recorded against this job
+			return err
+		}
+		log.Printf("🗑️ Job %s is no longer available: %s\n", jobID, pe.URL)
+		StoreFailedJob(db, jobID, jobLink, "Job expired")
+		return err
+	}
 	if err != nil {
 		log.Printf("❌ Failed to navigate or wait for description container: %s -> %v\n", jobID, err)
 		StoreFailedJob(db, jobID, jobLink, "Navigation or container wait failed")
@@ -82,7 +99,7 @@ func navigateAndClickApply(ctx context.Context, db *sql.DB, jobID string, jobLin
 	// Step 2: Extract raw job description
 	var rawDescription string
 	err = chromedp.Run(ctx,
-		chromedp.Text(`div[class^='html-description__DescriptionContainer']`, &rawDescription, chromedp.NodeVisible, chromedp.ByQuery),
+		run.Text(selectors.DetailDescription, &rawDescription),
 	)
 	if err != nil || strings.TrimSpace(rawDescription) == "" {
 		log.Printf("❌ Failed to extract job description for jobID %s: %v\n", jobID, err)
@@ -90,31 +107,24 @@ func navigateAndClickApply(ctx context.Context, db *sql.DB, jobID string, jobLin
 		return err
 	}
 
-	// Step 3: Summarize using Hugging Face
-	summary, err := extractStructuredSummary(rawDescription)
-	if err != nil {
-		log.Printf("⚠️ Failed to summarize job description for jobID %s: %v\n", jobID, err)
-		// Optional fallback: 
-		summary = rawDescription
-	}
-
-	// Step 4: Store summarized description
-	err = storeJobDescription(db, jobID, jobLink, strings.TrimSpace(summary))
+	// Step 3: Store the raw description; the summarizer pool structures it later
+	err = storeRawDescription(db, jobID, jobLink, strings.TrimSpace(rawDescription))
 	if err != nil {
 		log.Printf("❌ Failed to store job description for jobID %s: %v\n", jobID, err)
 		return err
 	}
 
-	// Step 5 (Commented Out): Click Apply Button
+	// Step 4: Click Apply Button
 	err = chromedp.Run(ctx,
-		chromedp.Click(`div.main-actions__ActionsContainer-sc-68c89ebb-0 button[data-testid="apply-button"]`, chromedp.NodeVisible),
-		chromedp.Sleep(5*time.Second),
+		pacer.Think(),
+		run.Click(selectors.DetailApply),
+		pacer.Think(), // let the application tab open
 	)
 	if err != nil {
 		log.Printf("⚠️ Apply button click failed for jobID %s: %v\n", jobID, err)
 	}
 
-	log.Printf("✅ Job %s processed and summarized", jobID)
+	log.Printf("✅ Job %s processed, description queued for summarization", jobID)
 	return nil
 }
 
@@ -0,0 +1,88 @@
+package analytics
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// Filters are the query params shared by the analytics endpoints
+type Filters struct {
+	Since    string // inclusive, YYYY-MM-DD
+	Until    string // inclusive, YYYY-MM-DD
+	Source   string // LinkedIn or Xing
+	Title    string // the search title (profile) a job was scraped for
+	Location string
+}
+
+func parseFilters(r *http.Request) (Filters, error) {
+	q := r.URL.Query()
+	f := Filters{
+		Since:    q.Get("since"),
+		Until:    q.Get("until"),
+		Source:   q.Get("source"),
+		Title:    q.Get("title"),
+		Location: q.Get("location"),
+	}
+	// A search profile is the title a batch of jobs was scraped for
+	if f.Title == "" {
+		f.Title = q.Get("profile")
+	}
+	for name, value := range map[string]string{"since": f.Since, "until": f.Until} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse(dateLayout, value); err != nil {
+			return f, fmt.Errorf("%s must be a date in YYYY-MM-DD format", name)
+		}
+	}
+	return f, nil
+}
+
+// where renders the filters against a relation exposing source, title, location and
+// the given date column
+func (f Filters) where(dateColumn string) (string, []interface{}) {
+	var (
+		clauses []string
+		args    []interface{}
+	)
+	if f.Since != "" {
+		clauses = append(clauses, dateColumn+" >= ?")
+		args = append(args, f.Since)
+	}
+	if f.Until != "" {
+		clauses = append(clauses, dateColumn+" <= ?")
+		args = append(args, f.Until)
+	}
+	if f.Source != "" {
+		clauses = append(clauses, "source = ?")
+		args = append(args, f.Source)
+	}
+	if f.Title != "" {
+		clauses = append(clauses, "title = ?")
+		args = append(args, f.Title)
+	}
+	if f.Location != "" {
+		clauses = append(clauses, "location LIKE ?")
+		args = append(args, "%"+f.Location+"%")
+	}
+	if len(clauses) == 0 {
+		return "1 = 1", nil
+	}
+	return strings.Join(clauses, " AND "), args
+}
+
+func intParam(r *http.Request, name string, fallback, max int) int {
+	n, err := strconv.Atoi(r.URL.Query().Get(name))
+	if err != nil || n <= 0 {
+		return fallback
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
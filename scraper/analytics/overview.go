@@ -0,0 +1,206 @@
+package analytics
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+type DailyPostings struct {
+	Day    string `json:"day"`
+	Source string `json:"source"`
+	Count  int    `json:"count"`
+}
+
+type NamedCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type ProcessingStats struct {
+	ProcessedJobs     int      `json:"processed_jobs"`
+	AvgHoursToDetail  *float64 `json:"avg_hours_to_detail"`
+	AvgHoursToSummary *float64 `json:"avg_hours_to_summary"`
+	PendingSummaries  int      `json:"pending_summaries"`
+	FailedSummaries   int      `json:"failed_summaries"`
+}
+
+type ApplyLinkStats struct {
+	ProcessedJobs int     `json:"processed_jobs"`
+	WithLink      int     `json:"with_link"`
+	CaptureRate   float64 `json:"capture_rate"`
+}
+
+type Overview struct {
+	TotalJobs      int             `json:"total_jobs"`
+	BySource       map[string]int  `json:"by_source"`
+	PostingsPerDay []DailyPostings `json:"postings_per_day"`
+	TopCompanies   []NamedCount    `json:"top_companies"`
+	Locations      []NamedCount    `json:"locations"`
+	JobTypes       []NamedCount    `json:"job_types"`
+	Processing     ProcessingStats `json:"processing"`
+	ApplyLinks     ApplyLinkStats  `json:"apply_links"`
+}
+
+// jobBase is one row per job across sources. job_date is the posting date when the
+// listing had a parseable one, otherwise the day it was scraped.
+const jobBase = `
+	SELECT j.*, COALESCE(date(j.posted_date), date(j.scraped_at)) AS job_date
+	FROM all_jobs j`
+
+// jobTypeExpr folds the free-form job_type the LLM returns into three buckets
+const jobTypeExpr = `
+	CASE
+		WHEN d.job_type LIKE '%hybrid%' THEN 'Hybrid'
+		WHEN d.job_type LIKE '%remote%' THEN 'Remote'
+		WHEN d.job_type LIKE '%site%' OR d.job_type LIKE '%office%' OR d.job_type LIKE '%vor ort%' THEN 'On-site'
+		ELSE 'Unknown'
+	END`
+
+// OverviewHandler serves /analytics/overview.
+// Query params: since, until (posting day), profile or title (search title), source,
+// location, top (rows for companies and locations, default 20).
+func OverviewHandler(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	filters, err := parseFilters(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	top := intParam(r, "top", 20, 500)
+
+	where, args := filters.where("job_date")
+	base := fmt.Sprintf(`WITH base AS (SELECT * FROM (%s) WHERE %s)`, jobBase, where)
+
+	overview := Overview{BySource: map[string]int{}}
+	steps := []func() error{
+		func() error {
+			return scanRows(db, base+` SELECT source, COUNT(*) FROM base GROUP BY source`, args, func(rows *sql.Rows) error {
+				var source string
+				var count int
+				if err := rows.Scan(&source, &count); err != nil {
+					return err
+				}
+				overview.BySource[source] = count
+				overview.TotalJobs += count
+				return nil
+			})
+		},
+		func() error {
+			overview.PostingsPerDay = []DailyPostings{}
+			return scanRows(db, base+`
+				SELECT job_date, source, COUNT(*) FROM base
+				WHERE job_date IS NOT NULL
+				GROUP BY job_date, source ORDER BY job_date, source`, args, func(rows *sql.Rows) error {
+				var d DailyPostings
+				if err := rows.Scan(&d.Day, &d.Source, &d.Count); err != nil {
+					return err
+				}
+				overview.PostingsPerDay = append(overview.PostingsPerDay, d)
+				return nil
+			})
+		},
+		func() error {
+			var err error
+			overview.TopCompanies, err = namedCounts(db, base+`
+				SELECT company, COUNT(*) AS cnt FROM base
+				WHERE company IS NOT NULL AND company != '' AND company != 'Unknown'
+				GROUP BY company ORDER BY cnt DESC, company LIMIT ?`, append(args, top))
+			return err
+		},
+		func() error {
+			var err error
+			overview.Locations, err = namedCounts(db, base+`
+				SELECT location, COUNT(*) AS cnt FROM base
+				WHERE location IS NOT NULL AND location != '' AND location != 'Unknown'
+				GROUP BY location ORDER BY cnt DESC, location LIMIT ?`, append(args, top))
+			return err
+		},
+		func() error {
+			var err error
+			overview.JobTypes, err = namedCounts(db, base+`
+				SELECT `+jobTypeExpr+` AS bucket, COUNT(*) AS cnt
+				FROM base b
+				JOIN all_job_descriptions d ON d.source = b.source AND d.job_id = b.id
+				GROUP BY bucket ORDER BY cnt DESC, bucket`, args)
+			return err
+		},
+		func() error {
+			p := &overview.Processing
+			var toDetail, toSummary sql.NullFloat64
+			err := db.QueryRow(base+`
+				SELECT
+					COUNT(b.processed_at),
+					AVG((julianday(b.processed_at) - julianday(b.scraped_at)) * 24),
+					AVG((julianday(d.summarized_at) - julianday(b.scraped_at)) * 24),
+					COALESCE(SUM(d.summary_status IN ('pending', 'running')), 0),
+					COALESCE(SUM(d.summary_status = 'failed'), 0)
+				FROM base b
+				LEFT JOIN all_job_descriptions d ON d.source = b.source AND d.job_id = b.id`, args...,
+			).Scan(&p.ProcessedJobs, &toDetail, &toSummary, &p.PendingSummaries, &p.FailedSummaries)
+			if toDetail.Valid {
+				p.AvgHoursToDetail = &toDetail.Float64
+			}
+			if toSummary.Valid {
+				p.AvgHoursToSummary = &toSummary.Float64
+			}
+			return err
+		},
+		func() error {
+			a := &overview.ApplyLinks
+			err := db.QueryRow(base+`
+				SELECT
+					COUNT(*),
+					COALESCE(SUM(EXISTS (
+						SELECT 1 FROM linkedin_job_application_links l WHERE b.source = 'LinkedIn' AND l.job_id = b.id
+						UNION ALL
+						SELECT 1 FROM xing_job_application_links x WHERE b.source = 'Xing' AND x.job_id = b.id
+					)), 0)
+				FROM base b
+				WHERE b.processed_at IS NOT NULL`, args...,
+			).Scan(&a.ProcessedJobs, &a.WithLink)
+			if a.ProcessedJobs > 0 {
+				a.CaptureRate = float64(a.WithLink) / float64(a.ProcessedJobs)
+			}
+			return err
+		},
+	}
+
+	for _, step := range steps {
+		if err := step(); err != nil {
+			http.Error(w, "Failed to compute overview: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(overview)
+}
+
+func scanRows(db *sql.DB, query string, args []interface{}, scan func(*sql.Rows) error) error {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func namedCounts(db *sql.DB, query string, args []interface{}) ([]NamedCount, error) {
+	counts := []NamedCount{}
+	err := scanRows(db, query, args, func(rows *sql.Rows) error {
+		var c NamedCount
+		if err := rows.Scan(&c.Name, &c.Count); err != nil {
+			return err
+		}
+		counts = append(counts, c)
+		return nil
+	})
+	return counts, err
+}
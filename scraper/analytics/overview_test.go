@@ -0,0 +1,109 @@
+package analytics
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func overviewJobs(t *testing.T) *sql.DB {
+	db := newTestDB(t)
+	insertJobs(t, db,
+		testJob{source: "LinkedIn", id: "l1", company: "ACME", location: "Berlin", posted: "2025-05-01",
+			scraped: "2025-05-01 10:00:00", processed: "2025-05-01 12:00:00"},
+		// No posting date: counted on the day it was scraped
+		testJob{source: "LinkedIn", id: "l2", company: "ACME", location: "Munich", scraped: "2025-05-03 08:00:00"},
+		testJob{source: "Xing", id: "x1", company: "Beta", location: "Berlin", posted: "2025-05-01",
+			scraped: "2025-05-02 00:00:00", processed: "2025-05-02 04:00:00"},
+		testJob{source: "Xing", id: "x2", company: "Unknown", posted: "2025-04-01", scraped: "2025-04-02 00:00:00"},
+	)
+	for _, stmt := range []string{
+		`INSERT INTO linkedin_job_description (job_id, job_link, job_type, summary_status, summarized_at)
+			VALUES ('l1', 'https://example.com/l1', 'Hybrid (2 days)', 'done', '2025-05-01 14:00:00')`,
+		`INSERT INTO xing_job_description (job_id, job_link, job_type, summary_status)
+			VALUES ('x1', 'https://example.com/x1', 'Fully remote', 'pending')`,
+		`INSERT INTO linkedin_job_application_links (job_id, job_link) VALUES ('l1', 'https://acme.example/apply')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestOverviewAggregations(t *testing.T) {
+	db := overviewJobs(t)
+
+	var got Overview
+	rec := get(t, db, OverviewHandler, "/analytics/overview")
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct{ name, got, want string }{
+		{"total", fmt.Sprint(got.TotalJobs, got.BySource), "4 map[LinkedIn:2 Xing:2]"},
+		{"postings per day", fmt.Sprint(got.PostingsPerDay),
+			"[{2025-04-01 Xing 1} {2025-05-01 LinkedIn 1} {2025-05-01 Xing 1} {2025-05-03 LinkedIn 1}]"},
+		// Unknown and empty names are left out
+		{"companies", fmt.Sprint(got.TopCompanies), "[{ACME 2} {Beta 1}]"},
+		{"locations", fmt.Sprint(got.Locations), "[{Berlin 2} {Munich 1}]"},
+		{"job types", fmt.Sprint(got.JobTypes), "[{Hybrid 1} {Remote 1}]"},
+		{"summaries", fmt.Sprint(got.Processing.ProcessedJobs, got.Processing.PendingSummaries, got.Processing.FailedSummaries), "2 1 0"},
+		{"apply links", fmt.Sprint(got.ApplyLinks), "{2 1 0.5}"},
+	} {
+		if c.got != c.want {
+			t.Errorf("%s = %s, want %s", c.name, c.got, c.want)
+		}
+	}
+
+	// l1 took 2h to its detail and 4h to its summary, x1 4h to its detail
+	for name, c := range map[string]struct {
+		got  *float64
+		want float64
+	}{
+		"avg hours to detail":  {got.Processing.AvgHoursToDetail, 3},
+		"avg hours to summary": {got.Processing.AvgHoursToSummary, 4},
+	} {
+		if c.got == nil || math.Abs(*c.got-c.want) > 0.01 {
+			t.Errorf("%s = %v, want %v", name, c.got, c.want)
+		}
+	}
+}
+
+func TestOverviewFilters(t *testing.T) {
+	db := overviewJobs(t)
+
+	for _, tc := range []struct {
+		query     string
+		total     int
+		companies string
+	}{
+		{"source=LinkedIn", 2, "[{ACME 2}]"},
+		{"since=2025-05-02", 1, "[{ACME 1}]"},
+		{"until=2025-05-01&location=berl", 2, "[{ACME 1} {Beta 1}]"},
+		{"top=1", 4, "[{ACME 2}]"},
+	} {
+		t.Run(tc.query, func(t *testing.T) {
+			var got Overview
+			rec := get(t, db, OverviewHandler, "/analytics/overview?"+tc.query)
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got.TotalJobs != tc.total || fmt.Sprint(got.TopCompanies) != tc.companies {
+				t.Errorf("total = %d, companies = %v; want %d, %s", got.TotalJobs, got.TopCompanies, tc.total, tc.companies)
+			}
+		})
+	}
+
+	// With nothing processed there are no averages to report
+	var got Overview
+	rec := get(t, db, OverviewHandler, "/analytics/overview?since=2025-05-03")
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Processing.AvgHoursToDetail != nil || got.ApplyLinks.CaptureRate != 0 {
+		t.Errorf("processing = %+v, apply links = %+v", got.Processing, got.ApplyLinks)
+	}
+}
@@ -0,0 +1,278 @@
+package analytics
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"job_scraper/scraper/skills"
+)
+
+// GroupedSkill is one of the top skills within a group (a title, location, source or week)
+type GroupedSkill struct {
+	Group string `json:"group"`
+	Skill string `json:"skill"`
+	Count int    `json:"count"`
+}
+
+type SkillPair struct {
+	SkillA string `json:"skill_a"`
+	SkillB string `json:"skill_b"`
+	Count  int    `json:"count"`
+}
+
+// SkillMovement compares a skill's job count in the current window with the one before
+type SkillMovement struct {
+	Skill     string  `json:"skill"`
+	Current   int     `json:"current"`
+	Previous  int     `json:"previous"`
+	Change    int     `json:"change"`
+	ChangePct float64 `json:"change_pct"`
+}
+
+type SkillAnalytics struct {
+	TopByTitle    []GroupedSkill  `json:"top_by_title"`
+	TopByLocation []GroupedSkill  `json:"top_by_location"`
+	TopBySource   []GroupedSkill  `json:"top_by_source"`
+	TopByWeek     []GroupedSkill  `json:"top_by_week"`
+	Pairs         []SkillPair     `json:"pairs"`
+	WindowDays    int             `json:"window_days"`
+	WindowEnd     string          `json:"window_end"`
+	Rising        []SkillMovement `json:"rising"`
+	Falling       []SkillMovement `json:"falling"`
+}
+
+// skillBase is one row per (job, canonical skill) with the job's listing attributes
+const skillBase = `
+	SELECT js.source, js.job_id, s.name AS skill, j.title, j.location,
+		` + skills.JobDateExpr + ` AS job_date
+	FROM job_skills js
+	JOIN skills s ON s.id = js.skill_id
+	LEFT JOIN all_jobs j ON j.source = js.source AND j.id = js.job_id`
+
+// SkillAnalyticsHandler serves /analytics/skills.
+// Query params: since, until, source, title, location (filters); top (skills per
+// group, default 10); pairs (default 25); window (days, default 28) and min_count
+// (default 3) for rising/falling; format=csv for a flat CSV export.
+func SkillAnalyticsHandler(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	filters, err := parseFilters(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	top := intParam(r, "top", 10, 100)
+	pairLimit := intParam(r, "pairs", 25, 500)
+	window := intParam(r, "window", 28, 365)
+	minCount := intParam(r, "min_count", 3, 1000000)
+
+	where, args := filters.where("job_date")
+	base := fmt.Sprintf(`WITH base AS (SELECT * FROM (%s) WHERE %s)`, skillBase, where)
+
+	result := SkillAnalytics{WindowDays: window}
+	dimensions := []struct {
+		expr string
+		dest *[]GroupedSkill
+	}{
+		{"COALESCE(title, '')", &result.TopByTitle},
+		{"COALESCE(location, '')", &result.TopByLocation},
+		{"source", &result.TopBySource},
+		{"strftime('%Y-W%W', job_date)", &result.TopByWeek},
+	}
+	for _, dim := range dimensions {
+		grouped, err := topSkillsBy(db, base, args, dim.expr, top)
+		if err != nil {
+			http.Error(w, "Failed to aggregate skills: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		*dim.dest = grouped
+	}
+
+	result.Pairs, err = skillPairs(db, base, args, pairLimit)
+	if err != nil {
+		http.Error(w, "Failed to aggregate skill pairs: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	result.WindowEnd = filters.Until
+	if result.WindowEnd == "" {
+		result.WindowEnd = time.Now().Format(dateLayout)
+	}
+	result.Rising, result.Falling, err = skillMovements(db, base, args, result.WindowEnd, window, minCount, top)
+	if err != nil {
+		http.Error(w, "Failed to compute skill movements: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		writeSkillCSV(w, result)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func topSkillsBy(db *sql.DB, base string, args []interface{}, groupExpr string, top int) ([]GroupedSkill, error) {
+	query := fmt.Sprintf(`%s
+		SELECT grp, skill, cnt FROM (
+			SELECT %s AS grp, skill, COUNT(*) AS cnt,
+				ROW_NUMBER() OVER (PARTITION BY %s ORDER BY COUNT(*) DESC, skill) AS rn
+			FROM base
+			GROUP BY grp, skill
+		)
+		WHERE rn <= ?
+		ORDER BY grp, cnt DESC, skill`, base, groupExpr, groupExpr)
+
+	rows, err := db.Query(query, append(append([]interface{}{}, args...), top)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	grouped := []GroupedSkill{}
+	for rows.Next() {
+		var g GroupedSkill
+		var group sql.NullString
+		if err := rows.Scan(&group, &g.Skill, &g.Count); err != nil {
+			return nil, err
+		}
+		g.Group = group.String
+		grouped = append(grouped, g)
+	}
+	return grouped, rows.Err()
+}
+
+func skillPairs(db *sql.DB, base string, args []interface{}, limit int) ([]SkillPair, error) {
+	query := fmt.Sprintf(`%s
+		SELECT a.skill, b.skill, COUNT(*) AS cnt
+		FROM base a
+		JOIN base b ON a.source = b.source AND a.job_id = b.job_id AND a.skill < b.skill
+		GROUP BY a.skill, b.skill
+		ORDER BY cnt DESC, a.skill, b.skill
+		LIMIT ?`, base)
+
+	rows, err := db.Query(query, append(append([]interface{}{}, args...), limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pairs := []SkillPair{}
+	for rows.Next() {
+		var p SkillPair
+		if err := rows.Scan(&p.SkillA, &p.SkillB, &p.Count); err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, p)
+	}
+	return pairs, rows.Err()
+}
+
+// skillMovements compares (end - window, end] with (end - 2*window, end - window]
+func skillMovements(db *sql.DB, base string, args []interface{}, end string, window, minCount, limit int) ([]SkillMovement, []SkillMovement, error) {
+	current := fmt.Sprintf("-%d days", window)
+	previous := fmt.Sprintf("-%d days", 2*window)
+	query := fmt.Sprintf(`%s
+		SELECT skill,
+			SUM(CASE WHEN job_date > date(?, ?) THEN 1 ELSE 0 END) AS cur,
+			SUM(CASE WHEN job_date <= date(?, ?) AND job_date > date(?, ?) THEN 1 ELSE 0 END) AS prev
+		FROM base
+		WHERE job_date <= ?
+		GROUP BY skill
+		HAVING cur + prev >= ?`, base)
+
+	queryArgs := append(append([]interface{}{}, args...),
+		end, current, end, current, end, previous, end, minCount)
+	rows, err := db.Query(query, queryArgs...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	rising, falling := []SkillMovement{}, []SkillMovement{}
+	for rows.Next() {
+		var m SkillMovement
+		if err := rows.Scan(&m.Skill, &m.Current, &m.Previous); err != nil {
+			return nil, nil, err
+		}
+		m.Change = m.Current - m.Previous
+		if m.Previous > 0 {
+			m.ChangePct = float64(m.Change) / float64(m.Previous) * 100
+		}
+		switch {
+		case m.Change > 0:
+			rising = append(rising, m)
+		case m.Change < 0:
+			falling = append(falling, m)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	// Ties go by name so the cut at limit does not vary between calls
+	sort.Slice(rising, func(i, j int) bool {
+		if rising[i].Change != rising[j].Change {
+			return rising[i].Change > rising[j].Change
+		}
+		return rising[i].Skill < rising[j].Skill
+	})
+	sort.Slice(falling, func(i, j int) bool {
+		if falling[i].Change != falling[j].Change {
+			return falling[i].Change < falling[j].Change
+		}
+		return falling[i].Skill < falling[j].Skill
+	})
+	if len(rising) > limit {
+		rising = rising[:limit]
+	}
+	if len(falling) > limit {
+		falling = falling[:limit]
+	}
+	return rising, falling, nil
+}
+
+// writeSkillCSV flattens every section into one sheet keyed by a section column
+func writeSkillCSV(w http.ResponseWriter, result SkillAnalytics) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="skill_analytics.csv"`)
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"section", "group", "skill", "skill_b", "count", "previous", "change"})
+
+	sections := []struct {
+		name string
+		rows []GroupedSkill
+	}{
+		{"top_by_title", result.TopByTitle},
+		{"top_by_location", result.TopByLocation},
+		{"top_by_source", result.TopBySource},
+		{"top_by_week", result.TopByWeek},
+	}
+	for _, section := range sections {
+		for _, g := range section.rows {
+			out.Write([]string{section.name, g.Group, g.Skill, "", strconv.Itoa(g.Count), "", ""})
+		}
+	}
+	for _, p := range result.Pairs {
+		out.Write([]string{"pairs", "", p.SkillA, p.SkillB, strconv.Itoa(p.Count), "", ""})
+	}
+	movements := []struct {
+		name string
+		rows []SkillMovement
+	}{
+		{"rising", result.Rising},
+		{"falling", result.Falling},
+	}
+	for _, section := range movements {
+		for _, m := range section.rows {
+			out.Write([]string{section.name, "", m.Skill, "", strconv.Itoa(m.Current), strconv.Itoa(m.Previous), strconv.Itoa(m.Change)})
+		}
+	}
+	out.Flush()
+}
@@ -0,0 +1,197 @@
+package analytics
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"job_scraper/config"
+	"job_scraper/scraper/skills"
+)
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := config.InitializeDatabaseAt(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := skills.SeedTaxonomy(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// testJob is a listing row; empty strings are stored as NULL
+type testJob struct {
+	source, id, title, company, location, posted, scraped, processed string
+}
+
+func insertJobs(t *testing.T, db *sql.DB, jobs ...testJob) {
+	t.Helper()
+	for _, j := range jobs {
+		table := map[string]string{"LinkedIn": "linkedin_jobs", "Xing": "xing_jobs"}[j.source]
+		_, err := db.Exec(fmt.Sprintf(`
+			INSERT INTO %s (id, jobid, title, company, location, posted_date, link, processed, scraped_at, processed_at)
+			VALUES (?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?, NULLIF(?, ''), NULLIF(?, ''))`, table),
+			j.id, j.id, j.title, j.company, j.location, j.posted, "https://example.com/"+j.id,
+			j.processed != "", j.scraped, j.processed)
+		if err != nil {
+			t.Fatalf("insert %s: %v", j.id, err)
+		}
+	}
+}
+
+func get(t *testing.T, db *sql.DB, handler func(*sql.DB, http.ResponseWriter, *http.Request), target string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler(db, rec, httptest.NewRequest(http.MethodGet, target, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s = %d: %s", target, rec.Code, rec.Body)
+	}
+	return rec
+}
+
+// skillJobs has Docker rising, Python falling and Go up by one between the
+// windows ending 2025-05-10
+func skillJobs(t *testing.T) *sql.DB {
+	db := newTestDB(t)
+	insertJobs(t, db,
+		testJob{source: "LinkedIn", id: "l1", title: "Backend Engineer", location: "Berlin", posted: "2025-05-01"},
+		testJob{source: "LinkedIn", id: "l2", title: "Backend Engineer", location: "Munich", posted: "2025-05-02"},
+		testJob{source: "Xing", id: "x1", title: "Backend Engineer", location: "Berlin", posted: "2025-04-01"},
+		testJob{source: "Xing", id: "x2", title: "Data Engineer", location: "Berlin", posted: "2025-04-02"},
+	)
+	for _, s := range []struct {
+		source, id string
+		raw        []string
+	}{
+		{"LinkedIn", "l1", []string{"Golang", "Docker"}},
+		{"LinkedIn", "l2", []string{"Go", "docker"}},
+		{"Xing", "x1", []string{"Go", "Python"}},
+		{"Xing", "x2", []string{"Python 3"}},
+	} {
+		if err := skills.IndexJob(db, s.source, s.id, s.raw); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestSkillAnalyticsAggregations(t *testing.T) {
+	db := skillJobs(t)
+
+	for _, tc := range []struct {
+		name   string
+		query  string
+		check  func(SkillAnalytics) []GroupedSkill
+		want   string
+		pairs  string
+		rising string
+	}{
+		{
+			name:  "top skill per source, ties broken by name",
+			query: "top=1",
+			check: func(a SkillAnalytics) []GroupedSkill { return a.TopBySource },
+			want:  "[{LinkedIn Docker 2} {Xing Python 2}]",
+			pairs: "[{Docker Go 2} {Go Python 1}]",
+		},
+		{
+			name:  "top skills per title",
+			query: "top=1",
+			check: func(a SkillAnalytics) []GroupedSkill { return a.TopByTitle },
+			want:  "[{Backend Engineer Go 3} {Data Engineer Python 1}]",
+			pairs: "[{Docker Go 2} {Go Python 1}]",
+		},
+		{
+			name:  "filtered by source",
+			query: "source=Xing",
+			check: func(a SkillAnalytics) []GroupedSkill { return a.TopByLocation },
+			want:  "[{Berlin Python 2} {Berlin Go 1}]",
+			pairs: "[{Go Python 1}]",
+		},
+		{
+			name:  "filtered by posting day",
+			query: "since=2025-04-02&until=2025-05-01",
+			check: func(a SkillAnalytics) []GroupedSkill { return a.TopByWeek },
+			want:  "[{2025-W13 Python 1} {2025-W17 Docker 1} {2025-W17 Go 1}]",
+			pairs: "[{Docker Go 1}]",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var got SkillAnalytics
+			rec := get(t, db, SkillAnalyticsHandler, "/analytics/skills?"+tc.query)
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if s := fmt.Sprint(tc.check(got)); s != tc.want {
+				t.Errorf("groups = %s, want %s", s, tc.want)
+			}
+			if s := fmt.Sprint(got.Pairs); s != tc.pairs {
+				t.Errorf("pairs = %s, want %s", s, tc.pairs)
+			}
+		})
+	}
+}
+
+func TestSkillAnalyticsMovements(t *testing.T) {
+	db := skillJobs(t)
+
+	var got SkillAnalytics
+	rec := get(t, db, SkillAnalyticsHandler, "/analytics/skills?until=2025-05-10&window=28&min_count=1")
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	// The current window is (04-12, 05-10], the one before it (03-15, 04-12]
+	if s := fmt.Sprint(got.Rising); s != "[{Docker 2 0 2 0} {Go 2 1 1 100}]" {
+		t.Errorf("rising = %s", s)
+	}
+	if s := fmt.Sprint(got.Falling); s != "[{Python 0 2 -2 -100}]" {
+		t.Errorf("falling = %s", s)
+	}
+	if got.WindowEnd != "2025-05-10" || got.WindowDays != 28 {
+		t.Errorf("window = %d days to %s", got.WindowDays, got.WindowEnd)
+	}
+
+	// min_count drops skills seen too rarely in both windows together
+	rec = get(t, db, SkillAnalyticsHandler, "/analytics/skills?until=2025-05-10&window=28&min_count=3")
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if s := fmt.Sprint(got.Rising, got.Falling); s != "[{Go 2 1 1 100}] []" {
+		t.Errorf("movements with min_count=3 = %s", s)
+	}
+}
+
+func TestSkillAnalyticsCSV(t *testing.T) {
+	db := skillJobs(t)
+
+	rec := get(t, db, SkillAnalyticsHandler, "/analytics/skills?format=csv&source=LinkedIn&until=2025-05-10&min_count=1")
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for _, row := range rows {
+		if row[0] == "top_by_source" || row[0] == "pairs" || row[0] == "rising" || row[0] == "section" {
+			lines = append(lines, strings.Join(row, ","))
+		}
+	}
+	want := []string{
+		"section,group,skill,skill_b,count,previous,change",
+		"top_by_source,LinkedIn,Docker,,2,,",
+		"top_by_source,LinkedIn,Go,,2,,",
+		"pairs,,Docker,Go,2,,",
+		"rising,,Docker,,2,0,2",
+		"rising,,Go,,2,0,2",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("csv =\n%s\nwant\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+}
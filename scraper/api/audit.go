@@ -0,0 +1,27 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"job_scraper/scraper/auth"
+)
+
+// listAudit returns the newest audit entries; ?limit= defaults to 100, max 1000
+func listAudit(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 100
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	entries, err := auth.AuditLog(db, limit)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "", "Failed to read audit log")
+		return
+	}
+	WriteJSON(w, http.StatusOK, entries)
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// ErrorBody is the JSON error envelope returned by every /api/v1 route
+type ErrorBody struct {
+	Error ErrorDetail `json:"error"`
+}
+
+// ErrorDetail carries a stable machine-readable code next to the human message
+type ErrorDetail struct {
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// WriteError writes the JSON error envelope; an empty code is derived from the status
+func WriteError(w http.ResponseWriter, status int, code, message string) {
+	if code == "" {
+		code = codeForStatus(status)
+	}
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorBody{Error: ErrorDetail{Status: status, Code: code, Message: message}})
+}
+
+// WriteJSON writes v with the given status
+func WriteJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func codeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "bad_request"
+	case http.StatusUnauthorized:
+		return "unauthorized"
+	case http.StatusForbidden:
+		return "forbidden"
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusMethodNotAllowed:
+		return "method_not_allowed"
+	case http.StatusConflict:
+		return "conflict"
+	case http.StatusRequestEntityTooLarge:
+		return "payload_too_large"
+	case http.StatusUnsupportedMediaType:
+		return "unsupported_media_type"
+	case http.StatusTooManyRequests:
+		return "rate_limited"
+	case http.StatusNotImplemented:
+		return "not_implemented"
+	case http.StatusServiceUnavailable:
+		return "unavailable"
+	}
+	if status >= 500 {
+		return "internal_error"
+	}
+	return "error"
+}
+
+// JSONErrors applies jsonErrors to /api/v1 paths only, so errors raised by the server
+// middleware (rate limits, body limits, panics) use the envelope as well
+func JSONErrors(next http.Handler) http.Handler {
+	wrapped := jsonErrors(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, Prefix+"/") {
+			wrapped.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// jsonErrors turns plain-text errors written with http.Error, by the mux (404, 405)
+// or by the shared handlers, into the JSON error envelope
+func jsonErrors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ew := &errorWriter{ResponseWriter: w}
+		next.ServeHTTP(ew, r)
+		if ew.captured {
+			WriteError(w, ew.status, "", strings.TrimSpace(ew.body.String()))
+		}
+	})
+}
+
+type errorWriter struct {
+	http.ResponseWriter
+	status   int
+	captured bool
+	wrote    bool
+	body     bytes.Buffer
+}
+
+func (ew *errorWriter) WriteHeader(status int) {
+	if ew.wrote || ew.captured {
+		return
+	}
+	if status >= 400 && strings.HasPrefix(ew.Header().Get("Content-Type"), "text/plain") {
+		ew.status = status
+		ew.captured = true
+		return
+	}
+	ew.wrote = true
+	ew.ResponseWriter.WriteHeader(status)
+}
+
+func (ew *errorWriter) Write(p []byte) (int, error) {
+	if ew.captured {
+		return ew.body.Write(p)
+	}
+	ew.wrote = true
+	return ew.ResponseWriter.Write(p)
+}
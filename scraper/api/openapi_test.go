@@ -0,0 +1,411 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"job_scraper/config"
+	"job_scraper/scraper/auth"
+	"job_scraper/scraper/skills"
+)
+
+func loadSpec(t *testing.T) map[string]interface{} {
+	t.Helper()
+	var spec map[string]interface{}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+	return spec
+}
+
+func TestRoutesMatchSpec(t *testing.T) {
+	spec := loadSpec(t)
+
+	documented := map[string]bool{}
+	for path, item := range spec["paths"].(map[string]interface{}) {
+		for method := range item.(map[string]interface{}) {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	registered := map[string]bool{}
+	for _, rt := range routes {
+		key := rt.method + " " + rt.path
+		registered[key] = true
+		if !documented[key] {
+			t.Errorf("route %s is not documented in openapi.json", key)
+		}
+	}
+	for key := range documented {
+		if !registered[key] {
+			t.Errorf("openapi.json documents %s but no route serves it", key)
+		}
+	}
+}
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := config.InitializeDatabaseAt(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := skills.SeedTaxonomy(db); err != nil {
+		t.Fatalf("seed taxonomy: %v", err)
+	}
+
+	seed := []string{
+		`INSERT INTO linkedin_jobs (id, jobid, title, company, location, posted_date, link, processed, sent, scraped_at, processed_at)
+		 VALUES ('101', '101', 'Go Developer', 'Acme', 'Berlin', '2025-01-10', 'https://linkedin.example/101', 1, 0, '2025-01-10 08:00:00', '2025-01-10 09:00:00'),
+		        ('102', '102', 'Data Engineer', 'Beta', 'Munich', 'Unknown', 'https://linkedin.example/102', 0, 0, '2025-01-11 08:00:00', NULL)`,
+		`INSERT INTO xing_jobs (id, jobid, title, company, location, posted_date, link, processed, sent, scraped_at)
+		 VALUES ('x1', 'x1', 'Backend Engineer', 'Gamma', 'Hamburg', '2025-01-12', 'https://xing.example/x1', 1, 1, '2025-01-12 08:00:00'),
+		        ('x2', 'x2', 'Go Developer', 'ACME', 'Berlin', '2025-01-13', 'https://xing.example/x2', 0, 0, '2025-01-13 08:00:00')`,
+		`INSERT INTO linkedin_failed_jobs (job_id, job_link, reason, failed_at)
+		 VALUES ('102', 'https://linkedin.example/102', 'Description not found', '2025-01-11 09:00:00')`,
+		`INSERT INTO linkedin_job_application_links (job_id, job_link) VALUES ('101', 'https://acme.example/apply')`,
+		`INSERT INTO xing_job_application_links (job_id, job_link) VALUES ('x1', 'https://gamma.example/apply')`,
+		`INSERT INTO linkedin_job_description (job_id, job_link, job_description, job_type, skills, summary_status, summarized_at)
+		 VALUES ('101', 'https://linkedin.example/101', 'Build services', 'Hybrid', 'Go, PostgreSQL', 'done', '2025-01-10 10:00:00')`,
+		`INSERT INTO linkedin_job_description (job_id, job_link, raw_description, summary_status)
+		 VALUES ('102', 'https://linkedin.example/102', 'Pipelines', 'pending')`,
+		`INSERT INTO xing_job_description (job_id, job_link, job_description, job_type, skills, summary_status)
+		 VALUES ('x1', 'https://xing.example/x1', 'APIs', 'Remote', 'Go, Docker', 'done')`,
+		`INSERT INTO scrape_pauses (source, state, url, paused_at, resume_after)
+		 VALUES ('Xing', 'rate_limited', 'https://www.xing.com/jobs/search', '2025-01-13 09:00:00', '2025-01-13 09:30:00')`,
+		`INSERT INTO scrape_events (source, kind, state, url, message)
+		 VALUES ('Xing', 'paused', 'rate_limited', 'https://www.xing.com/jobs/search', 'Xing showed a rate_limited page'),
+		        ('LinkedIn', 'selector_broken', NULL, NULL, 'LinkedIn selectors for listing.company matched nothing on 3 pages')`,
+		`INSERT INTO selector_health (source, field, version, selector, matches, pages, broken, checked_at)
+		 VALUES ('LinkedIn', 'listing.card', 1, '.jobs-search__results-list li', 75, 3, 0, '2025-01-13 09:00:00'),
+		        ('LinkedIn', 'listing.company', 1, NULL, 0, 3, 1, '2025-01-13 09:00:00')`,
+	}
+	for _, stmt := range seed {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+	if err := skills.IndexJob(db, "LinkedIn", "101", []string{"Go", "PostgreSQL"}); err != nil {
+		t.Fatalf("index skills: %v", err)
+	}
+	if err := skills.IndexJob(db, "Xing", "x1", []string{"Go", "Docker"}); err != nil {
+		t.Fatalf("index skills: %v", err)
+	}
+	return db
+}
+
+// mintKey stores a key with the given scopes and returns its token
+func mintKey(t *testing.T, db *sql.DB, scopes ...string) string {
+	t.Helper()
+	token, _, err := auth.Mint(db, "test-"+strings.Join(scopes, "-"), scopes)
+	if err != nil {
+		t.Fatalf("mint key: %v", err)
+	}
+	return token
+}
+
+// TestHandlersConformToSpec calls the handlers through the router and validates each
+// JSON body against the schema documented for its route and status. Objects are
+// checked strictly: a field the handler emits but the spec lacks is a failure.
+func TestHandlersConformToSpec(t *testing.T) {
+	spec := loadSpec(t)
+	db := newTestDB(t)
+	router := NewRouter(db)
+	keys := map[string]string{
+		"read":  mintKey(t, db, auth.ScopeRead),
+		"admin": mintKey(t, db, auth.ScopeAdmin),
+	}
+	revoked, key, err := auth.Mint(db, "revoked", []string{auth.ScopeRead})
+	if err != nil {
+		t.Fatalf("mint key: %v", err)
+	}
+	if err := auth.Revoke(db, key.ID); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	keys["revoked"] = revoked
+
+	cases := []struct {
+		method, route, target, body string
+		key                         string
+	}{
+		{"GET", "/jobs", "/jobs", "", "read"},
+		{"GET", "/jobs", "/jobs?limit=1&sort=-scraped_at", "", "read"},
+		{"GET", "/jobs", "/jobs?status=bogus", "", "read"},
+		{"GET", "/jobs/{id}", "/jobs/101", "", "read"},
+		{"GET", "/jobs/{id}", "/jobs/102?source=linkedin", "", "read"},
+		{"GET", "/jobs/{id}", "/jobs/x2", "", "read"},
+		{"GET", "/jobs/{id}", "/jobs/missing", "", "read"},
+		{"POST", "/scrapes", "/scrapes", `{"source": "monster"}`, "admin"},
+		{"POST", "/scrapes", "/scrapes", `not json`, "admin"},
+		{"GET", "/scrapes/pauses", "/scrapes/pauses", "", "read"},
+		{"GET", "/scrapes/events", "/scrapes/events?limit=5", "", "read"},
+		{"GET", "/scrapes/proxies", "/scrapes/proxies", "", "read"},
+		{"GET", "/scrapes/selectors", "/scrapes/selectors", "", "read"},
+		{"DELETE", "/scrapes/pauses/{source}", "/scrapes/pauses/monster", "", "admin"},
+		{"DELETE", "/scrapes/pauses/{source}", "/scrapes/pauses/linkedin", "", "admin"},
+		{"GET", "/sources/linkedin/links", "/sources/linkedin/links", "", "read"},
+		{"GET", "/sources/linkedin/descriptions", "/sources/linkedin/descriptions", "", "read"},
+		{"GET", "/sources/xing/links", "/sources/xing/links", "", "read"},
+		{"GET", "/sources/xing/descriptions", "/sources/xing/descriptions?status=done", "", "read"},
+		{"GET", "/skills", "/skills", "", "read"},
+		{"GET", "/skills/trends", "/skills/trends?interval=day", "", "read"},
+		{"GET", "/skills/trends", "/skills/trends?interval=year", "", "read"},
+		{"GET", "/analytics/skills", "/analytics/skills?min_count=1", "", "read"},
+		{"GET", "/analytics/overview", "/analytics/overview", "", "read"},
+		{"GET", "/analytics/overview", "/analytics/overview?since=yesterday", "", "read"},
+		{"GET", "/search", "/search?q=go", "", "read"},
+		{"GET", "/summary-cache/stats", "/summary-cache/stats", "", "read"},
+		{"GET", "/audit", "/audit", "", "admin"},
+		{"GET", "/audit", "/audit", "", "read"},
+		{"GET", "/jobs", "/jobs", "", ""},
+		{"GET", "/jobs", "/jobs", "", "revoked"},
+		{"POST", "/scrapes", "/scrapes", `{"source": "linkedin"}`, "read"},
+		{"POST", "/uploads", "/uploads?sinks=ftp", "", "admin"},
+		// Marks the seed jobs as sent, so it runs last
+		{"POST", "/uploads", "/uploads?sinks=file", "", "admin"},
+		{"GET", "/uploads/status", "/uploads/status", "", "read"},
+		{"GET", "/jobs/{id}", "/jobs/x1", "", "read"},
+	}
+	t.Setenv("UPLOAD_DIR", t.TempDir())
+
+	for _, tc := range cases {
+		t.Run(tc.method+" "+tc.target, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, Prefix+tc.target, strings.NewReader(tc.body))
+			if tc.key != "" {
+				req.Header.Set("Authorization", "Bearer "+keys[tc.key])
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			op, ok := lookup(spec, "paths", tc.route, strings.ToLower(tc.method)).(map[string]interface{})
+			if !ok {
+				t.Fatalf("no operation %s %s in spec", tc.method, tc.route)
+			}
+			status := fmt.Sprint(rec.Code)
+			response, _ := lookup(op, "responses", status).(map[string]interface{})
+			schema, ok := lookup(resolve(spec, response), "content", "application/json", "schema").(map[string]interface{})
+			if !ok {
+				t.Fatalf("status %s is not documented for %s %s (body: %s)", status, tc.method, tc.route, rec.Body.String())
+			}
+			if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+				t.Fatalf("Content-Type = %q, want application/json", ct)
+			}
+
+			var body interface{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("response is not JSON: %v\n%s", err, rec.Body.String())
+			}
+			for _, problem := range validate(spec, schema, body, "$") {
+				t.Error(problem)
+			}
+		})
+	}
+}
+
+func lookup(v interface{}, keys ...string) interface{} {
+	for _, k := range keys {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[k]
+	}
+	return v
+}
+
+func resolve(spec, schema map[string]interface{}) map[string]interface{} {
+	if schema == nil {
+		return map[string]interface{}{}
+	}
+	for {
+		ref, ok := schema["$ref"].(string)
+		if !ok {
+			return schema
+		}
+		keys := strings.Split(strings.TrimPrefix(ref, "#/"), "/")
+		schema, _ = lookup(spec, keys...).(map[string]interface{})
+		if schema == nil {
+			return map[string]interface{}{}
+		}
+	}
+}
+
+// validate covers the subset of OpenAPI 3.0 schemas the document uses
+func validate(spec, schema map[string]interface{}, v interface{}, at string) []string {
+	schema = resolve(spec, schema)
+
+	if v == nil {
+		if schema["nullable"] == true {
+			return nil
+		}
+		return []string{at + ": null is not allowed"}
+	}
+
+	if parts, ok := schema["allOf"].([]interface{}); ok {
+		var problems []string
+		for _, part := range parts {
+			problems = append(problems, validate(spec, part.(map[string]interface{}), v, at)...)
+		}
+		return problems
+	}
+
+	if variants, ok := schema["oneOf"].([]interface{}); ok {
+		for _, variant := range variants {
+			if len(validate(spec, variant.(map[string]interface{}), v, at)) == 0 {
+				return nil
+			}
+		}
+		return []string{at + ": matches none of the oneOf schemas"}
+	}
+
+	var problems []string
+	switch schema["type"] {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: want object, got %T", at, v)}
+		}
+		props, _ := schema["properties"].(map[string]interface{})
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := obj[name.(string)]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: missing required field %q", at, name))
+			}
+		}
+		extra, _ := schema["additionalProperties"].(map[string]interface{})
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if prop, ok := props[k].(map[string]interface{}); ok {
+				problems = append(problems, validate(spec, prop, obj[k], at+"."+k)...)
+			} else if extra != nil {
+				problems = append(problems, validate(spec, extra, obj[k], at+"."+k)...)
+			} else {
+				problems = append(problems, fmt.Sprintf("%s: field %q is not in the spec", at, k))
+			}
+		}
+	case "array":
+		arr, ok := v.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: want array, got %T", at, v)}
+		}
+		items, _ := schema["items"].(map[string]interface{})
+		for i, item := range arr {
+			problems = append(problems, validate(spec, items, item, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			return []string{fmt.Sprintf("%s: want string, got %T", at, v)}
+		}
+		if enum, ok := schema["enum"].([]interface{}); ok {
+			found := false
+			for _, e := range enum {
+				found = found || e == s
+			}
+			if !found {
+				problems = append(problems, fmt.Sprintf("%s: %q is not in enum %v", at, s, enum))
+			}
+		}
+	case "integer":
+		n, ok := v.(float64)
+		if !ok || n != math.Trunc(n) {
+			return []string{fmt.Sprintf("%s: want integer, got %v", at, v)}
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			return []string{fmt.Sprintf("%s: want number, got %T", at, v)}
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return []string{fmt.Sprintf("%s: want boolean, got %T", at, v)}
+		}
+	}
+	return problems
+}
+
+func TestLegacyAliasesAreDeprecated(t *testing.T) {
+	db := newTestDB(t)
+	router := NewRouter(db)
+
+	req := httptest.NewRequest(http.MethodGet, "/viewlinkedmetadata", nil)
+	req.Header.Set("X-API-Key", mintKey(t, db, auth.ScopeRead))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if rec.Header().Get("Deprecation") != "true" {
+		t.Error("missing Deprecation header")
+	}
+	if link := rec.Header().Get("Link"); !strings.Contains(link, Prefix+"/jobs") {
+		t.Errorf("Link = %q, want successor %s/jobs", link, Prefix)
+	}
+}
+
+func TestLegacyAliasMethods(t *testing.T) {
+	db := newTestDB(t)
+	router := NewRouter(db)
+	key := mintKey(t, db, auth.ScopeRead)
+
+	for _, tc := range []struct {
+		method, path string
+		status       int
+	}{
+		// Read-only aliases are served as GET whatever the method
+		{http.MethodGet, "/viewlinkedmetadata", http.StatusOK},
+		{http.MethodPost, "/viewxingjobs", http.StatusOK},
+		// Scrape and upload aliases only take POST; with a read key POST stops at the scope check
+		{http.MethodPost, "/uploadjobs", http.StatusForbidden},
+		{http.MethodPost, "/joblistings", http.StatusForbidden},
+		{http.MethodPost, "/loginlinkedin", http.StatusForbidden},
+		{http.MethodPost, "/loginxing", http.StatusForbidden},
+		{http.MethodGet, "/uploadjobs", http.StatusMethodNotAllowed},
+		{http.MethodPut, "/joblistings", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/loginlinkedin", http.StatusMethodNotAllowed},
+		{http.MethodDelete, "/loginxing", http.StatusMethodNotAllowed},
+	} {
+		t.Run(tc.method+" "+tc.path, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			req.Header.Set("X-API-Key", key)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tc.status {
+				t.Fatalf("status = %d, want %d (body: %s)", rec.Code, tc.status, rec.Body.String())
+			}
+			if tc.status == http.StatusMethodNotAllowed {
+				if allow := rec.Header().Get("Allow"); allow != http.MethodPost {
+					t.Errorf("Allow = %q, want POST", allow)
+				}
+			} else if rec.Header().Get("Deprecation") != "true" {
+				t.Error("missing Deprecation header")
+			}
+		})
+	}
+}
+
+func TestErrorsKeepNosniff(t *testing.T) {
+	router := NewRouter(newTestDB(t))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Prefix+"/jobs", nil))
+
+	if rec.Code != http.StatusUnauthorized || !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+		t.Fatalf("status = %d, Content-Type = %q; want a 401 JSON envelope", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if got := rec.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Errorf("X-Content-Type-Options = %q, want nosniff", got)
+	}
+}
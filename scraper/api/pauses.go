@@ -0,0 +1,59 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"job_scraper/scraper/browser"
+	"job_scraper/scraper/pause"
+)
+
+// listPauses returns the sources whose scrapes are paused
+func listPauses(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	pauses, err := pause.Active(db)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "", "Failed to read scrape pauses")
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]interface{}{"pauses": pauses})
+}
+
+// resumeScrapes lifts the pause of a source (linkedin or xing) by hand, e.g. after
+// clearing a checkpoint in the browser or to skip a rate-limit cooldown
+func resumeScrapes(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	site, ok := browser.Sites[strings.ToLower(r.PathValue("source"))]
+	if !ok {
+		WriteError(w, http.StatusNotFound, "", "Unknown source; use linkedin or xing")
+		return
+	}
+	resumed, err := pause.Resume(db, site.Name, "resumed through the API")
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "", "Failed to resume "+site.Name)
+		return
+	}
+	if !resumed {
+		WriteError(w, http.StatusNotFound, "", site.Name+" is not paused")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listScrapeEvents returns the newest pause, resume and selector events; ?limit= defaults to 100, max 1000
+func listScrapeEvents(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 100
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	events, err := pause.Events(db, limit)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "", "Failed to read scrape events")
+		return
+	}
+	WriteJSON(w, http.StatusOK, events)
+}
@@ -0,0 +1,14 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+
+	"job_scraper/scraper/rotation"
+)
+
+// listProxies returns the health of the listing scrapes' proxy pool; it is empty when
+// scrapes go direct
+func listProxies(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, http.StatusOK, map[string]interface{}{"proxies": rotation.Default().Proxies()})
+}
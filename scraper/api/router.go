@@ -0,0 +1,132 @@
+// Package api wires the HTTP routes. Resources live under /api/v1 with method
+// patterns and JSON error bodies; the old verb-style paths stay registered as
+// deprecated aliases until the frontend has moved over.
+package api
+
+import (
+	"database/sql"
+	"net/http"
+
+	"job_scraper/scraper"
+	"job_scraper/scraper/Linkedin"
+	"job_scraper/scraper/Xing"
+	"job_scraper/scraper/analytics"
+	"job_scraper/scraper/auth"
+	"job_scraper/scraper/search"
+	"job_scraper/scraper/skills"
+	"job_scraper/scraper/summary"
+)
+
+const Prefix = "/api/v1"
+
+// route is one /api/v1 endpoint; path is relative to Prefix and matches the OpenAPI document
+type route struct {
+	method  string
+	path    string
+	scope   string
+	handler func(db *sql.DB, w http.ResponseWriter, r *http.Request)
+}
+
+var routes = []route{
+	{"GET", "/jobs", auth.ScopeRead, func(db *sql.DB, w http.ResponseWriter, r *http.Request) { scraper.ViewJobsHandler(w, r, db) }},
+	{"GET", "/jobs/{id}", auth.ScopeRead, func(db *sql.DB, w http.ResponseWriter, r *http.Request) { scraper.GetJobHandler(w, r, db) }},
+	{"POST", "/scrapes", auth.ScopeScrape, createScrape},
+	{"GET", "/scrapes/pauses", auth.ScopeRead, listPauses},
+	{"DELETE", "/scrapes/pauses/{source}", auth.ScopeScrape, resumeScrapes},
+	{"GET", "/scrapes/events", auth.ScopeRead, listScrapeEvents},
+	{"GET", "/scrapes/proxies", auth.ScopeRead, listProxies},
+	{"GET", "/scrapes/selectors", auth.ScopeRead, listSelectorHealth},
+	{"POST", "/uploads", auth.ScopeUpload, func(db *sql.DB, w http.ResponseWriter, r *http.Request) { scraper.UploadHandler(w, r, db) }},
+	{"GET", "/uploads/status", auth.ScopeRead, uploadStatus},
+
+	{"GET", "/sources/linkedin/links", auth.ScopeRead, Linkedin.ViewLinkedInJobs},
+	{"GET", "/sources/linkedin/descriptions", auth.ScopeRead, Linkedin.ViewLinkedInJobDescriptions},
+	{"GET", "/sources/xing/links", auth.ScopeRead, Xing.ViewXingJobs},
+	{"GET", "/sources/xing/descriptions", auth.ScopeRead, Xing.ViewXingJobDescriptions},
+
+	{"GET", "/skills", auth.ScopeRead, skills.SkillsHandler},
+	{"GET", "/skills/trends", auth.ScopeRead, skills.SkillTrendsHandler},
+	{"GET", "/analytics/skills", auth.ScopeRead, analytics.SkillAnalyticsHandler},
+	{"GET", "/analytics/overview", auth.ScopeRead, analytics.OverviewHandler},
+	{"GET", "/search", auth.ScopeRead, search.SearchHandler},
+	{"GET", "/summary-cache/stats", auth.ScopeRead, summary.CacheStatsHandler},
+
+	{"GET", "/audit", auth.ScopeAdmin, listAudit},
+}
+
+// NewRouter returns the handler serving /api/v1, the API docs and the legacy aliases
+func NewRouter(db *sql.DB) http.Handler {
+	v1 := http.NewServeMux()
+	for _, rt := range routes {
+		handler := rt.handler
+		v1.Handle(rt.method+" "+Prefix+rt.path, auth.Require(db, rt.scope, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler(db, w, r)
+		})))
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(Prefix+"/", jsonErrors(v1))
+	mux.HandleFunc("GET /openapi.json", serveOpenAPI)
+	mux.HandleFunc("GET /docs", serveDocs)
+	registerLegacy(mux, v1, db)
+	return mux
+}
+
+// registerLegacy keeps the pre-v1 paths working. Read-only aliases are served by the
+// v1 handler whatever their method; the scrape and upload aliases only take POST,
+// like their successors, and need the same scopes.
+func registerLegacy(mux *http.ServeMux, v1 *http.ServeMux, db *sql.DB) {
+	aliases := map[string]string{
+		"/viewlinkedmetadata": Prefix + "/jobs",
+		"/viewlinkedinjobs":   Prefix + "/sources/linkedin/links",
+		"/viewlinkedindesc":   Prefix + "/sources/linkedin/descriptions",
+		"/viewxingjobs":       Prefix + "/sources/xing/links",
+		"/viewxingdesc":       Prefix + "/sources/xing/descriptions",
+		"/skills":             Prefix + "/skills",
+		"/skills/trends":      Prefix + "/skills/trends",
+		"/analytics/skills":   Prefix + "/analytics/skills",
+		"/analytics/overview": Prefix + "/analytics/overview",
+		"/search":             Prefix + "/search",
+		"/summarycache/stats": Prefix + "/summary-cache/stats",
+	}
+	for old, successor := range aliases {
+		successor := successor
+		mux.Handle(old, deprecated(successor, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r2 := r.Clone(r.Context())
+			r2.URL.Path = successor
+			r2.Method = http.MethodGet
+			v1.ServeHTTP(w, r2)
+		})))
+	}
+
+	// Any other method gets the mux's 405 with Allow: POST
+	actions := []struct {
+		path, successor string
+		scope           string
+		handler         func(db *sql.DB, w http.ResponseWriter, r *http.Request)
+	}{
+		{"/uploadjobs", Prefix + "/uploads", auth.ScopeUpload, func(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+			scraper.UploadHandler(w, r, db)
+		}},
+		{"/joblistings", Prefix + "/scrapes", auth.ScopeScrape, func(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+			scraper.JobListingsHandler(w, r, db)
+		}},
+		{"/loginlinkedin", Prefix + "/scrapes", auth.ScopeScrape, Linkedin.LoginLinkedInHandler},
+		{"/loginxing", Prefix + "/scrapes", auth.ScopeScrape, Xing.LoginXingHandler},
+	}
+	for _, a := range actions {
+		handler := a.handler
+		mux.Handle("POST "+a.path, deprecated(a.successor, auth.Require(db, a.scope, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler(db, w, r)
+		}))))
+	}
+}
+
+// deprecated marks a legacy path and points clients at its successor (RFC 8594 style headers)
+func deprecated(successor string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+successor+">; rel=\"successor-version\"")
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"job_scraper/scraper/Linkedin"
+	"job_scraper/scraper/Xing"
+	"job_scraper/scraper/pause"
+)
+
+// ScrapeRequest is the body of POST /api/v1/scrapes.
+// Stage "listings" collects search results, "details" opens every unprocessed job.
+type ScrapeRequest struct {
+	Source string `json:"source"`
+	Stage  string `json:"stage"`
+}
+
+// ScrapeResponse reports a finished listings scrape
+type ScrapeResponse struct {
+	Source string `json:"source"`
+	Stage  string `json:"stage"`
+	Status string `json:"status"`
+}
+
+func createScrape(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	var req ScrapeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			WriteError(w, http.StatusRequestEntityTooLarge, "", fmt.Sprintf("Body must be at most %d bytes", tooLarge.Limit))
+			return
+		}
+		WriteError(w, http.StatusBadRequest, "invalid_body", "Body must be JSON like {\"source\": \"linkedin\", \"stage\": \"listings\"}")
+		return
+	}
+	req.Source = strings.ToLower(req.Source)
+	if req.Stage == "" {
+		req.Stage = "listings"
+	}
+
+	// Scrapes outlive the request on purpose; a client timeout must not abort the browser mid-run
+	ctx := context.Background()
+
+	switch req.Source + "/" + req.Stage {
+	case "linkedin/listings":
+		if err := Linkedin.LinkedinJobListingsHandler(ctx, db); err != nil {
+			scrapeFailed(w, "LinkedIn", err)
+			return
+		}
+	case "xing/listings":
+		if err := Xing.XingJobListingsHandler(ctx, db); err != nil {
+			scrapeFailed(w, "Xing", err)
+			return
+		}
+	case "linkedin/details":
+		Linkedin.LoginLinkedInHandler(db, w, r)
+		return
+	case "xing/details":
+		Xing.LoginXingHandler(db, w, r)
+		return
+	default:
+		WriteError(w, http.StatusBadRequest, "invalid_scrape", "source must be linkedin or xing and stage listings or details")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, ScrapeResponse{Source: req.Source, Stage: req.Stage, Status: "completed"})
+}
+
+// scrapeFailed tells a paused source (409) apart from a failing scraper (502)
+func scrapeFailed(w http.ResponseWriter, source string, err error) {
+	if pause.IsPaused(err) {
+		WriteError(w, http.StatusConflict, "scrape_paused", source+" is paused: "+err.Error())
+		return
+	}
+	WriteError(w, http.StatusBadGateway, "scrape_failed", source+" error: "+err.Error())
+}
@@ -0,0 +1,24 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+
+	"job_scraper/scraper/selectors"
+)
+
+// listSelectorHealth returns the selectors version in use and, per field, what the
+// last run that looked for it matched; broken fields come first
+func listSelectorHealth(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	statuses, err := selectors.Statuses(db)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "", "Failed to read selector health")
+		return
+	}
+	file := selectors.Default()
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"version": file.Version,
+		"updated": file.Updated,
+		"fields":  statuses,
+	})
+}
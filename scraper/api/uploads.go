@@ -0,0 +1,18 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+
+	"job_scraper/scraper/outbox"
+)
+
+// uploadStatus reports the outbox delivery state per sink
+func uploadStatus(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	stats, err := outbox.Stats(db)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "", "Failed to read the upload outbox")
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]interface{}{"sinks": stats})
+}
@@ -0,0 +1,204 @@
+// Package auth stores per-user API keys and guards the HTTP routes with them.
+// Keys are random tokens shown once when minted; only their sha256 hash is stored.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Scopes a key can hold; admin implies all others
+const (
+	ScopeRead   = "read"
+	ScopeScrape = "scrape"
+	ScopeUpload = "upload"
+	ScopeAdmin  = "admin"
+)
+
+var knownScopes = map[string]bool{ScopeRead: true, ScopeScrape: true, ScopeUpload: true, ScopeAdmin: true}
+
+// keyPrefix marks tokens of this service so they are recognizable in configs and logs
+const keyPrefix = "jse_"
+
+var (
+	ErrInvalidKey = errors.New("invalid or revoked API key")
+	ErrNotFound   = errors.New("API key not found")
+)
+
+// Key is a stored API key without its secret
+type Key struct {
+	ID         int64    `json:"id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	CreatedAt  string   `json:"created_at"`
+	LastUsedAt string   `json:"last_used_at"`
+	RevokedAt  string   `json:"revoked_at"`
+}
+
+// Allows reports whether the key may use an endpoint requiring scope
+func (k Key) Allows(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseScopes validates a comma-separated scope list
+func ParseScopes(raw string) ([]string, error) {
+	seen := map[string]bool{}
+	var scopes []string
+	for _, s := range strings.Split(raw, ",") {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s == "" || seen[s] {
+			continue
+		}
+		if !knownScopes[s] {
+			return nil, fmt.Errorf("unknown scope %q (want read, scrape, upload or admin)", s)
+		}
+		seen[s] = true
+		scopes = append(scopes, s)
+	}
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+	sort.Strings(scopes)
+	return scopes, nil
+}
+
+func hashKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Mint creates a key and returns its plaintext token, which is not stored anywhere
+func Mint(db *sql.DB, name string, scopes []string) (string, Key, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", Key{}, fmt.Errorf("failed to generate key: %v", err)
+	}
+	token := keyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	key := Key{Name: name, Prefix: token[:len(keyPrefix)+8], Scopes: scopes}
+
+	res, err := db.Exec(`INSERT INTO api_keys (name, prefix, key_hash, scopes) VALUES (?, ?, ?, ?)`,
+		name, key.Prefix, hashKey(token), strings.Join(scopes, ","))
+	if err != nil {
+		return "", Key{}, fmt.Errorf("failed to store key: %v", err)
+	}
+	key.ID, _ = res.LastInsertId()
+	return token, key, nil
+}
+
+// Revoke disables a key by id; revoked keys stay listed for the audit trail
+func Revoke(db *sql.DB, id int64) error {
+	res, err := db.Exec(`UPDATE api_keys SET revoked_at = CURRENT_TIMESTAMP WHERE id = ? AND revoked_at IS NULL`, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke key: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// List returns all keys, revoked ones included
+func List(db *sql.DB) ([]Key, error) {
+	rows, err := db.Query(`
+		SELECT id, name, prefix, scopes, COALESCE(created_at, ''), COALESCE(last_used_at, ''), COALESCE(revoked_at, '')
+		FROM api_keys ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keys: %v", err)
+	}
+	defer rows.Close()
+
+	keys := []Key{}
+	for rows.Next() {
+		var k Key
+		var scopes string
+		if err := rows.Scan(&k.ID, &k.Name, &k.Prefix, &scopes, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan key: %v", err)
+		}
+		k.Scopes = strings.Split(scopes, ",")
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// lastUsedResolution is how stale last_used_at may get: Authenticate only writes it
+// once the stored value is older, so busy keys do not cost a write per request
+const lastUsedResolution = "-1 minute"
+
+// Authenticate looks up an active key by its plaintext token
+func Authenticate(db *sql.DB, token string) (Key, error) {
+	var k Key
+	var scopes string
+	err := db.QueryRow(`
+		SELECT id, name, prefix, scopes, COALESCE(created_at, '')
+		FROM api_keys WHERE key_hash = ? AND revoked_at IS NULL`, hashKey(token)).
+		Scan(&k.ID, &k.Name, &k.Prefix, &scopes, &k.CreatedAt)
+	if err == sql.ErrNoRows {
+		return Key{}, ErrInvalidKey
+	}
+	if err != nil {
+		return Key{}, fmt.Errorf("failed to look up key: %v", err)
+	}
+	k.Scopes = strings.Split(scopes, ",")
+
+	if _, err := db.Exec(`
+		UPDATE api_keys SET last_used_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND (last_used_at IS NULL OR last_used_at < datetime('now', ?))`, k.ID, lastUsedResolution); err != nil {
+		return Key{}, fmt.Errorf("failed to touch key: %v", err)
+	}
+	return k, nil
+}
+
+// AuditEntry is one audited request
+type AuditEntry struct {
+	ID         int64  `json:"id"`
+	KeyID      int64  `json:"key_id"`
+	KeyName    string `json:"key_name"`
+	Scope      string `json:"scope"`
+	Method     string `json:"method"`
+	Path       string `json:"path"`
+	Status     int    `json:"status"`
+	RemoteAddr string `json:"remote_addr"`
+	CreatedAt  string `json:"created_at"`
+}
+
+func recordAudit(db *sql.DB, e AuditEntry) error {
+	_, err := db.Exec(`
+		INSERT INTO api_audit_log (key_id, key_name, scope, method, path, status, remote_addr)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		e.KeyID, e.KeyName, e.Scope, e.Method, e.Path, e.Status, e.RemoteAddr)
+	return err
+}
+
+// AuditLog returns the most recent audit entries, newest first
+func AuditLog(db *sql.DB, limit int) ([]AuditEntry, error) {
+	rows, err := db.Query(`
+		SELECT id, key_id, key_name, scope, method, path, status, COALESCE(remote_addr, ''), COALESCE(created_at, '')
+		FROM api_audit_log ORDER BY id DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %v", err)
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.ID, &e.KeyID, &e.KeyName, &e.Scope, &e.Method, &e.Path, &e.Status, &e.RemoteAddr, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %v", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
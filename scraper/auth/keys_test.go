@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"path/filepath"
+	"testing"
+
+	"job_scraper/config"
+)
+
+func TestAuthenticateThrottlesLastUsed(t *testing.T) {
+	db, err := config.InitializeDatabaseAt(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	token, key, err := Mint(db, "test", []string{ScopeRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		age   string // of last_used_at before the request, as a SQLite modifier; empty for never
+		touch bool
+	}{
+		{"never used", "", true},
+		{"used seconds ago", "-10 seconds", false},
+		{"used just under a minute ago", "-50 seconds", false},
+		{"used two minutes ago", "-2 minutes", true},
+		{"used last week", "-7 days", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := db.Exec(`UPDATE api_keys SET last_used_at = NULL WHERE id = ?`, key.ID); err != nil {
+				t.Fatal(err)
+			}
+			if tc.age != "" {
+				if _, err := db.Exec(`UPDATE api_keys SET last_used_at = datetime('now', ?) WHERE id = ?`, tc.age, key.ID); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var before string
+			db.QueryRow(`SELECT COALESCE(last_used_at, '') FROM api_keys WHERE id = ?`, key.ID).Scan(&before)
+
+			if _, err := Authenticate(db, token); err != nil {
+				t.Fatal(err)
+			}
+			var after string
+			var fresh bool
+			if err := db.QueryRow(`SELECT last_used_at, last_used_at >= datetime('now', '-5 seconds') FROM api_keys WHERE id = ?`, key.ID).Scan(&after, &fresh); err != nil {
+				t.Fatal(err)
+			}
+			if touched := after != before && fresh; touched != tc.touch {
+				t.Errorf("last_used_at %q -> %q, touched = %v, want %v", before, after, touched, tc.touch)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	"strings"
+)
+
+type contextKey struct{}
+
+// FromContext returns the key that authenticated the request
+func FromContext(ctx context.Context) (Key, bool) {
+	k, ok := ctx.Value(contextKey{}).(Key)
+	return k, ok
+}
+
+// tokenFromRequest accepts "Authorization: Bearer <key>" or "X-API-Key: <key>"
+func tokenFromRequest(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); h != "" {
+		if scheme, token, ok := strings.Cut(h, " "); ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
+
+// Require rejects requests without an active key holding scope. Everything but
+// read calls is written to the audit log with the resulting status.
+func Require(db *sql.DB, scope string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := tokenFromRequest(r)
+		if token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="job_scraper"`)
+			http.Error(w, "API key required: send Authorization: Bearer <key> or X-API-Key", http.StatusUnauthorized)
+			return
+		}
+
+		key, err := Authenticate(db, token)
+		if err == ErrInvalidKey {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="job_scraper", error="invalid_token"`)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			log.Printf("❌ API key lookup failed: %v", err)
+			http.Error(w, "Failed to check API key", http.StatusInternalServerError)
+			return
+		}
+		if !key.Allows(scope) {
+			http.Error(w, "API key lacks the "+scope+" scope", http.StatusForbidden)
+			return
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), contextKey{}, key))
+		if scope == ScopeRead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+		entry := AuditEntry{
+			KeyID:      key.ID,
+			KeyName:    key.Name,
+			Scope:      scope,
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Status:     sw.status,
+			RemoteAddr: r.RemoteAddr,
+		}
+		if err := recordAudit(db, entry); err != nil {
+			log.Printf("⚠️ Failed to write audit log for key %s: %v", key.Name, err)
+		} else {
+			log.Printf("📝 %s %s by key %q -> %d", r.Method, r.URL.Path, key.Name, sw.status)
+		}
+	})
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	if !sw.wroteHeader {
+		sw.status = status
+		sw.wroteHeader = true
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(p []byte) (int, error) {
+	sw.wroteHeader = true
+	return sw.ResponseWriter.Write(p)
+}
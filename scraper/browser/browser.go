@@ -0,0 +1,395 @@
+// Package browser owns the Chromium processes the scrapers drive. A Manager runs at
+// most one Chromium per profile, each with its own user data directory and a free
+// remote-debugging port, attaches to one that is already running for that profile,
+// and on shutdown stops exactly the processes it started.
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config is the manager configuration; see ConfigFromEnv for the variables
+type Config struct {
+	ExecPath     string        // Chromium binary; empty looks it up in PATH
+	ProfilesDir  string        // parent of the per-profile user data directories
+	StartTimeout time.Duration // how long a started browser has to answer /json/version
+	Flags        []string      // extra command-line flags for every started browser
+	Headless     bool          // start browsers without a window unless Options.Visible
+}
+
+// ConfigFromEnv reads
+//
+//	CHROMIUM_PATH          default: chromium, chromium-browser or google-chrome from PATH
+//	BROWSER_PROFILES_DIR   default browser-profiles
+//	BROWSER_START_TIMEOUT  default 15s
+//	BROWSER_FLAGS          extra flags, space-separated
+//	BROWSER_HEADLESS       true to run scrapes without a window (default false)
+func ConfigFromEnv() Config {
+	cfg := Config{
+		ExecPath:     os.Getenv("CHROMIUM_PATH"),
+		ProfilesDir:  os.Getenv("BROWSER_PROFILES_DIR"),
+		StartTimeout: 15 * time.Second,
+		Flags:        strings.Fields(os.Getenv("BROWSER_FLAGS")),
+	}
+	if cfg.ProfilesDir == "" {
+		cfg.ProfilesDir = "browser-profiles"
+	}
+	if raw := os.Getenv("BROWSER_START_TIMEOUT"); raw != "" {
+		if d, err := time.ParseDuration(raw); err == nil && d > 0 {
+			cfg.StartTimeout = d
+		} else {
+			log.Printf("⚠️ Ignoring invalid BROWSER_START_TIMEOUT=%q", raw)
+		}
+	}
+	if raw := os.Getenv("BROWSER_HEADLESS"); raw != "" {
+		headless, err := strconv.ParseBool(raw)
+		if err != nil {
+			log.Printf("⚠️ Ignoring invalid BROWSER_HEADLESS=%q", raw)
+		}
+		cfg.Headless = headless
+	}
+	return cfg
+}
+
+// Options tune how a profile's browser is started; they are ignored when attaching
+type Options struct {
+	Flags   []string // e.g. --window-size=800,600
+	Visible bool     // open a window even when the manager runs headless, e.g. for a login
+}
+
+// Browser is a running Chromium for one profile
+type Browser struct {
+	Profile  string
+	Port     int
+	Headless bool
+	owned    bool // started by this manager, so it is stopped by it
+	cmd      *exec.Cmd
+	exited   chan struct{}
+	refs     int
+	m        *Manager
+}
+
+// DebugURL is the remote-debugging endpoint, for chromedp.NewRemoteAllocator
+func (b *Browser) DebugURL() string {
+	return "http://127.0.0.1:" + strconv.Itoa(b.Port)
+}
+
+// Owned reports whether the manager started this browser
+func (b *Browser) Owned() bool { return b.owned }
+
+// Release gives the browser back. The last release of a browser the manager started
+// stops it; an attached browser is left running.
+func (b *Browser) Release() {
+	b.m.release(b)
+}
+
+// Manager starts, shares and stops the browsers of all profiles
+type Manager struct {
+	cfg      Config
+	mu       sync.Mutex
+	browsers map[string]*Browser
+	pending  map[string]chan struct{} // profiles with an Acquire under way, closed when it is done
+}
+
+func NewManager(cfg Config) *Manager {
+	if cfg.StartTimeout <= 0 {
+		cfg.StartTimeout = 15 * time.Second
+	}
+	return &Manager{cfg: cfg, browsers: make(map[string]*Browser), pending: make(map[string]chan struct{})}
+}
+
+var (
+	defaultManager *Manager
+	defaultOnce    sync.Once
+)
+
+// Default is the process-wide manager configured from the environment
+func Default() *Manager {
+	defaultOnce.Do(func() { defaultManager = NewManager(ConfigFromEnv()) })
+	return defaultManager
+}
+
+// ProfileDir is the user data directory of a profile
+func (m *Manager) ProfileDir(profile string) string {
+	return filepath.Join(m.cfg.ProfilesDir, profile)
+}
+
+// Acquire returns the browser of a profile: the one this manager already runs, one
+// found running on the profile directory, or a newly started one. Every Acquire
+// needs a Release.
+//
+// Acquires of one profile take turns, since a profile directory can only be open in
+// one Chromium at a time. The manager's lock is not held while a browser is probed
+// or started, so other profiles are not held up meanwhile.
+func (m *Manager) Acquire(ctx context.Context, profile string, opts Options) (*Browser, error) {
+	m.mu.Lock()
+	for {
+		busy, ok := m.pending[profile]
+		if !ok {
+			break
+		}
+		m.mu.Unlock()
+		select {
+		case <-busy:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		m.mu.Lock()
+	}
+	done := make(chan struct{})
+	m.pending[profile] = done
+	current := m.browsers[profile]
+	if current != nil {
+		// Held while it is probed, so a release cannot stop it in the meantime
+		current.refs++
+	}
+	m.mu.Unlock()
+
+	b, err := m.acquire(ctx, profile, opts, current)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.pending, profile)
+	close(done)
+	if err != nil {
+		return nil, err
+	}
+	m.browsers[profile] = b
+	return b, nil
+}
+
+// acquire is the slow part of Acquire, run without the manager's lock. current is the
+// browser the manager runs for the profile, if any, with a reference taken on it.
+func (m *Manager) acquire(ctx context.Context, profile string, opts Options, current *Browser) (*Browser, error) {
+	headless := m.cfg.Headless && !opts.Visible
+	if current != nil {
+		err := Healthy(ctx, current.DebugURL())
+		if err == nil && !(current.owned && current.Headless != headless) {
+			return current, nil
+		}
+
+		m.mu.Lock()
+		current.refs--
+		if err == nil {
+			m.mu.Unlock()
+			// A profile directory can only be open in one Chromium at a time
+			return nil, fmt.Errorf("browser: profile %s is in use by a %s browser", profile, mode(current.Headless))
+		}
+		if m.browsers[profile] == current {
+			delete(m.browsers, profile)
+		}
+		m.mu.Unlock()
+		log.Printf("⚠️ Browser for profile %s stopped responding, starting a new one", profile)
+		current.stopProcess()
+	}
+
+	dir := m.ProfileDir(profile)
+	if port, ok := activePort(dir); ok {
+		b := &Browser{Profile: profile, Port: port, refs: 1, m: m}
+		if err := Healthy(ctx, b.DebugURL()); err == nil {
+			log.Printf("🔗 Attached to the running browser for profile %s on port %d", profile, port)
+			return b, nil
+		}
+	}
+
+	return m.start(ctx, profile, dir, headless, opts)
+}
+
+func (m *Manager) start(ctx context.Context, profile, dir string, headless bool, opts Options) (*Browser, error) {
+	path, err := m.execPath()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("browser: profile %s: %v", profile, err)
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("browser: profile %s: %v", profile, err)
+	}
+	port, err := FreePort()
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{
+		"--remote-debugging-port=" + strconv.Itoa(port),
+		"--remote-debugging-address=127.0.0.1",
+		"--user-data-dir=" + abs,
+		"--no-first-run",
+		"--no-default-browser-check",
+	}
+	if headless {
+		args = append(args, "--headless=new")
+	}
+	args = append(args, m.cfg.Flags...)
+	args = append(args, opts.Flags...)
+
+	cmd := exec.Command(path, args...)
+	ownProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("browser: start %s: %v", path, err)
+	}
+	b := &Browser{Profile: profile, Port: port, Headless: headless, owned: true, cmd: cmd, exited: make(chan struct{}), refs: 1, m: m}
+	go func() {
+		cmd.Wait()
+		close(b.exited)
+	}()
+
+	waitCtx, cancel := context.WithTimeout(ctx, m.cfg.StartTimeout)
+	defer cancel()
+	if err := waitHealthy(waitCtx, b); err != nil {
+		b.stopProcess()
+		return nil, fmt.Errorf("browser: profile %s did not come up on port %d: %v", profile, port, err)
+	}
+	log.Printf("🖥️ Started %s browser for profile %s (pid %d, port %d)", mode(headless), profile, cmd.Process.Pid, port)
+	return b, nil
+}
+
+func mode(headless bool) string {
+	if headless {
+		return "headless"
+	}
+	return "visible"
+}
+
+func (m *Manager) execPath() (string, error) {
+	return FindChromium(m.cfg.ExecPath)
+}
+
+// FindChromium returns execPath if set, or else the first Chromium found in PATH
+func FindChromium(execPath string) (string, error) {
+	if execPath != "" {
+		return execPath, nil
+	}
+	for _, name := range []string{"chromium", "chromium-browser", "google-chrome", "/snap/bin/chromium"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("browser: no Chromium found; set CHROMIUM_PATH")
+}
+
+func (m *Manager) release(b *Browser) {
+	m.mu.Lock()
+	if b.refs > 0 {
+		b.refs--
+	}
+	if b.refs > 0 {
+		m.mu.Unlock()
+		return
+	}
+	if m.browsers[b.Profile] == b {
+		delete(m.browsers, b.Profile)
+	}
+	m.mu.Unlock()
+	b.stopProcess()
+}
+
+// stopProcess terminates an owned browser and its child processes, escalating to a
+// kill when it does not exit in time. Attached browsers are left running.
+func (b *Browser) stopProcess() {
+	if !b.owned || b.cmd == nil || b.cmd.Process == nil {
+		return
+	}
+	select {
+	case <-b.exited:
+		return
+	default:
+	}
+
+	terminate(b.cmd)
+	select {
+	case <-b.exited:
+	case <-time.After(5 * time.Second):
+		kill(b.cmd)
+		<-b.exited
+	}
+	log.Printf("🛑 Stopped browser for profile %s (pid %d)", b.Profile, b.cmd.Process.Pid)
+}
+
+// Shutdown stops every browser this manager started, whether or not it was released
+func (m *Manager) Shutdown() {
+	m.mu.Lock()
+	browsers := m.browsers
+	m.browsers = make(map[string]*Browser)
+	m.mu.Unlock()
+	for _, b := range browsers {
+		b.stopProcess()
+	}
+}
+
+// Healthy checks that a remote-debugging endpoint answers /json/version
+func Healthy(ctx context.Context, debugURL string) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, debugURL+"/json/version", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("/json/version returned %s", resp.Status)
+	}
+	var version struct {
+		WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&version); err != nil || version.WebSocketDebuggerURL == "" {
+		return fmt.Errorf("/json/version has no webSocketDebuggerUrl")
+	}
+	return nil
+}
+
+func waitHealthy(ctx context.Context, b *Browser) error {
+	for {
+		err := Healthy(ctx, b.DebugURL())
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-b.exited:
+			return fmt.Errorf("process exited")
+		case <-ctx.Done():
+			return err
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+// FreePort asks the kernel for an unused TCP port on the loopback interface
+func FreePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("browser: no free port: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// activePort reads the port Chromium records in DevToolsActivePort while it runs
+// with remote debugging on a user data directory
+func activePort(dir string) (int, bool) {
+	raw, err := os.ReadFile(filepath.Join(dir, "DevToolsActivePort"))
+	if err != nil {
+		return 0, false
+	}
+	first, _, _ := strings.Cut(string(raw), "\n")
+	port, err := strconv.Atoi(strings.TrimSpace(first))
+	return port, err == nil && port > 0
+}
@@ -0,0 +1,231 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The test binary doubles as a fake Chromium: with FAKE_CHROMIUM set it serves
+// /json/version on the requested debug port like the real browser does, after
+// FAKE_CHROMIUM_DELAY if that is set
+func TestMain(m *testing.M) {
+	if os.Getenv("FAKE_CHROMIUM") == "1" {
+		fakeChromium(os.Args[1:])
+		return
+	}
+	os.Exit(m.Run())
+}
+
+func fakeChromium(args []string) {
+	var port, dir string
+	for _, arg := range args {
+		if v, ok := strings.CutPrefix(arg, "--remote-debugging-port="); ok {
+			port = v
+		}
+		if v, ok := strings.CutPrefix(arg, "--user-data-dir="); ok {
+			dir = v
+		}
+	}
+	if delay, err := time.ParseDuration(os.Getenv("FAKE_CHROMIUM_DELAY")); err == nil {
+		time.Sleep(delay)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:"+port)
+	if err != nil {
+		os.Exit(1)
+	}
+	os.WriteFile(filepath.Join(dir, "DevToolsActivePort"), []byte(port+"\n/devtools/browser/fake"), 0o600)
+	os.WriteFile(filepath.Join(dir, "args"), []byte(strings.Join(args, "\n")), 0o600)
+	http.Serve(l, versionHandler(port))
+}
+
+func versionHandler(port string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/json/version" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"Browser": "FakeChromium/1.0", "webSocketDebuggerUrl": "ws://127.0.0.1:%s/devtools/browser/fake"}`, port)
+	})
+}
+
+func fakeManager(t *testing.T, headless bool) *Manager {
+	t.Helper()
+	t.Setenv("FAKE_CHROMIUM", "1")
+	m := NewManager(Config{ExecPath: os.Args[0], ProfilesDir: t.TempDir(), StartTimeout: 10 * time.Second, Headless: headless})
+	t.Cleanup(m.Shutdown)
+	return m
+}
+
+func TestAcquireStartsOneBrowserPerProfile(t *testing.T) {
+	m := fakeManager(t, false)
+	ctx := context.Background()
+
+	first, err := m.Acquire(ctx, "linkedin", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !first.Owned() {
+		t.Error("started browser is not owned")
+	}
+	again, err := m.Acquire(ctx, "linkedin", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != first {
+		t.Error("second Acquire started another browser for the same profile")
+	}
+	other, err := m.Acquire(ctx, "xing", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Port == first.Port {
+		t.Errorf("profiles share debug port %d", other.Port)
+	}
+
+	// Only the last release stops the browser
+	first.Release()
+	if err := Healthy(ctx, first.DebugURL()); err != nil {
+		t.Fatalf("browser stopped while still in use: %v", err)
+	}
+	again.Release()
+	if err := Healthy(ctx, first.DebugURL()); err == nil {
+		t.Error("browser still running after its last release")
+	}
+	if err := Healthy(ctx, other.DebugURL()); err != nil {
+		t.Errorf("releasing one profile stopped another: %v", err)
+	}
+}
+
+func TestAcquireDoesNotHoldUpOtherProfilesWhileStarting(t *testing.T) {
+	m := fakeManager(t, false)
+	ctx := context.Background()
+
+	running, err := m.Acquire(ctx, "linkedin", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer running.Release()
+
+	// Browsers started from here on take a while to come up
+	t.Setenv("FAKE_CHROMIUM_DELAY", "1500ms")
+	type acquired struct {
+		b   *Browser
+		err error
+	}
+	slow := make(chan acquired, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			b, err := m.Acquire(ctx, "xing", Options{})
+			slow <- acquired{b, err}
+		}()
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	begin := time.Now()
+	again, err := m.Acquire(ctx, "linkedin", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	again.Release()
+	if waited := time.Since(begin); waited > time.Second {
+		t.Errorf("Acquire of a running profile waited %v for another profile to start", waited)
+	}
+
+	// The two Acquires of the starting profile share one browser
+	first, second := <-slow, <-slow
+	if first.err != nil || second.err != nil {
+		t.Fatalf("slow Acquire: %v, %v", first.err, second.err)
+	}
+	if first.b != second.b {
+		t.Error("concurrent Acquires started two browsers for one profile")
+	}
+	first.b.Release()
+	second.b.Release()
+	if err := Healthy(ctx, first.b.DebugURL()); err == nil {
+		t.Error("browser still running after its last release")
+	}
+}
+
+func TestAcquireAttachesToARunningBrowser(t *testing.T) {
+	m := NewManager(Config{ExecPath: "/nonexistent/chromium", ProfilesDir: t.TempDir()})
+
+	srv := httptest.NewServer(nil)
+	port := srv.Listener.Addr().(*net.TCPAddr).Port
+	srv.Config.Handler = versionHandler(fmt.Sprint(port))
+	defer srv.Close()
+
+	dir := m.ProfileDir("xing")
+	os.MkdirAll(dir, 0o700)
+	os.WriteFile(filepath.Join(dir, "DevToolsActivePort"), []byte(fmt.Sprintf("%d\n/devtools/browser/x", port)), 0o600)
+
+	b, err := m.Acquire(context.Background(), "xing", Options{})
+	if err != nil {
+		t.Fatalf("attach: %v", err)
+	}
+	if b.Owned() || b.Port != port {
+		t.Errorf("got owned=%v port=%d, want an attached browser on %d", b.Owned(), b.Port, port)
+	}
+	b.Release()
+	m.Shutdown()
+	if err := Healthy(context.Background(), b.DebugURL()); err != nil {
+		t.Errorf("attached browser was stopped: %v", err)
+	}
+}
+
+func TestShutdownStopsOwnedBrowsers(t *testing.T) {
+	m := fakeManager(t, false)
+	b, err := m.Acquire(context.Background(), "linkedin", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Shutdown()
+	if err := Healthy(context.Background(), b.DebugURL()); err == nil {
+		t.Error("browser still running after Shutdown")
+	}
+}
+
+func TestAcquireReportsAMissingBrowser(t *testing.T) {
+	m := NewManager(Config{ExecPath: "/nonexistent/chromium", ProfilesDir: t.TempDir()})
+	if _, err := m.Acquire(context.Background(), "linkedin", Options{}); err == nil {
+		t.Error("Acquire succeeded without a browser binary")
+	}
+}
+
+func TestHeadlessUnlessVisible(t *testing.T) {
+	m := fakeManager(t, true)
+	ctx := context.Background()
+	started := func(profile string) string {
+		raw, _ := os.ReadFile(filepath.Join(m.ProfileDir(profile), "args"))
+		return string(raw)
+	}
+
+	b, err := m.Acquire(ctx, "linkedin", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !b.Headless || !strings.Contains(started("linkedin"), "--headless=new") {
+		t.Errorf("scrape browser is not headless: %q", started("linkedin"))
+	}
+	// The profile is locked by the headless browser, so a login must wait for it
+	if _, err := m.Acquire(ctx, "linkedin", Options{Visible: true}); err == nil {
+		t.Error("visible Acquire shared the headless browser")
+	}
+	b.Release()
+
+	login, err := m.Acquire(ctx, "linkedin", Options{Visible: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer login.Release()
+	if login.Headless || strings.Contains(started("linkedin"), "--headless") {
+		t.Errorf("login browser is headless: %q", started("linkedin"))
+	}
+}
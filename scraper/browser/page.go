@@ -0,0 +1,103 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+)
+
+// PageState is what a scraper is looking at after a navigation
+type PageState string
+
+const (
+	PageNormal      PageState = "normal"
+	PageAuthwall    PageState = "authwall"
+	PageLoggedOut   PageState = "logged_out"
+	PageCheckpoint  PageState = "checkpoint"
+	PageRateLimited PageState = "rate_limited"
+	PageExpired     PageState = "expired"
+)
+
+// Blocking reports whether the state stops the whole run rather than one job: every
+// following page would show the same wall, so it says nothing about the job itself
+func (s PageState) Blocking() bool {
+	switch s {
+	case PageAuthwall, PageLoggedOut, PageCheckpoint, PageRateLimited:
+		return true
+	}
+	return false
+}
+
+// ClassifyPage tells the state of a page from its URL, title and visible text
+func (s Site) ClassifyPage(pageURL, title, text string) PageState {
+	at := ""
+	if u, err := url.Parse(pageURL); err == nil {
+		at = strings.ToLower(strings.TrimPrefix(u.Host, "www.") + u.Path)
+	}
+	content := strings.ToLower(title + "\n" + text)
+
+	switch {
+	case containsAny(at, s.Checkpoint), containsAny(content, s.CheckpointText):
+		return PageCheckpoint
+	case containsAny(at, s.Authwall):
+		return PageAuthwall
+	case containsAny(at, s.LoggedOut):
+		return PageLoggedOut
+	case containsAny(content, s.RateLimitedText):
+		return PageRateLimited
+	case containsAny(content, s.ExpiredText):
+		return PageExpired
+	}
+	return PageNormal
+}
+
+// PageError is returned by ExpectNormalPage for a page that is not a normal job page
+type PageError struct {
+	Site  string
+	State PageState
+	URL   string
+}
+
+func (e *PageError) Error() string {
+	return fmt.Sprintf("%s showed a %s page at %s", e.Site, e.State, e.URL)
+}
+
+// AsPageError unwraps a *PageError from err
+func AsPageError(err error) (*PageError, bool) {
+	var pe *PageError
+	ok := errors.As(err, &pe)
+	return pe, ok
+}
+
+// ExpectNormalPage is an action that fails with a *PageError unless the current page
+// is a normal one. Put it after a navigation, before waiting for page content, so a
+// wall is reported as such instead of as a missing element.
+func ExpectNormalPage(site Site) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		state, at, err := inspect(ctx, site)
+		if err != nil {
+			return err
+		}
+		if state != PageNormal {
+			return &PageError{Site: site.Name, State: state, URL: at}
+		}
+		return nil
+	})
+}
+
+func inspect(ctx context.Context, site Site) (PageState, string, error) {
+	var at, title, text string
+	err := chromedp.Tasks{
+		chromedp.Location(&at),
+		chromedp.Title(&title),
+		chromedp.Evaluate(`document.body ? document.body.innerText.slice(0, 5000) : ''`, &text),
+	}.Do(ctx)
+	if err != nil {
+		return "", "", fmt.Errorf("%s page inspection: %v", site.Name, err)
+	}
+	return site.ClassifyPage(at, title, text), at, nil
+}
@@ -0,0 +1,39 @@
+package browser
+
+import "testing"
+
+func TestClassifyPage(t *testing.T) {
+	cases := []struct {
+		site             Site
+		url, title, text string
+		want             PageState
+	}{
+		{LinkedIn, "https://www.linkedin.com/jobs/view/4012345678/", "Go Developer | Acme", "About the job\nBuild services", PageNormal},
+		{LinkedIn, "https://www.linkedin.com/authwall?trk=qf&original_referer=", "Sign Up | LinkedIn", "Join now", PageAuthwall},
+		{LinkedIn, "https://www.linkedin.com/login?session_redirect=x", "LinkedIn Login", "Sign in", PageLoggedOut},
+		{LinkedIn, "https://www.linkedin.com/checkpoint/challenge/AgH", "Security Verification", "", PageCheckpoint},
+		{LinkedIn, "https://www.linkedin.com/jobs/view/1/", "", "Let's do a quick security check", PageCheckpoint},
+		{LinkedIn, "https://www.linkedin.com/jobs/view/1/", "www.linkedin.com", "This page isn’t working\nHTTP ERROR 429", PageRateLimited},
+		{LinkedIn, "https://www.linkedin.com/jobs/view/1/", "Go Developer", "No longer accepting applications", PageExpired},
+		{Xing, "https://www.xing.com/jobs/berlin-go-developer-123", "Go Developer", "Jetzt bewerben", PageNormal},
+		{Xing, "https://login.xing.com/?dest_url=x", "Login", "", PageLoggedOut},
+		{Xing, "https://www.xing.com/jobs/berlin-go-developer-123", "", "Diese Stellenanzeige ist nicht mehr verfügbar", PageExpired},
+		{Xing, "https://www.xing.com/jobs/search?keywords=go", "", "Zu viele Anfragen", PageRateLimited},
+	}
+	for _, c := range cases {
+		if got := c.site.ClassifyPage(c.url, c.title, c.text); got != c.want {
+			t.Errorf("%s %s %q: got %s, want %s", c.site.Name, c.url, c.text, got, c.want)
+		}
+	}
+}
+
+func TestOnlyWallsBlockTheRun(t *testing.T) {
+	for state, want := range map[PageState]bool{
+		PageNormal: false, PageExpired: false,
+		PageAuthwall: true, PageLoggedOut: true, PageCheckpoint: true, PageRateLimited: true,
+	} {
+		if got := state.Blocking(); got != want {
+			t.Errorf("%s.Blocking() = %v, want %v", state, got, want)
+		}
+	}
+}
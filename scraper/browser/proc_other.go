@@ -0,0 +1,15 @@
+//go:build !unix
+
+package browser
+
+import "os/exec"
+
+func ownProcessGroup(cmd *exec.Cmd) {}
+
+func terminate(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
+
+func kill(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
@@ -0,0 +1,224 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/storage"
+	"github.com/chromedp/chromedp"
+)
+
+// Site describes how to tell whether a profile is logged in to a job site and what
+// state a page is in. URL patterns are matched against the host and path of the page;
+// text markers against its title and visible text, case-insensitively.
+type Site struct {
+	Name       string
+	Profile    string
+	LoginURL   string   // where a visible login starts
+	CheckURL   string   // a page that needs a session; sites redirect away from it when logged out
+	LoggedOut  []string // e.g. linkedin.com/login
+	Authwall   []string // sign-in walls in front of public pages
+	Checkpoint []string // security challenges that need a human
+
+	CheckpointText  []string // CAPTCHAs and security checks rendered in place
+	RateLimitedText []string
+	ExpiredText     []string // removed or closed job ads
+}
+
+var (
+	LinkedIn = Site{
+		Name:       "LinkedIn",
+		Profile:    "linkedin",
+		LoginURL:   "https://www.linkedin.com/login",
+		CheckURL:   "https://www.linkedin.com/feed/",
+		LoggedOut:  []string{"linkedin.com/login", "linkedin.com/uas/login", "linkedin.com/signup"},
+		Authwall:   []string{"linkedin.com/authwall"},
+		Checkpoint: []string{"linkedin.com/checkpoint/"},
+
+		CheckpointText:  []string{"let's do a quick security check", "security verification", "captcha"},
+		RateLimitedText: []string{"http error 429", "http error 999", "too many requests"},
+		ExpiredText:     []string{"no longer accepting applications", "this job is no longer available", "page not found", "this page doesn’t exist"},
+	}
+	Xing = Site{
+		Name:       "Xing",
+		Profile:    "xing",
+		LoginURL:   "https://login.xing.com/",
+		CheckURL:   "https://www.xing.com/notifications",
+		LoggedOut:  []string{"login.xing.com", "xing.com/login", "xing.com/start/signup"},
+		Checkpoint: []string{"xing.com/challenge", "login.xing.com/verify"},
+
+		CheckpointText:  []string{"captcha", "sicherheitsüberprüfung", "security check"},
+		RateLimitedText: []string{"http error 429", "too many requests", "zu viele anfragen"},
+		ExpiredText:     []string{"nicht mehr verfügbar", "no longer available", "seite nicht gefunden", "page not found"},
+	}
+)
+
+// Sites are the known sites by profile name
+var Sites = map[string]Site{LinkedIn.Profile: LinkedIn, Xing.Profile: Xing}
+
+// SessionState is the outcome of a session check
+type SessionState string
+
+const (
+	SessionValid      SessionState = "valid"
+	SessionLoggedOut  SessionState = "logged_out"
+	SessionCheckpoint SessionState = "checkpoint"
+)
+
+// Classify tells the session state from the URL a page ended up on
+func (s Site) Classify(pageURL string) SessionState {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return SessionLoggedOut
+	}
+	at := strings.ToLower(strings.TrimPrefix(u.Host, "www.") + u.Path)
+	switch {
+	case containsAny(at, s.Checkpoint):
+		return SessionCheckpoint
+	case containsAny(at, s.LoggedOut), containsAny(at, s.Authwall):
+		return SessionLoggedOut
+	}
+	return SessionValid
+}
+
+func containsAny(s string, patterns []string) bool {
+	for _, p := range patterns {
+		if strings.Contains(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// SessionError is returned when a run would start without a usable session
+type SessionError struct {
+	Site  string
+	State SessionState
+	URL   string
+}
+
+func (e *SessionError) Error() string {
+	return fmt.Sprintf("%s session is %s (landed on %s); log in again with go run ./cmd/login -site %s",
+		e.Site, e.State, e.URL, strings.ToLower(e.Site))
+}
+
+// IsSessionError reports whether err is a failed session check
+func IsSessionError(err error) bool {
+	var se *SessionError
+	return errors.As(err, &se)
+}
+
+// CheckSession opens the site's check page in the chromedp context and classifies
+// where it lands
+func CheckSession(ctx context.Context, site Site) (SessionState, string, error) {
+	var landed string
+	if err := chromedp.Run(ctx, chromedp.Navigate(site.CheckURL), chromedp.Location(&landed)); err != nil {
+		return "", "", fmt.Errorf("%s session check: %v", site.Name, err)
+	}
+	return site.Classify(landed), landed, nil
+}
+
+// CookiesPath is where a profile's exported cookies are kept, next to its user data
+// directory so they can be copied to a server that has no profile yet
+func (m *Manager) CookiesPath(profile string) string {
+	return filepath.Join(m.cfg.ProfilesDir, profile+".cookies.json")
+}
+
+// EnsureSession checks that the browser behind ctx is logged in to the site before
+// a run starts. A profile directory without a session falls back to the exported
+// cookies, if there are any; a *SessionError means a visible login is needed.
+func (m *Manager) EnsureSession(ctx context.Context, site Site) error {
+	state, landed, err := CheckSession(ctx, site)
+	if err != nil {
+		return err
+	}
+	if state == SessionLoggedOut {
+		imported, err := LoadCookies(ctx, m.CookiesPath(site.Profile))
+		if err != nil {
+			return err
+		}
+		if imported > 0 {
+			log.Printf("🍪 Imported %d exported cookies into profile %s", imported, site.Profile)
+			if state, landed, err = CheckSession(ctx, site); err != nil {
+				return err
+			}
+		}
+	}
+	if state != SessionValid {
+		return &SessionError{Site: site.Name, State: state, URL: landed}
+	}
+	log.Printf("🔐 %s session is valid", site.Name)
+	return nil
+}
+
+// SaveCookies exports every cookie of the browser behind ctx to path
+func SaveCookies(ctx context.Context, path string) (int, error) {
+	var cookies []*network.Cookie
+	err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		cookies, err = storage.GetCookies().Do(ctx)
+		return err
+	}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read cookies: %v", err)
+	}
+
+	params := make([]*network.CookieParam, 0, len(cookies))
+	for _, c := range cookies {
+		p := &network.CookieParam{
+			Name: c.Name, Value: c.Value, Domain: c.Domain, Path: c.Path,
+			Secure: c.Secure, HTTPOnly: c.HTTPOnly, SameSite: c.SameSite, Priority: c.Priority,
+			SourceScheme: c.SourceScheme, SourcePort: c.SourcePort, PartitionKey: c.PartitionKey,
+		}
+		if !c.Session {
+			expires := cdp.TimeSinceEpoch(time.Unix(0, int64(c.Expires*float64(time.Second))))
+			p.Expires = &expires
+		}
+		params = append(params, p)
+	}
+
+	raw, err := json.MarshalIndent(params, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return 0, err
+	}
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		return 0, fmt.Errorf("failed to write cookies: %v", err)
+	}
+	return len(params), nil
+}
+
+// LoadCookies imports cookies exported by SaveCookies into the browser behind ctx;
+// a missing file imports nothing
+func LoadCookies(ctx context.Context, path string) (int, error) {
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read cookies: %v", err)
+	}
+	var params []*network.CookieParam
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return 0, fmt.Errorf("invalid cookies file %s: %v", path, err)
+	}
+	if len(params) == 0 {
+		return 0, nil
+	}
+	if err := chromedp.Run(ctx, storage.SetCookies(params)); err != nil {
+		return 0, fmt.Errorf("failed to import cookies: %v", err)
+	}
+	return len(params), nil
+}
@@ -0,0 +1,53 @@
+package browser
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		site Site
+		url  string
+		want SessionState
+	}{
+		{LinkedIn, "https://www.linkedin.com/feed/", SessionValid},
+		{LinkedIn, "https://www.linkedin.com/jobs/view/4012345678/", SessionValid},
+		{LinkedIn, "https://www.linkedin.com/login?session_redirect=%2Ffeed%2F", SessionLoggedOut},
+		{LinkedIn, "https://www.linkedin.com/authwall?trk=gf", SessionLoggedOut},
+		{LinkedIn, "https://www.linkedin.com/uas/login?fromSignIn=true", SessionLoggedOut},
+		{LinkedIn, "https://www.linkedin.com/checkpoint/challenge/AgH", SessionCheckpoint},
+		{Xing, "https://www.xing.com/notifications", SessionValid},
+		{Xing, "https://login.xing.com/?dest_url=https%3A%2F%2Fwww.xing.com%2Fnotifications", SessionLoggedOut},
+		{Xing, "https://login.xing.com/verify/2fa", SessionCheckpoint},
+		{Xing, "://broken", SessionLoggedOut},
+	}
+	for _, c := range cases {
+		if got := c.site.Classify(c.url); got != c.want {
+			t.Errorf("%s %s: got %s, want %s", c.site.Name, c.url, got, c.want)
+		}
+	}
+}
+
+func TestSessionErrorNamesTheLogin(t *testing.T) {
+	var err error = &SessionError{Site: "Xing", State: SessionCheckpoint, URL: "https://login.xing.com/verify"}
+	if !IsSessionError(err) {
+		t.Fatal("IsSessionError = false")
+	}
+	if got, want := err.Error(), "Xing session is checkpoint (landed on https://login.xing.com/verify); log in again with go run ./cmd/login -site xing"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestLoadCookiesWithoutExport(t *testing.T) {
+	m := NewManager(Config{ProfilesDir: t.TempDir()})
+	if got := m.CookiesPath("linkedin"); filepath.Base(got) != "linkedin.cookies.json" {
+		t.Errorf("CookiesPath = %s", got)
+	}
+	// No browser is needed when there is nothing to import
+	n, err := LoadCookies(context.Background(), m.CookiesPath("linkedin"))
+	if n != 0 || err != nil {
+		t.Errorf("got %d, %v; want nothing imported", n, err)
+	}
+}
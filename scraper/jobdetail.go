@@ -0,0 +1,237 @@
+package scraper
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"job_scraper/scraper/outbox"
+	"job_scraper/scraper/skills"
+)
+
+// sourceTables names the per-source tables behind the all_jobs view
+type sourceTables struct {
+	jobs, links, descriptions, failures string
+}
+
+var tablesBySource = map[string]sourceTables{
+	"LinkedIn": {"linkedin_jobs", "linkedin_job_application_links", "linkedin_job_description", "linkedin_failed_jobs"},
+	"Xing":     {"xing_jobs", "xing_job_application_links", "xing_job_description", "xing_failed_jobs"},
+}
+
+// JobSummary is the structured description produced by the summarizer
+type JobSummary struct {
+	Description  string   `json:"description"`
+	JobType      string   `json:"job_type"`
+	Skills       []string `json:"skills"`
+	Status       string   `json:"status"`
+	Attempts     int      `json:"attempts"`
+	Error        string   `json:"error"`
+	SummarizedAt string   `json:"summarized_at"`
+}
+
+// ApplicationLink is an external apply page captured for a job
+type ApplicationLink struct {
+	ID   int    `json:"id"`
+	Link string `json:"link"`
+}
+
+// JobFailure is a scrape failure of one job link
+type JobFailure struct {
+	JobLink  string `json:"job_link"`
+	Reason   string `json:"reason"`
+	Count    int    `json:"count"`
+	FailedAt string `json:"failed_at"`
+}
+
+// UploadStatus tells whether the job was queued for upload and how each sink it was
+// queued for got on with it. A queued job has not necessarily been delivered.
+type UploadStatus struct {
+	Queued bool                 `json:"queued"`
+	Sinks  []outbox.JobDelivery `json:"sinks"`
+}
+
+// JobDetail is everything known about one posting
+type JobDetail struct {
+	Job              JobResponse       `json:"job"`
+	ScrapedAt        string            `json:"scraped_at"`
+	ProcessedAt      string            `json:"processed_at"`
+	Summary          *JobSummary       `json:"summary"`
+	RawDescription   string            `json:"raw_description"`
+	ApplicationLinks []ApplicationLink `json:"application_links"`
+	Failures         []JobFailure      `json:"failures"`
+	Duplicates       []JobResponse     `json:"duplicates"`
+	Upload           UploadStatus      `json:"upload"`
+}
+
+// GetJobHandler returns the listing, summary, raw description, links, failures, dedup
+// siblings and upload status of one job. ?source= picks the source when the id exists in both.
+func GetJobHandler(w http.ResponseWriter, r *http.Request, db *sql.DB) {
+	id := r.PathValue("id")
+	query := `
+		SELECT j.source, j.id, COALESCE(j.jobid, ''), COALESCE(j.title, ''), COALESCE(j.company, ''),
+			COALESCE(j.location, ''), COALESCE(j.posted_date, ''), COALESCE(j.link, ''),
+			COALESCE(j.processed, 0), COALESCE(j.sent, 0),
+			COALESCE(j.scraped_at, ''), COALESCE(j.processed_at, '')
+		FROM all_jobs j
+		WHERE j.id = ?`
+	args := []interface{}{id}
+	if source := r.URL.Query().Get("source"); source != "" {
+		query += ` AND lower(j.source) = lower(?)`
+		args = append(args, source)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching job: %v", err), http.StatusInternalServerError)
+		return
+	}
+	var matches []JobDetail
+	for rows.Next() {
+		var d JobDetail
+		job := &d.Job
+		if err := rows.Scan(&job.Source, &job.ID, &job.JobID, &job.Title, &job.Company, &job.Location,
+			&job.PostedDate, &job.Link, &job.Processed, &job.Sent, &d.ScrapedAt, &d.ProcessedAt); err != nil {
+			rows.Close()
+			http.Error(w, fmt.Sprintf("Error scanning job row: %v", err), http.StatusInternalServerError)
+			return
+		}
+		matches = append(matches, d)
+	}
+	rows.Close()
+
+	switch len(matches) {
+	case 0:
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	case 1:
+	default:
+		http.Error(w, "Job id exists in several sources, pass ?source=", http.StatusConflict)
+		return
+	}
+
+	detail := matches[0]
+	if err := loadJobDetail(db, &detail); err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching job details: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(detail)
+}
+
+// loadJobDetail fills everything besides the listing row
+func loadJobDetail(db *sql.DB, d *JobDetail) error {
+	tables := tablesBySource[d.Job.Source]
+	d.Upload.Queued = d.Job.Sent
+	deliveries, err := outbox.JobDeliveries(db, d.Job.Source, d.Job.ID)
+	if err != nil {
+		return err
+	}
+	d.Upload.Sinks = deliveries
+
+	// Summary and raw description
+	var (
+		summary  JobSummary
+		rawSkill string
+	)
+	err = db.QueryRow(fmt.Sprintf(`
+		SELECT COALESCE(job_description, ''), COALESCE(job_type, ''), COALESCE(skills, ''),
+			COALESCE(raw_description, ''), summary_status, summary_attempts,
+			COALESCE(summary_error, ''), COALESCE(summarized_at, '')
+		FROM %s WHERE job_id = ?`, tables.descriptions), d.Job.ID).
+		Scan(&summary.Description, &summary.JobType, &rawSkill, &d.RawDescription,
+			&summary.Status, &summary.Attempts, &summary.Error, &summary.SummarizedAt)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return fmt.Errorf("description: %v", err)
+	default:
+		names, err := skills.NamesByJob(db, d.Job.Source, []string{d.Job.ID})
+		if err != nil {
+			return err
+		}
+		summary.Skills = names[d.Job.ID]
+		if summary.Skills == nil {
+			summary.Skills = skills.SplitRaw([]string{rawSkill})
+		}
+		if summary.Skills == nil {
+			summary.Skills = []string{}
+		}
+		d.Summary = &summary
+	}
+
+	// Captured application links
+	d.ApplicationLinks = []ApplicationLink{}
+	rows, err := db.Query(fmt.Sprintf(`SELECT id, job_link FROM %s WHERE job_id = ? ORDER BY id`, tables.links), d.Job.ID)
+	if err != nil {
+		return fmt.Errorf("application links: %v", err)
+	}
+	for rows.Next() {
+		var l ApplicationLink
+		if err := rows.Scan(&l.ID, &l.Link); err != nil {
+			rows.Close()
+			return fmt.Errorf("application links: %v", err)
+		}
+		d.ApplicationLinks = append(d.ApplicationLinks, l)
+	}
+	rows.Close()
+
+	// Failure history
+	d.Failures = []JobFailure{}
+	rows, err = db.Query(fmt.Sprintf(`
+		SELECT COALESCE(job_link, ''), COALESCE(reason, ''), failure_count, COALESCE(failed_at, '')
+		FROM %s WHERE job_id = ? ORDER BY failed_at DESC, id DESC`, tables.failures), d.Job.ID)
+	if err != nil {
+		return fmt.Errorf("failures: %v", err)
+	}
+	for rows.Next() {
+		var f JobFailure
+		if err := rows.Scan(&f.JobLink, &f.Reason, &f.Count, &f.FailedAt); err != nil {
+			rows.Close()
+			return fmt.Errorf("failures: %v", err)
+		}
+		d.Failures = append(d.Failures, f)
+	}
+	rows.Close()
+
+	// Dedup siblings: the same posting stored again under a link that only differs in
+	// its query, or under the same job id. Listing titles are the search keyword, not
+	// the posting's title, so title and company cannot tell postings apart.
+	d.Duplicates = []JobResponse{}
+	rows, err = db.Query(`
+		SELECT j.source, j.id, COALESCE(j.jobid, ''), COALESCE(j.title, ''), COALESCE(j.company, ''),
+			COALESCE(j.location, ''), COALESCE(j.posted_date, ''), COALESCE(j.link, ''),
+			COALESCE(j.processed, 0), COALESCE(j.sent, 0)
+		FROM all_jobs j
+		WHERE NOT (j.source = ? AND j.id = ?)
+			AND ((? != '' AND rtrim(substr(j.link, 1, instr(j.link || '?', '?') - 1), '/') = ?)
+				OR (? != '' AND j.source = ? AND j.jobid = ?))
+		ORDER BY j.source, j.id`,
+		d.Job.Source, d.Job.ID,
+		canonicalLink(d.Job.Link), canonicalLink(d.Job.Link),
+		d.Job.JobID, d.Job.Source, d.Job.JobID)
+	if err != nil {
+		return fmt.Errorf("duplicates: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var job JobResponse
+		if err := rows.Scan(&job.Source, &job.ID, &job.JobID, &job.Title, &job.Company, &job.Location,
+			&job.PostedDate, &job.Link, &job.Processed, &job.Sent); err != nil {
+			return fmt.Errorf("duplicates: %v", err)
+		}
+		d.Duplicates = append(d.Duplicates, job)
+	}
+	return rows.Err()
+}
+
+// canonicalLink drops the query and trailing slash that tracking parameters vary
+func canonicalLink(link string) string {
+	if i := strings.IndexByte(link, '?'); i >= 0 {
+		link = link[:i]
+	}
+	return strings.TrimRight(link, "/")
+}
@@ -0,0 +1,64 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"job_scraper/config"
+)
+
+func TestJobDetailDuplicates(t *testing.T) {
+	db, err := config.InitializeDatabaseAt(filepath.Join(t.TempDir(), "detail.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// Every row comes from the search "Backend Engineer", which is stored as the title
+	mustExec(t, db, `INSERT INTO linkedin_jobs (id, jobid, title, company, link) VALUES
+		('l1', '101', 'Backend Engineer', 'ACME', 'https://www.linkedin.com/jobs/view/101'),
+		('l2', '102', 'Backend Engineer', 'ACME', 'https://www.linkedin.com/jobs/view/102'),
+		('l3', '103', 'Backend Engineer', 'Unknown', 'https://www.linkedin.com/jobs/view/103'),
+		('l4', '104', 'Backend Engineer', 'Unknown', 'https://www.linkedin.com/jobs/view/104'),
+		('l5', '', 'Backend Engineer', 'ACME', 'https://www.linkedin.com/jobs/view/101/?refId=abc')`)
+	mustExec(t, db, `INSERT INTO xing_jobs (id, jobid, title, company, link) VALUES
+		('x1', '201', 'Backend Engineer', 'ACME', 'https://www.xing.com/jobs/berlin-backend-engineer-201')`)
+
+	for _, tc := range []struct {
+		source, id string
+		want       string
+	}{
+		// Same search and company, but different postings
+		{"LinkedIn", "l2", "[]"},
+		{"LinkedIn", "l3", "[]"},
+		{"Xing", "x1", "[]"},
+		// The same posting under a tracking link
+		{"LinkedIn", "l1", "[LinkedIn/l5]"},
+		{"LinkedIn", "l5", "[LinkedIn/l1]"},
+	} {
+		t.Run(tc.id, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/jobs/"+tc.id+"?source="+tc.source, nil)
+			req.SetPathValue("id", tc.id)
+			rec := httptest.NewRecorder()
+			GetJobHandler(rec, req, db)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("GET /jobs/%s = %d: %s", tc.id, rec.Code, rec.Body)
+			}
+			var detail JobDetail
+			if err := json.NewDecoder(rec.Body).Decode(&detail); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, job := range detail.Duplicates {
+				got = append(got, job.Source+"/"+job.ID)
+			}
+			if s := fmt.Sprint(got); s != tc.want {
+				t.Errorf("duplicates = %s, want %s", s, tc.want)
+			}
+		})
+	}
+}
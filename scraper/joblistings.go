@@ -9,6 +9,7 @@ import (
 
 	"job_scraper/scraper/Linkedin"
 	// "job_scraper/scraper/Xing"
+	"job_scraper/scraper/listquery"
 
 )
 
@@ -39,6 +40,7 @@ func JobListingsHandler(w http.ResponseWriter, r *http.Request, db *sql.DB) {
 
 // JobResponse struct for API response
 type JobResponse struct {
+	Source     string `json:"source"`
 	ID         string `json:"id"`
 	JobID      string `json:"jobId"`
 	Title      string `json:"title"`
@@ -50,54 +52,61 @@ type JobResponse struct {
 	Sent       bool   `json:"sent"`
 }
 
-// ViewJobsHandler fetches jobs from both LinkedIn and Xing databases
+// jobListSpec lists LinkedIn and Xing jobs together through the all_jobs view
+var jobListSpec = listquery.Spec{
+	From: "all_jobs j",
+	Sorts: map[string]string{
+		"posted_date": "COALESCE(j.posted_date, '')",
+		"scraped_at":  "COALESCE(j.scraped_at, '')",
+		"title":       "COALESCE(j.title, '')",
+		"company":     "COALESCE(j.company, '')",
+		"location":    "COALESCE(j.location, '')",
+	},
+	DefaultSort: "-posted_date",
+	TieBreaker:  "j.source || ':' || j.id",
+	Source:      "j.source",
+	Company:     "j.company",
+	Location:    "j.location",
+	Date:        "COALESCE(date(j.posted_date), date(j.scraped_at))",
+	Statuses: map[string]string{
+		"pending":   "COALESCE(j.processed, 0) = 0",
+		"processed": "j.processed = 1",
+		"sent":      "j.sent = 1",
+		"unsent":    "COALESCE(j.sent, 0) = 0",
+	},
+}
+
+// ViewJobsHandler lists jobs from both LinkedIn and Xing with the common list params
 func ViewJobsHandler(w http.ResponseWriter, r *http.Request, db *sql.DB) {
-	// Fetch LinkedIn jobs
-	linkedinRows, err := db.Query("SELECT id, jobid, title, company, location, posted_date, link, processed, sent FROM linkedin_jobs ORDER BY posted_date DESC")
+	params, err := listquery.Parse(r, jobListSpec)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error fetching LinkedIn jobs: %v", err), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	defer linkedinRows.Close()
-
-	var linkedinJobs []JobResponse
-	for linkedinRows.Next() {
-		var job JobResponse
-		var sent sql.NullBool
 
-		err := linkedinRows.Scan(
-			&job.ID,
-			&job.JobID,
-			&job.Title,
-			&job.Company,
-			&job.Location,
-			&job.PostedDate,
-			&job.Link,
-			&job.Processed,
-			&sent,
-		)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Error scanning LinkedIn row: %v", err), http.StatusInternalServerError)
-			return
-		}
-		job.Sent = sent.Valid && sent.Bool
-		linkedinJobs = append(linkedinJobs, job)
+	total, err := params.Count(db, jobListSpec)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error counting jobs: %v", err), http.StatusInternalServerError)
+		return
 	}
 
-	// Fetch Xing jobs
-	xingRows, err := db.Query("SELECT id, jobid, title, company, location, posted_date, link, processed, sent FROM xing_jobs ORDER BY posted_date DESC")
+	query, args := params.Query(jobListSpec, `
+		j.source, j.id, COALESCE(j.jobid, ''), COALESCE(j.title, ''), COALESCE(j.company, ''),
+		COALESCE(j.location, ''), COALESCE(j.posted_date, ''), COALESCE(j.link, ''),
+		COALESCE(j.processed, 0), COALESCE(j.sent, 0)`)
+	rows, err := db.Query(query, args...)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error fetching Xing jobs: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Error fetching jobs: %v", err), http.StatusInternalServerError)
 		return
 	}
-	defer xingRows.Close()
+	defer rows.Close()
 
-	var xingJobs []JobResponse
-	for xingRows.Next() {
+	page := params.NewPage()
+	jobs := []JobResponse{}
+	for rows.Next() {
 		var job JobResponse
-		var sent sql.NullBool
-
-		err := xingRows.Scan(
+		ok, err := page.Scan(rows,
+			&job.Source,
 			&job.ID,
 			&job.JobID,
 			&job.Title,
@@ -106,33 +115,16 @@ func ViewJobsHandler(w http.ResponseWriter, r *http.Request, db *sql.DB) {
 			&job.PostedDate,
 			&job.Link,
 			&job.Processed,
-			&sent,
+			&job.Sent,
 		)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Error scanning Xing row: %v", err), http.StatusInternalServerError)
+			http.Error(w, fmt.Sprintf("Error scanning job row: %v", err), http.StatusInternalServerError)
 			return
 		}
-		job.Sent = sent.Valid && sent.Bool
-		xingJobs = append(xingJobs, job)
-	}
-
-	// Get job counts
-	var linkedinCount, xingCount int
-	err = db.QueryRow("SELECT COUNT(*) FROM linkedin_jobs").Scan(&linkedinCount)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Error fetching LinkedIn job count: %v", err), http.StatusInternalServerError)
-		return
-	}
-	err = db.QueryRow("SELECT COUNT(*) FROM xing_jobs").Scan(&xingCount)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Error fetching Xing job count: %v", err), http.StatusInternalServerError)
-		return
+		if ok {
+			jobs = append(jobs, job)
+		}
 	}
 
-	// Convert to JSON and return response
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"linkedin": map[string]interface{}{"count": linkedinCount, "jobs": linkedinJobs},
-		"xing":     map[string]interface{}{"count": xingCount, "jobs": xingJobs},
-	})
+	listquery.WriteJSON(w, page.Envelope(jobs, total))
 }
@@ -0,0 +1,307 @@
+// Package listquery is the shared pagination, filtering and sorting layer for the
+// list endpoints. Every endpoint describes its table once in a Spec; requests are
+// parsed into Params and rendered into a page query and a count query.
+//
+// Common query params: limit (default 50, max 500), cursor or offset, sort
+// (field, or -field for descending), source, company, location, status, since,
+// until (YYYY-MM-DD).
+package listquery
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 500
+)
+
+// Spec describes one list endpoint
+type Spec struct {
+	From        string            // FROM clause, including joins and aliases
+	Sorts       map[string]string // sort param -> SQL expression; keep expressions non-NULL
+	DefaultSort string            // e.g. "-posted_date"
+	TieBreaker  string            // unique SQL expression that makes the order total
+
+	Source   string            // SQL expression for the source filter
+	Company  string            // SQL expression for the company filter (substring match)
+	Location string            // SQL expression for the location filter (substring match)
+	Date     string            // SQL date expression for since/until
+	Statuses map[string]string // status value -> SQL condition
+}
+
+// Params is a parsed list request
+type Params struct {
+	Limit   int
+	Offset  int
+	cursor  *cursor
+	sortKey string
+	desc    bool
+	where   []string
+	args    []interface{}
+}
+
+type cursor struct {
+	Sort interface{} `json:"s"`
+	Tie  interface{} `json:"t"`
+}
+
+// Envelope is the common response shape of all list endpoints
+type Envelope struct {
+	Items      interface{} `json:"items"`
+	Total      int         `json:"total"`
+	Limit      int         `json:"limit"`
+	Offset     int         `json:"offset,omitempty"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// Parse validates the request's list params against the spec
+func Parse(r *http.Request, spec Spec) (Params, error) {
+	q := r.URL.Query()
+	p := Params{Limit: DefaultLimit}
+
+	if raw := q.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			return p, fmt.Errorf("limit must be a positive integer")
+		}
+		p.Limit = min(n, MaxLimit)
+	}
+	if raw := q.Get("offset"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return p, fmt.Errorf("offset must be a non-negative integer")
+		}
+		p.Offset = n
+	}
+	if raw := q.Get("cursor"); raw != "" {
+		if p.Offset > 0 {
+			return p, fmt.Errorf("use either cursor or offset, not both")
+		}
+		c, err := decodeCursor(raw)
+		if err != nil {
+			return p, fmt.Errorf("invalid cursor")
+		}
+		p.cursor = &c
+	}
+
+	sortParam := q.Get("sort")
+	if sortParam == "" {
+		sortParam = spec.DefaultSort
+	}
+	p.desc = strings.HasPrefix(sortParam, "-")
+	p.sortKey = strings.TrimPrefix(sortParam, "-")
+	if _, ok := spec.Sorts[p.sortKey]; !ok {
+		return p, fmt.Errorf("sort must be one of %s", strings.Join(sortKeys(spec), ", "))
+	}
+
+	filters := []struct {
+		param, expr string
+		like        bool
+	}{
+		{"source", spec.Source, false},
+		{"company", spec.Company, true},
+		{"location", spec.Location, true},
+	}
+	for _, f := range filters {
+		value := q.Get(f.param)
+		if value == "" {
+			continue
+		}
+		if f.expr == "" {
+			return p, fmt.Errorf("%s filter is not supported here", f.param)
+		}
+		if f.like {
+			p.where = append(p.where, f.expr+" LIKE ?")
+			p.args = append(p.args, "%"+value+"%")
+		} else {
+			p.where = append(p.where, f.expr+" = ?")
+			p.args = append(p.args, value)
+		}
+	}
+
+	if status := q.Get("status"); status != "" {
+		cond, ok := spec.Statuses[status]
+		if !ok {
+			if len(spec.Statuses) == 0 {
+				return p, fmt.Errorf("status filter is not supported here")
+			}
+			return p, fmt.Errorf("status must be one of %s", strings.Join(statusKeys(spec), ", "))
+		}
+		p.where = append(p.where, cond)
+	}
+
+	for _, bound := range []struct{ param, op string }{{"since", ">="}, {"until", "<="}} {
+		value := q.Get(bound.param)
+		if value == "" {
+			continue
+		}
+		if spec.Date == "" {
+			return p, fmt.Errorf("%s filter is not supported here", bound.param)
+		}
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return p, fmt.Errorf("%s must be a date in YYYY-MM-DD format", bound.param)
+		}
+		p.where = append(p.where, fmt.Sprintf("%s %s ?", spec.Date, bound.op))
+		p.args = append(p.args, value)
+	}
+
+	return p, nil
+}
+
+// Query renders the page query. The sort and tie-breaker values are selected as two
+// trailing columns which Page.Scan consumes to build the next cursor.
+func (p Params) Query(spec Spec, columns string) (string, []interface{}) {
+	sortExpr := spec.Sorts[p.sortKey]
+	where := append([]string{}, p.where...)
+	args := append([]interface{}{}, p.args...)
+
+	if p.cursor != nil {
+		op := ">"
+		if p.desc {
+			op = "<"
+		}
+		where = append(where, fmt.Sprintf("(%[1]s %[3]s ? OR (%[1]s = ? AND %[2]s %[3]s ?))", sortExpr, spec.TieBreaker, op))
+		args = append(args, p.cursor.Sort, p.cursor.Sort, p.cursor.Tie)
+	}
+
+	dir := "ASC"
+	if p.desc {
+		dir = "DESC"
+	}
+	query := fmt.Sprintf("SELECT %s, %s, %s FROM %s %s ORDER BY %s %s, %s %s LIMIT ? OFFSET ?",
+		columns, sortExpr, spec.TieBreaker, spec.From, whereClause(where),
+		sortExpr, dir, spec.TieBreaker, dir)
+	args = append(args, p.Limit+1, p.Offset)
+	return query, args
+}
+
+// Count returns the number of rows matching the filters, ignoring pagination
+func (p Params) Count(db *sql.DB, spec Spec) (int, error) {
+	var total int
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s %s", spec.From, whereClause(p.where))
+	err := db.QueryRow(query, p.args...).Scan(&total)
+	return total, err
+}
+
+// Page collects one page of rows
+type Page struct {
+	params   Params
+	n        int
+	lastSort interface{}
+	lastTie  interface{}
+	more     bool
+}
+
+func (p Params) NewPage() *Page {
+	return &Page{params: p}
+}
+
+// Scan reads the current row into dest. It returns false for the extra row fetched
+// past the limit, which only tells us that there is a next page.
+func (pg *Page) Scan(rows *sql.Rows, dest ...interface{}) (bool, error) {
+	var sortValue, tieValue interface{}
+	if err := rows.Scan(append(dest, &sortValue, &tieValue)...); err != nil {
+		return false, err
+	}
+	if pg.n == pg.params.Limit {
+		pg.more = true
+		return false, nil
+	}
+	pg.n++
+	pg.lastSort, pg.lastTie = cursorValue(sortValue), cursorValue(tieValue)
+	return true, nil
+}
+
+// Envelope wraps the collected items with the total and the next cursor
+func (pg *Page) Envelope(items interface{}, total int) Envelope {
+	env := Envelope{Items: items, Total: total, Limit: pg.params.Limit, Offset: pg.params.Offset}
+	if pg.more {
+		env.NextCursor = cursor{Sort: pg.lastSort, Tie: pg.lastTie}.encode()
+	}
+	return env
+}
+
+// WriteJSON encodes an envelope as the response body
+func WriteJSON(w http.ResponseWriter, env Envelope) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(env)
+}
+
+// cursorValue keeps cursor values comparable with what SQLite stores
+func cursorValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case []byte:
+		return string(t)
+	case time.Time:
+		return t.UTC().Format("2006-01-02 15:04:05")
+	default:
+		return v
+	}
+}
+
+func (c cursor) encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	dec := json.NewDecoder(strings.NewReader(string(raw)))
+	dec.UseNumber()
+	if err := dec.Decode(&c); err != nil {
+		return c, err
+	}
+	c.Sort, c.Tie = fromJSON(c.Sort), fromJSON(c.Tie)
+	return c, nil
+}
+
+// fromJSON turns json.Number back into an int64 or float64 so SQLite compares numerically
+func fromJSON(v interface{}) interface{} {
+	n, ok := v.(json.Number)
+	if !ok {
+		return v
+	}
+	if i, err := n.Int64(); err == nil {
+		return i
+	}
+	f, _ := n.Float64()
+	return f
+}
+
+func whereClause(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(conds, " AND ")
+}
+
+func sortKeys(spec Spec) []string {
+	keys := make([]string, 0, len(spec.Sorts))
+	for k := range spec.Sorts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func statusKeys(spec Spec) []string {
+	keys := make([]string, 0, len(spec.Statuses))
+	for k := range spec.Statuses {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,228 @@
+package listquery
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		sort     interface{}
+		tie      interface{}
+		wantSort interface{}
+	}{
+		{"string", "2025-05-01", "job-1", "2025-05-01"},
+		{"integer", int64(42), int64(7), int64(42)},
+		{"large integer", int64(1) << 60, int64(3), int64(1) << 60},
+		{"float", 3.25, int64(7), 3.25},
+		{"null", nil, "job-1", nil},
+		{"bytes", cursorValue([]byte("ACME")), "job-1", "ACME"},
+		{"time", cursorValue(time.Date(2025, 5, 1, 10, 0, 0, 0, time.FixedZone("CEST", 2*3600))), "job-1", "2025-05-01 08:00:00"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := decodeCursor(cursor{Sort: tc.sort, Tie: tc.tie}.encode())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Sort, tc.wantSort) || !reflect.DeepEqual(got.Tie, tc.tie) {
+				t.Errorf("round trip = %#v / %#v, want %#v / %#v", got.Sort, got.Tie, tc.wantSort, tc.tie)
+			}
+		})
+	}
+
+	for _, raw := range []string{"not base64!", "bm90IGpzb24", ""} {
+		if _, err := decodeCursor(raw); err == nil {
+			t.Errorf("decodeCursor(%q) accepted", raw)
+		}
+	}
+}
+
+var testSpec = Spec{
+	From:        "items",
+	Sorts:       map[string]string{"score": "score", "name": "COALESCE(name, '')"},
+	DefaultSort: "-score",
+	TieBreaker:  "id",
+	Source:      "source",
+	Company:     "company",
+	Date:        "day",
+	Statuses:    map[string]string{"open": "closed = 0"},
+}
+
+func TestParseValidates(t *testing.T) {
+	for _, tc := range []struct {
+		query string
+		err   string
+	}{
+		{"", ""},
+		{"limit=10&offset=20&sort=name&source=Xing&company=acme&status=open&since=2025-01-01&until=2025-02-01", ""},
+		{"limit=0", "limit must be a positive integer"},
+		{"limit=abc", "limit must be a positive integer"},
+		{"offset=-1", "offset must be a non-negative integer"},
+		{"offset=5&cursor=" + (cursor{Sort: 1, Tie: 1}).encode(), "use either cursor or offset, not both"},
+		{"cursor=garbage!", "invalid cursor"},
+		{"sort=-salary", "sort must be one of name, score"},
+		{"location=Berlin", "location filter is not supported here"},
+		{"status=closed", "status must be one of open"},
+		{"since=01.05.2025", "since must be a date in YYYY-MM-DD format"},
+	} {
+		_, err := Parse(httptest.NewRequest("GET", "/items?"+tc.query, nil), testSpec)
+		if got := fmt.Sprint(err); (tc.err == "" && err != nil) || (tc.err != "" && got != tc.err) {
+			t.Errorf("Parse(%q) = %v, want %q", tc.query, err, tc.err)
+		}
+	}
+
+	p, _ := Parse(httptest.NewRequest("GET", "/items?limit=9999", nil), testSpec)
+	if p.Limit != MaxLimit || p.sortKey != "score" || !p.desc {
+		t.Errorf("Parse = limit %d, sort %q desc %v; want %d, score, true", p.Limit, p.sortKey, p.desc, MaxLimit)
+	}
+}
+
+func newItemsDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(`CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT, score REAL, source TEXT, company TEXT, day TEXT, closed BOOLEAN)`); err != nil {
+		t.Fatal(err)
+	}
+	// Many equal scores and names, a NULL name, and a skipped id
+	for _, row := range []string{
+		`(1, 'b', 1.5, 'LinkedIn', 'ACME', '2025-01-01', 0)`,
+		`(2, 'a', 2.0, 'Xing', 'Beta', '2025-01-02', 0)`,
+		`(3, NULL, 1.5, 'LinkedIn', 'ACME GmbH', '2025-01-03', 1)`,
+		`(4, 'a', 1.5, 'Xing', 'Gamma', '2025-01-04', 0)`,
+		`(6, 'c', 0.5, 'LinkedIn', 'acme', '2025-01-05', 0)`,
+		`(7, 'b', 2.0, 'Xing', 'Beta', '2025-01-06', 0)`,
+		`(8, 'a', 1.5, 'LinkedIn', 'Delta', '2025-01-07', 1)`,
+	} {
+		if _, err := db.Exec(`INSERT INTO items VALUES ` + row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+// pageThrough follows next_cursor from the first page to the last and returns the ids
+// in order and the totals reported on every page
+func pageThrough(t *testing.T, db *sql.DB, query string) ([]int64, []int) {
+	t.Helper()
+	var ids []int64
+	var totals []int
+	next := ""
+	for pages := 0; ; pages++ {
+		if pages > 20 {
+			t.Fatal("cursor does not advance")
+		}
+		target := "/items?" + query
+		if next != "" {
+			target += "&cursor=" + next
+		}
+		p, err := Parse(httptest.NewRequest("GET", target, nil), testSpec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		q, args := p.Query(testSpec, "id")
+		rows, err := db.Query(q, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		page := p.NewPage()
+		var items []int64
+		for rows.Next() {
+			var id int64
+			ok, err := page.Scan(rows, &id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok {
+				items = append(items, id)
+			}
+		}
+		rows.Close()
+		total, err := p.Count(db, testSpec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		env := page.Envelope(items, total)
+		ids = append(ids, items...)
+		totals = append(totals, env.Total)
+		if env.NextCursor == "" {
+			return ids, totals
+		}
+		next = env.NextCursor
+	}
+}
+
+func TestCursorPagingMatchesOneQuery(t *testing.T) {
+	db := newItemsDB(t)
+
+	for _, tc := range []struct {
+		query string
+		want  []int64
+	}{
+		{"limit=2", []int64{7, 2, 8, 4, 3, 1, 6}},
+		{"limit=3&sort=score", []int64{6, 1, 3, 4, 8, 2, 7}},
+		{"limit=1&sort=name", []int64{3, 2, 4, 8, 1, 7, 6}},
+		{"limit=2&sort=-name", []int64{6, 7, 1, 8, 4, 2, 3}},
+		{"limit=2&company=acme", []int64{3, 1, 6}},
+		{"limit=2&source=Xing&status=open", []int64{7, 2, 4}},
+		{"limit=2&since=2025-01-02&until=2025-01-05&sort=name", []int64{3, 2, 4, 6}},
+		{"limit=50", []int64{7, 2, 8, 4, 3, 1, 6}},
+	} {
+		t.Run(tc.query, func(t *testing.T) {
+			ids, totals := pageThrough(t, db, tc.query)
+			if !reflect.DeepEqual(ids, tc.want) {
+				t.Errorf("ids = %v, want %v", ids, tc.want)
+			}
+			for _, total := range totals {
+				if total != len(tc.want) {
+					t.Errorf("totals = %v, want %d on every page", totals, len(tc.want))
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestOffsetPaging(t *testing.T) {
+	db := newItemsDB(t)
+
+	p, err := Parse(httptest.NewRequest("GET", "/items?limit=2&offset=5", nil), testSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, args := p.Query(testSpec, "id")
+	if !strings.Contains(q, "LIMIT ? OFFSET ?") {
+		t.Fatalf("query = %s", q)
+	}
+	rows, err := db.Query(q, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	page := p.NewPage()
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if ok, err := page.Scan(rows, &id); err != nil {
+			t.Fatal(err)
+		} else if ok {
+			ids = append(ids, id)
+		}
+	}
+	env := page.Envelope(ids, 7)
+	if fmt.Sprint(ids) != "[1 6]" || env.NextCursor != "" || env.Offset != 5 {
+		t.Errorf("page = %v, cursor %q, offset %d; want [1 6], no cursor, offset 5", ids, env.NextCursor, env.Offset)
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// CORS answers preflights and sets the Access-Control headers for allowed origins.
+// Requests from other origins are served without CORS headers, so browsers block them.
+// Credentials are never allowed: API keys travel in headers, not cookies. Unless any
+// origin is allowed, every response varies by Origin, so a cache does not hand the
+// answer for one origin to another.
+func CORS(cfg Config) func(http.Handler) http.Handler {
+	anyOrigin := false
+	origins := make(map[string]bool)
+	for _, o := range cfg.AllowedOrigins {
+		if o == "*" {
+			anyOrigin = true
+		}
+		origins[strings.TrimSuffix(o, "/")] = true
+	}
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			allowed := origin != "" && (anyOrigin || origins[origin])
+			if !anyOrigin {
+				w.Header().Add("Vary", "Origin")
+			}
+
+			if allowed {
+				if anyOrigin {
+					w.Header().Set("Access-Control-Allow-Origin", "*")
+				} else {
+					w.Header().Set("Access-Control-Allow-Origin", origin)
+				}
+				w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, Retry-After, Deprecation, Link")
+			}
+
+			// Preflight
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				if !allowed {
+					http.Error(w, "Origin not allowed", http.StatusForbidden)
+					return
+				}
+				w.Header().Set("Access-Control-Allow-Methods", methods)
+				w.Header().Set("Access-Control-Allow-Headers", headers)
+				w.Header().Set("Access-Control-Max-Age", "600")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// gzipMinSize is the smallest body worth compressing; below it the gzip framing
+// costs more than it saves
+const gzipMinSize = 1024
+
+var gzipWriters = sync.Pool{
+	New: func() interface{} { return gzip.NewWriter(nil) },
+}
+
+// Gzip compresses responses of at least gzipMinSize bytes for clients that accept it
+func Gzip(enabled bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !enabled {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Not deferred: after a panic nothing buffered is sent, so Recover's 500 can go out
+			gw := &gzipWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(gw, r)
+			gw.close()
+		})
+	}
+}
+
+// gzipWriter holds the status and the start of the body back until it knows whether
+// the body reaches gzipMinSize. Bodies the handler already encoded, and responses
+// without a body, pass through untouched.
+type gzipWriter struct {
+	http.ResponseWriter
+	gz          *gzip.Writer
+	status      int
+	wroteHeader bool // the handler called WriteHeader or Write
+	sent        bool // the status went out
+	passthrough bool
+	buf         []byte
+}
+
+func (gw *gzipWriter) WriteHeader(status int) {
+	if gw.wroteHeader {
+		return
+	}
+	gw.wroteHeader = true
+	gw.status = status
+	if status == http.StatusNoContent || status == http.StatusNotModified || gw.Header().Get("Content-Encoding") != "" {
+		gw.passthrough = true
+		gw.send()
+	}
+}
+
+func (gw *gzipWriter) Write(p []byte) (int, error) {
+	if !gw.wroteHeader {
+		if gw.Header().Get("Content-Type") == "" {
+			gw.Header().Set("Content-Type", http.DetectContentType(p))
+		}
+		gw.WriteHeader(http.StatusOK)
+	}
+	switch {
+	case gw.passthrough:
+		return gw.ResponseWriter.Write(p)
+	case gw.gz != nil:
+		return gw.gz.Write(p)
+	}
+
+	gw.buf = append(gw.buf, p...)
+	if len(gw.buf) < gzipMinSize {
+		return len(p), nil
+	}
+	h := gw.Header()
+	h.Set("Content-Encoding", "gzip")
+	h.Del("Content-Length")
+	gw.send()
+	gw.gz = gzipWriters.Get().(*gzip.Writer)
+	gw.gz.Reset(gw.ResponseWriter)
+	buf := gw.buf
+	gw.buf = nil
+	if _, err := gw.gz.Write(buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (gw *gzipWriter) send() {
+	if !gw.sent {
+		gw.sent = true
+		gw.ResponseWriter.WriteHeader(gw.status)
+	}
+}
+
+// close finishes the compressed stream, or writes out a body that stayed too small
+func (gw *gzipWriter) close() {
+	if gw.gz != nil {
+		gw.gz.Close()
+		gzipWriters.Put(gw.gz)
+		return
+	}
+	if gw.wroteHeader {
+		gw.send()
+	}
+	if len(gw.buf) > 0 {
+		gw.ResponseWriter.Write(gw.buf)
+	}
+}
@@ -0,0 +1,296 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	io.WriteString(w, "ok")
+})
+
+func TestCORS(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://app.example/, http://localhost:3000")
+	t.Setenv("CORS_ALLOWED_METHODS", "")
+	t.Setenv("CORS_ALLOWED_HEADERS", "")
+	listed := CORS(ConfigFromEnv())(okHandler)
+	anyOrigin := CORS(Config{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}})(okHandler)
+
+	for _, tc := range []struct {
+		name          string
+		handler       http.Handler
+		method        string
+		origin        string
+		preflight     string // Access-Control-Request-Method
+		status        int
+		allowOrigin   string
+		allowMethods  string
+		allowHeaders  string
+		varyOnOrigin  bool
+		reachesRouter bool
+	}{
+		{name: "allowed origin", handler: listed, method: "GET", origin: "https://app.example",
+			status: 200, allowOrigin: "https://app.example", varyOnOrigin: true, reachesRouter: true},
+		{name: "other origin", handler: listed, method: "GET", origin: "https://evil.example",
+			status: 200, varyOnOrigin: true, reachesRouter: true},
+		{name: "no origin", handler: listed, method: "POST",
+			status: 200, varyOnOrigin: true, reachesRouter: true},
+		{name: "preflight for DELETE", handler: listed, method: "OPTIONS", origin: "http://localhost:3000", preflight: "DELETE",
+			status: 204, allowOrigin: "http://localhost:3000", allowMethods: "GET, POST, DELETE, OPTIONS",
+			allowHeaders: "Content-Type, Authorization, X-API-Key", varyOnOrigin: true},
+		{name: "preflight from other origin", handler: listed, method: "OPTIONS", origin: "https://evil.example", preflight: "DELETE",
+			status: 403, varyOnOrigin: true},
+		{name: "plain OPTIONS is not a preflight", handler: listed, method: "OPTIONS", origin: "https://app.example",
+			status: 200, allowOrigin: "https://app.example", varyOnOrigin: true, reachesRouter: true},
+		{name: "any origin", handler: anyOrigin, method: "GET", origin: "https://elsewhere.example",
+			status: 200, allowOrigin: "*", reachesRouter: true},
+		{name: "any origin preflight", handler: anyOrigin, method: "OPTIONS", origin: "https://elsewhere.example", preflight: "GET",
+			status: 204, allowOrigin: "*", allowMethods: "GET"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/api/v1/jobs", nil)
+			if tc.origin != "" {
+				req.Header.Set("Origin", tc.origin)
+			}
+			if tc.preflight != "" {
+				req.Header.Set("Access-Control-Request-Method", tc.preflight)
+			}
+			rec := httptest.NewRecorder()
+			tc.handler.ServeHTTP(rec, req)
+
+			h := rec.Header()
+			if rec.Code != tc.status {
+				t.Errorf("status = %d, want %d", rec.Code, tc.status)
+			}
+			for name, want := range map[string]string{
+				"Access-Control-Allow-Origin":  tc.allowOrigin,
+				"Access-Control-Allow-Methods": tc.allowMethods,
+				"Access-Control-Allow-Headers": tc.allowHeaders,
+				// API keys travel in headers; cookies are never invited along
+				"Access-Control-Allow-Credentials": "",
+			} {
+				if got := h.Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+			if vary := strings.Contains(strings.Join(h.Values("Vary"), ","), "Origin"); vary != tc.varyOnOrigin {
+				t.Errorf("Vary = %q, want Origin in it: %v", h.Values("Vary"), tc.varyOnOrigin)
+			}
+			if reached := rec.Body.String() == "ok"; reached != tc.reachesRouter {
+				t.Errorf("body = %q, want the handler reached: %v", rec.Body, tc.reachesRouter)
+			}
+		})
+	}
+}
+
+func TestGzip(t *testing.T) {
+	large := strings.Repeat("compress me ", 200)
+	for _, tc := range []struct {
+		name     string
+		method   string
+		accept   string
+		status   int
+		encoding string // set by the handler
+		chunks   []string
+		gzipped  bool
+	}{
+		{name: "large body", accept: "gzip, deflate", chunks: []string{large}, gzipped: true},
+		{name: "large body in small writes", accept: "gzip", chunks: strings.SplitAfter(large, " "), gzipped: true},
+		{name: "client without gzip", accept: "br", chunks: []string{large}},
+		{name: "no Accept-Encoding", chunks: []string{large}},
+		{name: "small body", accept: "gzip", status: http.StatusCreated, chunks: []string{`{"id": 1}`}},
+		{name: "already encoded", accept: "gzip", encoding: "br", chunks: []string{large}},
+		{name: "no content", accept: "gzip", status: http.StatusNoContent},
+		{name: "status without a body", accept: "gzip", status: http.StatusAccepted},
+		{name: "HEAD", method: "HEAD", accept: "gzip", chunks: []string{large}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := Gzip(true)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tc.encoding != "" {
+					w.Header().Set("Content-Encoding", tc.encoding)
+				}
+				if tc.status != 0 {
+					w.WriteHeader(tc.status)
+				}
+				for _, c := range tc.chunks {
+					io.WriteString(w, c)
+				}
+			}))
+			method := tc.method
+			if method == "" {
+				method = "GET"
+			}
+			req := httptest.NewRequest(method, "/", nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept-Encoding", tc.accept)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			wantStatus := tc.status
+			if wantStatus == 0 {
+				wantStatus = http.StatusOK
+			}
+			if rec.Code != wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, wantStatus)
+			}
+			if vary := rec.Header().Get("Vary"); vary != "Accept-Encoding" {
+				t.Errorf("Vary = %q", vary)
+			}
+
+			body := rec.Body.Bytes()
+			encoding := rec.Header().Get("Content-Encoding")
+			if tc.gzipped {
+				if encoding != "gzip" {
+					t.Fatalf("Content-Encoding = %q, want gzip", encoding)
+				}
+				zr, err := gzip.NewReader(bytes.NewReader(body))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if body, err = io.ReadAll(zr); err != nil {
+					t.Fatal(err)
+				}
+			} else if encoding != tc.encoding {
+				t.Errorf("Content-Encoding = %q, want %q", encoding, tc.encoding)
+			}
+			if want := strings.Join(tc.chunks, ""); string(body) != want {
+				t.Errorf("body = %d bytes, want %d", len(body), len(want))
+			}
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	l := newLimiter(1, 2, func() time.Time { return now })
+	handler := l.middleware(okHandler)
+
+	for i, step := range []struct {
+		advance    time.Duration
+		client     string
+		status     int
+		retryAfter string
+	}{
+		{0, "10.0.0.1:1111", 200, ""},
+		{0, "10.0.0.1:2222", 200, ""}, // another port of the same client
+		{0, "10.0.0.1:1111", 429, "1"},
+		{0, "10.0.0.2:1111", 200, ""}, // other clients have buckets of their own
+		{500 * time.Millisecond, "10.0.0.1:1111", 429, "1"},
+		{500 * time.Millisecond, "10.0.0.1:1111", 200, ""},
+		{0, "10.0.0.1:1111", 429, "1"},
+		// A long pause refills the bucket, but only up to the burst
+		{time.Hour, "10.0.0.1:1111", 200, ""},
+		{0, "10.0.0.1:1111", 200, ""},
+		{0, "10.0.0.1:1111", 429, "1"},
+	} {
+		now = now.Add(step.advance)
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = step.client
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != step.status || rec.Header().Get("Retry-After") != step.retryAfter {
+			t.Errorf("step %d: %d with Retry-After %q, want %d with %q",
+				i+1, rec.Code, rec.Header().Get("Retry-After"), step.status, step.retryAfter)
+		}
+	}
+
+	// A rate of 0 lets everything through
+	unlimited := RateLimit(0, 0)(okHandler)
+	for i := 0; i < 100; i++ {
+		rec := httptest.NewRecorder()
+		unlimited.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		if rec.Code != 200 {
+			t.Fatalf("request %d = %d without a limit", i+1, rec.Code)
+		}
+	}
+}
+
+func TestRecover(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		handler http.Handler
+	}{
+		{"panic", Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		}))},
+		// Nothing the handler buffered before the panic is sent
+		{"panic behind gzip", Recover(Gzip(true)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "partial")
+			panic("boom")
+		})))},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			rec := httptest.NewRecorder()
+			tc.handler.ServeHTTP(rec, req)
+			if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "Internal server error") {
+				t.Errorf("response = %d %q, want a 500", rec.Code, rec.Body)
+			}
+		})
+	}
+
+	// An aborted handler is left to net/http, which closes the connection quietly
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler passed on", p)
+		}
+	}()
+	Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
+
+func TestMaxBody(t *testing.T) {
+	// The handler answers 413 when reading runs into the limit, as the API handlers do
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.Write(body)
+	})
+
+	for _, tc := range []struct {
+		name     string
+		limit    int64
+		body     string
+		streamed bool // no Content-Length, so only reading finds out
+		status   int
+	}{
+		{"within the limit", 10, "0123456789", false, 200},
+		{"declared too large", 10, "0123456789A", false, 413},
+		{"streamed within the limit", 10, "0123456789", true, 200},
+		{"streamed too large", 10, "0123456789A", true, 413},
+		{"no limit", 0, strings.Repeat("x", 1<<16), false, 200},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var body io.Reader = strings.NewReader(tc.body)
+			if tc.streamed {
+				body = io.MultiReader(body)
+			}
+			req := httptest.NewRequest("POST", "/", body)
+			if tc.streamed {
+				req.ContentLength = -1
+			}
+			rec := httptest.NewRecorder()
+			MaxBody(tc.limit)(echo).ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Errorf("status = %d, want %d", rec.Code, tc.status)
+			}
+			if tc.status == 200 && rec.Body.String() != tc.body {
+				t.Errorf("handler read %d bytes, want %d", rec.Body.Len(), len(tc.body))
+			}
+		})
+	}
+}
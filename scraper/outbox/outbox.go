@@ -0,0 +1,456 @@
+// Package outbox makes uploads reliable. Jobs are queued in upload_outbox in the same
+// SQLite transaction that marks them sent, with one upload_deliveries row per target
+// sink. Dispatch then delivers whatever is not delivered yet, so a crash or a failing
+// sink leads to a redelivery rather than a lost or silently skipped job. Every entry
+// carries an idempotency key, and the sinks upsert, so redeliveries are harmless.
+//
+// Delivered entries are pruned at the end of every dispatch. Each job keeps its newest
+// delivered entry per sink, which JobDeliveries reads to tell when the job last
+// arrived there; older versions are deleted.
+//
+// Uploads are incremental: upload_state keeps the content hash last queued for each
+// record and sink, so only new records (event "created") and records whose content
+// changed (event "updated") are queued again.
+package outbox
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"job_scraper/scraper/sink"
+)
+
+// Delivery states in upload_deliveries
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed" // retried by the next dispatch
+)
+
+// Events delivered with each job
+const (
+	EventCreated = "created"
+	EventUpdated = "updated"
+)
+
+// legacySink received uploads before change tracking existed, as one document per
+// application link. Rows already marked sent but not yet tracked for it are queued
+// once more as updates, so the per-job document replaces the per-link ones.
+const legacySink = "mongo"
+
+// Entry is a job collected for upload, with the jobs table row it came from
+type Entry struct {
+	Table     string // linkedin_jobs or xing_jobs
+	RowID     string
+	Job       sink.Job
+	Sent      bool   // the row was marked sent by an earlier upload
+	ChangedAt string // latest updated_at of the joined rows
+}
+
+// Changes counts what Enqueue did with the collected entries
+type Changes struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+}
+
+// IdempotencyKey is derived from the job's content, so the same version of a job
+// always carries the same key. It also returns the hash and the stored payload.
+func IdempotencyKey(job sink.Job) (key, hash string, payload []byte, err error) {
+	job.IdempotencyKey, job.Event = "", ""
+	payload, err = json.Marshal(job)
+	if err != nil {
+		return "", "", nil, err
+	}
+	sum := sha256.Sum256(payload)
+	hash = hex.EncodeToString(sum[:])
+	return job.Key() + ":" + hash[:16], hash, payload, nil
+}
+
+// Enqueue queues, inside tx, every entry whose content differs from what was last
+// queued for each of the named sinks, and marks the job rows as sent
+func Enqueue(tx *sql.Tx, entries []Entry, sinks []string) (Changes, error) {
+	var changes Changes
+	for _, e := range entries {
+		key, hash, payload, err := IdempotencyKey(e.Job)
+		if err != nil {
+			return changes, fmt.Errorf("outbox: encode %s: %v", e.Job.Key(), err)
+		}
+
+		event := ""
+		for _, name := range sinks {
+			var last string
+			err := tx.QueryRow(`SELECT content_hash FROM upload_state WHERE sink = ? AND source = ? AND job_id = ?`,
+				name, e.Job.Source, e.Job.ID).Scan(&last)
+			var queued string
+			switch {
+			case err == sql.ErrNoRows && e.Sent && name == legacySink:
+				queued, err = queue(tx, e, key, string(payload), name, EventUpdated)
+			case err == sql.ErrNoRows:
+				queued, err = queue(tx, e, key, string(payload), name, EventCreated)
+			case err != nil:
+			case last == hash:
+				continue
+			default:
+				queued, err = queue(tx, e, key, string(payload), name, EventUpdated)
+			}
+			if err != nil {
+				return changes, fmt.Errorf("outbox: enqueue %s for %s: %v", e.Job.Key(), name, err)
+			}
+			event = strongest(event, queued)
+
+			if _, err := tx.Exec(`
+				INSERT INTO upload_state (sink, source, job_id, content_hash, queued_at)
+				VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+				ON CONFLICT (sink, source, job_id) DO UPDATE SET
+					content_hash = excluded.content_hash, queued_at = excluded.queued_at`,
+				name, e.Job.Source, e.Job.ID, hash); err != nil {
+				return changes, fmt.Errorf("outbox: record state of %s: %v", e.Job.Key(), err)
+			}
+		}
+
+		switch event {
+		case EventCreated:
+			changes.Created++
+		case EventUpdated:
+			changes.Updated++
+		default:
+			changes.Unchanged++
+		}
+
+		if !e.Sent {
+			if _, err := tx.Exec(fmt.Sprintf("UPDATE %s SET sent = TRUE WHERE id = ?", e.Table), e.RowID); err != nil {
+				return changes, fmt.Errorf("outbox: mark %s as sent: %v", e.Job.Key(), err)
+			}
+		}
+	}
+	return changes, nil
+}
+
+// queue stores the entry once per content version and (re)opens its delivery to a
+// sink; a version that was delivered before and came back is delivered again.
+// Older versions of the job still waiting for the sink are dropped, so a batch never
+// holds two versions of one job that the sink could apply out of order. When the sink
+// never got the job, the newest version is still sent as created. It returns the
+// event queued.
+func queue(tx *sql.Tx, e Entry, key, payload, sinkName, event string) (string, error) {
+	superseded := `
+		SELECT d.outbox_id FROM upload_deliveries d
+		JOIN upload_outbox o ON o.id = d.outbox_id
+		WHERE d.sink = ? AND d.status != ? AND o.source = ? AND o.job_row_id = ? AND o.idempotency_key != ?`
+	args := []interface{}{sinkName, StatusDelivered, e.Job.Source, e.RowID, key}
+
+	var neverDelivered bool
+	if err := tx.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM upload_deliveries WHERE sink = ? AND event = ? AND outbox_id IN (`+superseded+`))`,
+		append([]interface{}{sinkName, EventCreated}, args...)...).Scan(&neverDelivered); err != nil {
+		return event, err
+	}
+	if neverDelivered {
+		event = EventCreated
+	}
+	if _, err := tx.Exec(`DELETE FROM upload_deliveries WHERE sink = ? AND outbox_id IN (`+superseded+`)`,
+		append([]interface{}{sinkName}, args...)...); err != nil {
+		return event, err
+	}
+
+	if _, err := tx.Exec(`
+		INSERT OR IGNORE INTO upload_outbox (source, job_row_id, idempotency_key, payload)
+		VALUES (?, ?, ?, ?)`, e.Job.Source, e.RowID, key, payload); err != nil {
+		return event, err
+	}
+	_, err := tx.Exec(`
+		INSERT INTO upload_deliveries (outbox_id, sink, status, event, updated_at)
+		SELECT id, ?, ?, ?, CURRENT_TIMESTAMP FROM upload_outbox WHERE idempotency_key = ?
+		ON CONFLICT (outbox_id, sink) DO UPDATE SET
+			status = excluded.status, event = excluded.event, updated_at = excluded.updated_at`,
+		sinkName, StatusPending, event, key)
+	return event, err
+}
+
+// strongest keeps "created" over "updated" when sinks disagree about a record
+func strongest(current, event string) string {
+	if current == EventCreated {
+		return current
+	}
+	return event
+}
+
+// Watermark is the oldest point in the change feed any of the sinks has read up to
+// for source; an empty string means a sink has not uploaded from it yet
+func Watermark(tx *sql.Tx, sinks []string, source string) (string, error) {
+	oldest := ""
+	for i, name := range sinks {
+		var at string
+		err := tx.QueryRow(`SELECT changed_at FROM upload_watermarks WHERE sink = ? AND source = ?`, name, source).Scan(&at)
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("outbox: read watermark: %v", err)
+		}
+		if i == 0 || at < oldest {
+			oldest = at
+		}
+	}
+	return oldest, nil
+}
+
+// SetWatermark moves the sinks' position in source's change feed forward to at
+func SetWatermark(tx *sql.Tx, sinks []string, source, at string) error {
+	for _, name := range sinks {
+		if _, err := tx.Exec(`
+			INSERT INTO upload_watermarks (sink, source, changed_at) VALUES (?, ?, ?)
+			ON CONFLICT (sink, source) DO UPDATE SET changed_at = MAX(changed_at, excluded.changed_at)`,
+			name, source, at); err != nil {
+			return fmt.Errorf("outbox: set watermark: %v", err)
+		}
+	}
+	return nil
+}
+
+type pending struct {
+	outboxID int64
+	job      sink.Job
+}
+
+// Dispatch delivers every undelivered entry queued for the given sinks, batchSize
+// entries per Write. Entries a sink rejects stay queued as failed for the next run.
+// The error is only set when the outbox itself cannot be read or updated.
+func Dispatch(ctx context.Context, db *sql.DB, sinks []sink.Sink, batchSize int) ([]sink.Result, error) {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	results := []sink.Result{}
+	for _, s := range sinks {
+		total := sink.Result{Sink: s.Name(), Failed: []string{}}
+		var after int64
+		for {
+			batch, err := loadPending(db, s.Name(), after, batchSize)
+			if err != nil {
+				return results, err
+			}
+			if len(batch) == 0 {
+				break
+			}
+			after = batch[len(batch)-1].outboxID
+
+			jobs := make([]sink.Job, len(batch))
+			for i, p := range batch {
+				jobs[i] = p.job
+			}
+			res, werr := s.Write(ctx, jobs)
+
+			failed := map[string]string{}
+			if werr != nil {
+				log.Printf("❌ Upload of %d jobs to %s failed: %v", len(jobs), s.Name(), werr)
+				total.Error = werr.Error()
+				for _, job := range jobs {
+					failed[job.Key()] = werr.Error()
+				}
+			} else {
+				for _, key := range res.Failed {
+					failed[key] = "rejected by " + s.Name()
+				}
+				total.Written += res.Written
+				total.Inserted += res.Inserted
+				total.Updated += res.Updated
+			}
+
+			if err := record(db, s.Name(), batch, failed); err != nil {
+				return results, err
+			}
+			for key := range failed {
+				total.Failed = append(total.Failed, key)
+			}
+		}
+		log.Printf("📤 %s: %d delivered (%d inserted, %d updated), %d failed", s.Name(), total.Written, total.Inserted, total.Updated, len(total.Failed))
+		results = append(results, total)
+	}
+	return results, prune(db)
+}
+
+func loadPending(db *sql.DB, sinkName string, after int64, limit int) ([]pending, error) {
+	rows, err := db.Query(`
+		SELECT o.id, o.idempotency_key, o.payload, d.event
+		FROM upload_deliveries d
+		JOIN upload_outbox o ON o.id = d.outbox_id
+		WHERE d.sink = ? AND d.status != ? AND d.outbox_id > ?
+		ORDER BY d.outbox_id
+		LIMIT ?`, sinkName, StatusDelivered, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("outbox: load pending deliveries: %v", err)
+	}
+	defer rows.Close()
+
+	var batch []pending
+	for rows.Next() {
+		var p pending
+		var key, payload, event string
+		if err := rows.Scan(&p.outboxID, &key, &payload, &event); err != nil {
+			return nil, fmt.Errorf("outbox: scan delivery: %v", err)
+		}
+		if err := json.Unmarshal([]byte(payload), &p.job); err != nil {
+			return nil, fmt.Errorf("outbox: decode entry %d: %v", p.outboxID, err)
+		}
+		p.job.IdempotencyKey, p.job.Event = key, event
+		batch = append(batch, p)
+	}
+	return batch, rows.Err()
+}
+
+// record stores the outcome of one batch; failed maps Job.Key to the error
+func record(db *sql.DB, sinkName string, batch []pending, failed map[string]string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("outbox: %v", err)
+	}
+	defer tx.Rollback()
+
+	for _, p := range batch {
+		if msg, ok := failed[p.job.Key()]; ok {
+			_, err = tx.Exec(`
+				UPDATE upload_deliveries
+				SET status = ?, attempts = attempts + 1, last_error = ?, updated_at = CURRENT_TIMESTAMP
+				WHERE outbox_id = ? AND sink = ?`, StatusFailed, msg, p.outboxID, sinkName)
+		} else {
+			_, err = tx.Exec(`
+				UPDATE upload_deliveries
+				SET status = ?, attempts = attempts + 1, last_error = NULL,
+					updated_at = CURRENT_TIMESTAMP, delivered_at = CURRENT_TIMESTAMP
+				WHERE outbox_id = ? AND sink = ?`, StatusDelivered, p.outboxID, sinkName)
+		}
+		if err != nil {
+			return fmt.Errorf("outbox: record delivery of %s to %s: %v", p.job.Key(), sinkName, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// prunable selects the entries no sink needs any more: nothing left to deliver, and
+// for every sink a newer delivery of the same job. Entries whose deliveries were all
+// superseded before they went out have no deliveries left and are selected too.
+const prunable = `
+	SELECT o.id FROM upload_outbox o
+	WHERE NOT EXISTS (
+		SELECT 1 FROM upload_deliveries d
+		WHERE d.outbox_id = o.id AND (d.status != ? OR NOT EXISTS (
+			SELECT 1 FROM upload_deliveries n
+			JOIN upload_outbox no ON no.id = n.outbox_id
+			WHERE no.source = o.source AND no.job_row_id = o.job_row_id AND n.sink = d.sink
+				AND n.status = ? AND n.outbox_id != d.outbox_id
+				AND (n.delivered_at > d.delivered_at OR (n.delivered_at = d.delivered_at AND n.outbox_id > d.outbox_id)))))`
+
+// prune deletes the entries selected by prunable with their deliveries
+func prune(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("outbox: %v", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`DELETE FROM upload_outbox WHERE id IN (`+prunable+`)`, StatusDelivered, StatusDelivered)
+	if err != nil {
+		return fmt.Errorf("outbox: prune: %v", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM upload_deliveries WHERE outbox_id NOT IN (SELECT id FROM upload_outbox)`); err != nil {
+		return fmt.Errorf("outbox: prune: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("outbox: prune: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		log.Printf("🧹 Pruned %d delivered outbox entries", n)
+	}
+	return nil
+}
+
+// JobDelivery is where one job stands with one sink
+type JobDelivery struct {
+	Sink        string `json:"sink"`
+	Pending     bool   `json:"pending"`                // a version of the job still waits for the sink
+	Attempts    int    `json:"attempts"`               // of the waiting version, else of the delivered one
+	LastError   string `json:"last_error,omitempty"`   // of the waiting version
+	DeliveredAt string `json:"delivered_at,omitempty"` // when a version of the job last arrived
+}
+
+// JobDeliveries reports how the job with the given jobs table row id got on with
+// each sink it was queued for, sorted by sink name
+func JobDeliveries(db *sql.DB, source, rowID string) ([]JobDelivery, error) {
+	rows, err := db.Query(`
+		SELECT d.sink, d.status, d.attempts, COALESCE(d.last_error, ''), COALESCE(d.delivered_at, '')
+		FROM upload_deliveries d
+		JOIN upload_outbox o ON o.id = d.outbox_id
+		WHERE o.source = ? AND o.job_row_id = ?
+		ORDER BY d.sink, d.outbox_id`, source, rowID)
+	if err != nil {
+		return nil, fmt.Errorf("outbox: job deliveries: %v", err)
+	}
+	defer rows.Close()
+
+	deliveries := []JobDelivery{}
+	for rows.Next() {
+		var name, status, lastError, deliveredAt string
+		var attempts int
+		if err := rows.Scan(&name, &status, &attempts, &lastError, &deliveredAt); err != nil {
+			return nil, fmt.Errorf("outbox: job deliveries: %v", err)
+		}
+		if n := len(deliveries); n == 0 || deliveries[n-1].Sink != name {
+			deliveries = append(deliveries, JobDelivery{Sink: name})
+		}
+		d := &deliveries[len(deliveries)-1]
+		if status != StatusDelivered {
+			d.Pending, d.Attempts, d.LastError = true, attempts, lastError
+		}
+		if deliveredAt > d.DeliveredAt {
+			d.DeliveredAt = deliveredAt
+			if !d.Pending {
+				d.Attempts = attempts
+			}
+		}
+	}
+	return deliveries, rows.Err()
+}
+
+// SinkStatus counts the outbox deliveries of one sink by state. Delivered only counts
+// what pruning keeps: the newest delivered version of each job.
+type SinkStatus struct {
+	Sink      string `json:"sink"`
+	Pending   int    `json:"pending"`
+	Delivered int    `json:"delivered"`
+	Failed    int    `json:"failed"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Stats reports delivery state per sink, sorted by sink name
+func Stats(db *sql.DB) ([]SinkStatus, error) {
+	rows, err := db.Query(`
+		SELECT sink,
+			SUM(CASE WHEN status = ? THEN 1 ELSE 0 END),
+			SUM(CASE WHEN status = ? THEN 1 ELSE 0 END),
+			SUM(CASE WHEN status = ? THEN 1 ELSE 0 END),
+			COALESCE((SELECT last_error FROM upload_deliveries l
+				WHERE l.sink = d.sink AND l.status = ? ORDER BY l.updated_at DESC LIMIT 1), '')
+		FROM upload_deliveries d
+		GROUP BY sink
+		ORDER BY sink`, StatusPending, StatusDelivered, StatusFailed, StatusFailed)
+	if err != nil {
+		return nil, fmt.Errorf("outbox: stats: %v", err)
+	}
+	defer rows.Close()
+
+	stats := []SinkStatus{}
+	for rows.Next() {
+		var st SinkStatus
+		if err := rows.Scan(&st.Sink, &st.Pending, &st.Delivered, &st.Failed, &st.LastError); err != nil {
+			return nil, fmt.Errorf("outbox: stats: %v", err)
+		}
+		stats = append(stats, st)
+	}
+	return stats, rows.Err()
+}
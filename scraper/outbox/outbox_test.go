@@ -0,0 +1,394 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"job_scraper/config"
+	"job_scraper/scraper/sink"
+)
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := config.InitializeDatabaseAt(filepath.Join(t.TempDir(), "outbox.db"))
+	if err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(`INSERT INTO linkedin_jobs (id, jobid, title, company, processed, sent)
+		VALUES ('101', '101', 'Go Developer', 'Acme', 1, 0), ('102', '102', 'Data Engineer', 'Beta', 1, 0)`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	return db
+}
+
+func entries() []Entry {
+	return []Entry{
+		{Table: "linkedin_jobs", RowID: "101", Job: sink.Job{ID: "101", JobID: "4001", Source: "LinkedIn", Title: "Go Developer"}},
+		{Table: "linkedin_jobs", RowID: "102", Job: sink.Job{ID: "102", JobID: "4002", Source: "LinkedIn", Title: "Data Engineer"}},
+	}
+}
+
+func enqueue(t *testing.T, db *sql.DB, e []Entry, sinks ...string) Changes {
+	t.Helper()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	changes, err := Enqueue(tx, e, sinks)
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	return changes
+}
+
+// recordingSink rejects the keys in reject and remembers what it received
+type recordingSink struct {
+	name     string
+	reject   map[string]bool
+	down     bool
+	received []sink.Job
+}
+
+func (s *recordingSink) Name() string { return s.name }
+
+func (s *recordingSink) Write(ctx context.Context, jobs []sink.Job) (sink.Result, error) {
+	if s.down {
+		return sink.Result{Sink: s.name}, errors.New("connection refused")
+	}
+	res := sink.Result{Sink: s.name, Failed: []string{}}
+	for _, job := range jobs {
+		s.received = append(s.received, job)
+		if s.reject[job.Key()] {
+			res.Failed = append(res.Failed, job.Key())
+			continue
+		}
+		res.Written++
+	}
+	return res, nil
+}
+
+func (s *recordingSink) Close(ctx context.Context) error { return nil }
+
+func TestEnqueueQueuesOnlyChanges(t *testing.T) {
+	db := newTestDB(t)
+	if c := enqueue(t, db, entries(), "mongo", "file"); c != (Changes{Created: 2}) {
+		t.Fatalf("first run = %+v, want 2 created", c)
+	}
+	var sent int
+	db.QueryRow(`SELECT COUNT(*) FROM linkedin_jobs WHERE sent = TRUE`).Scan(&sent)
+	if sent != 2 {
+		t.Errorf("%d jobs marked sent, want 2", sent)
+	}
+
+	// Same content again: nothing queued
+	if c := enqueue(t, db, entries(), "mongo", "file"); c != (Changes{Unchanged: 2}) {
+		t.Errorf("unchanged run = %+v", c)
+	}
+	var queued, deliveries int
+	db.QueryRow(`SELECT COUNT(*) FROM upload_outbox`).Scan(&queued)
+	db.QueryRow(`SELECT COUNT(*) FROM upload_deliveries`).Scan(&deliveries)
+	if queued != 2 || deliveries != 4 {
+		t.Errorf("%d entries, %d deliveries, want 2 and 4", queued, deliveries)
+	}
+
+	// Changed content is a new version with its own key, sent as an update once the
+	// first version has been delivered
+	if _, err := Dispatch(context.Background(), db, []sink.Sink{&recordingSink{name: "mongo"}}, 10); err != nil {
+		t.Fatal(err)
+	}
+	changed := entries()[:1]
+	changed[0].Job.Description = &sink.Description{Text: "Build APIs", Skills: []string{"Go", "Kubernetes"}}
+	if c := enqueue(t, db, changed, "mongo"); c != (Changes{Updated: 1}) {
+		t.Errorf("changed run = %+v", c)
+	}
+	var event string
+	db.QueryRow(`SELECT event FROM upload_deliveries ORDER BY outbox_id DESC LIMIT 1`).Scan(&event)
+	if event != EventUpdated {
+		t.Errorf("event = %q, want %q", event, EventUpdated)
+	}
+
+	// A sink that has not seen the jobs yet gets them as new
+	if c := enqueue(t, db, entries(), "webhook"); c != (Changes{Created: 2}) {
+		t.Errorf("new sink = %+v", c)
+	}
+}
+
+func TestEnqueueReexportsJobsSentToMongoBeforeTracking(t *testing.T) {
+	db := newTestDB(t)
+	e := entries()
+	e[0].Sent = true
+	// New to the file sink, so created overall
+	if c := enqueue(t, db, e, "mongo", "file"); c != (Changes{Created: 2}) {
+		t.Errorf("changes = %+v", c)
+	}
+	// Mongo holds the job in the per-link shape: the per-job document replaces it
+	var events []string
+	rows, _ := db.Query(`SELECT d.sink || ' ' || d.event FROM upload_deliveries d
+		JOIN upload_outbox o ON o.id = d.outbox_id WHERE o.job_row_id = '101' ORDER BY d.sink`)
+	for rows.Next() {
+		var ev string
+		rows.Scan(&ev)
+		events = append(events, ev)
+	}
+	rows.Close()
+	if fmt.Sprint(events) != "[file created mongo updated]" {
+		t.Errorf("deliveries of the sent job = %v", events)
+	}
+
+	// Only once: after that the job is tracked like any other
+	if c := enqueue(t, db, e, "mongo", "file"); c != (Changes{Unchanged: 2}) {
+		t.Errorf("second run = %+v", c)
+	}
+}
+
+func TestWatermarkIsTheOldestSinkPosition(t *testing.T) {
+	db := newTestDB(t)
+	tx, _ := db.Begin()
+	defer tx.Rollback()
+
+	if at, _ := Watermark(tx, []string{"mongo"}, "LinkedIn"); at != "" {
+		t.Errorf("fresh watermark = %q", at)
+	}
+	SetWatermark(tx, []string{"mongo", "file"}, "LinkedIn", "2025-01-10 08:00:00.000")
+	SetWatermark(tx, []string{"mongo"}, "LinkedIn", "2025-01-12 08:00:00.000")
+	SetWatermark(tx, []string{"mongo"}, "LinkedIn", "2025-01-11 08:00:00.000") // never moves back
+
+	if at, _ := Watermark(tx, []string{"mongo"}, "LinkedIn"); at != "2025-01-12 08:00:00.000" {
+		t.Errorf("mongo = %q", at)
+	}
+	if at, _ := Watermark(tx, []string{"mongo", "file"}, "LinkedIn"); at != "2025-01-10 08:00:00.000" {
+		t.Errorf("mongo+file = %q", at)
+	}
+	if at, _ := Watermark(tx, []string{"mongo", "webhook"}, "LinkedIn"); at != "" {
+		t.Errorf("with a new sink = %q", at)
+	}
+}
+
+func TestEnqueueRollsBackWithTheTransaction(t *testing.T) {
+	db := newTestDB(t)
+	tx, _ := db.Begin()
+	if _, err := Enqueue(tx, entries(), []string{"mongo"}); err != nil {
+		t.Fatal(err)
+	}
+	tx.Rollback()
+
+	var queued, sent int
+	db.QueryRow(`SELECT COUNT(*) FROM upload_outbox`).Scan(&queued)
+	db.QueryRow(`SELECT COUNT(*) FROM linkedin_jobs WHERE sent = TRUE`).Scan(&sent)
+	if queued != 0 || sent != 0 {
+		t.Errorf("after rollback: %d queued, %d sent", queued, sent)
+	}
+}
+
+func TestDispatchRetriesUntilDelivered(t *testing.T) {
+	db := newTestDB(t)
+	enqueue(t, db, entries(), "mongo", "webhook")
+
+	mongo := &recordingSink{name: "mongo", reject: map[string]bool{"LinkedIn:4002": true}}
+	webhook := &recordingSink{name: "webhook", down: true}
+	results, err := Dispatch(context.Background(), db, []sink.Sink{mongo, webhook}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Written != 1 || len(results[0].Failed) != 1 {
+		t.Errorf("mongo = %+v", results[0])
+	}
+	if results[1].Written != 0 || len(results[1].Failed) != 2 || results[1].Error == "" {
+		t.Errorf("webhook = %+v", results[1])
+	}
+	for _, job := range mongo.received {
+		if job.IdempotencyKey == "" || job.Event != EventCreated {
+			t.Errorf("%s delivered with key %q and event %q", job.Key(), job.IdempotencyKey, job.Event)
+		}
+	}
+
+	stats, err := Stats(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SinkStatus{
+		{Sink: "mongo", Delivered: 1, Failed: 1, LastError: "rejected by mongo"},
+		{Sink: "webhook", Failed: 2, LastError: "connection refused"},
+	}
+	if len(stats) != 2 || stats[0] != want[0] || stats[1] != want[1] {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+
+	// Next run redelivers only what failed, with the same key
+	firstKey := mongo.received[1].IdempotencyKey
+	mongo.reject, mongo.received = nil, nil
+	webhook.down = false
+	results, _ = Dispatch(context.Background(), db, []sink.Sink{mongo, webhook}, 10)
+	if len(mongo.received) != 1 || mongo.received[0].IdempotencyKey != firstKey {
+		t.Errorf("mongo redelivery = %+v", mongo.received)
+	}
+	if results[1].Written != 2 {
+		t.Errorf("webhook redelivery = %+v", results[1])
+	}
+
+	var undelivered, attempts int
+	db.QueryRow(`SELECT COUNT(*) FROM upload_deliveries WHERE status != ?`, StatusDelivered).Scan(&undelivered)
+	db.QueryRow(`SELECT attempts FROM upload_deliveries d JOIN upload_outbox o ON o.id = d.outbox_id
+		WHERE d.sink = 'mongo' AND o.job_row_id = '102'`).Scan(&attempts)
+	if undelivered != 0 || attempts != 2 {
+		t.Errorf("%d undelivered, %d attempts for the retried job", undelivered, attempts)
+	}
+}
+
+// undelivered lists the keys of job 101 waiting for mongo, with their event
+func undelivered(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+	rows, err := db.Query(`
+		SELECT o.idempotency_key, d.event FROM upload_deliveries d
+		JOIN upload_outbox o ON o.id = d.outbox_id
+		WHERE d.sink = 'mongo' AND d.status != ? AND o.job_row_id = '101'
+		ORDER BY o.id`, StatusDelivered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var key, event string
+		rows.Scan(&key, &event)
+		out = append(out, key+" "+event)
+	}
+	return out
+}
+
+func TestEnqueueKeepsOnlyTheNewestUndeliveredVersion(t *testing.T) {
+	db := newTestDB(t)
+	version := func(text string) []Entry {
+		e := entries()[:1]
+		if text != "" {
+			e[0].Job.Description = &sink.Description{Text: text}
+		}
+		return e
+	}
+	keyOf := func(e []Entry) string {
+		key, _, _, _ := IdempotencyKey(e[0].Job)
+		return key
+	}
+
+	// Never delivered: the newer version replaces the older one and still creates the job
+	enqueue(t, db, version(""), "mongo")
+	if c := enqueue(t, db, version("v2"), "mongo"); c != (Changes{Created: 1}) {
+		t.Errorf("second version before delivery = %+v, want created", c)
+	}
+	if got := undelivered(t, db); len(got) != 1 || got[0] != keyOf(version("v2"))+" created" {
+		t.Errorf("waiting = %v, want only v2 as created", got)
+	}
+
+	mongo := &recordingSink{name: "mongo"}
+	if _, err := Dispatch(context.Background(), db, []sink.Sink{mongo}, 10); err != nil {
+		t.Fatal(err)
+	}
+	if len(mongo.received) != 1 || mongo.received[0].Description.Text != "v2" {
+		t.Fatalf("delivered %+v, want v2 only", mongo.received)
+	}
+
+	// Delivered once: later versions are updates, and only the newest is kept
+	for _, text := range []string{"v3", "v4"} {
+		if c := enqueue(t, db, version(text), "mongo"); c != (Changes{Updated: 1}) {
+			t.Errorf("%s = %+v, want updated", text, c)
+		}
+	}
+	if got := undelivered(t, db); len(got) != 1 || got[0] != keyOf(version("v4"))+" updated" {
+		t.Errorf("waiting = %v, want only v4 as updated", got)
+	}
+
+	// Going back to an older version reopens it and drops the one in between
+	enqueue(t, db, version("v2"), "mongo")
+	if got := undelivered(t, db); len(got) != 1 || got[0] != keyOf(version("v2"))+" updated" {
+		t.Errorf("waiting = %v, want only v2 as updated", got)
+	}
+
+	mongo.received = nil
+	if _, err := Dispatch(context.Background(), db, []sink.Sink{mongo}, 10); err != nil {
+		t.Fatal(err)
+	}
+	if len(mongo.received) != 1 || mongo.received[0].Description.Text != "v2" {
+		t.Errorf("delivered %+v, want v2 only", mongo.received)
+	}
+}
+
+func TestDispatchPrunesSupersededDeliveries(t *testing.T) {
+	db := newTestDB(t)
+	enqueue(t, db, entries(), "mongo", "file")
+
+	mongo := &recordingSink{name: "mongo"}
+	file := &recordingSink{name: "file", down: true}
+	if _, err := Dispatch(context.Background(), db, []sink.Sink{mongo, file}, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	deliveries := func() string {
+		t.Helper()
+		got, err := JobDeliveries(db, "LinkedIn", "101")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range got {
+			if got[i].DeliveredAt != "" {
+				got[i].DeliveredAt = "set"
+			}
+		}
+		return fmt.Sprintf("%+v", got)
+	}
+	if got, want := deliveries(), "[{Sink:file Pending:true Attempts:1 LastError:connection refused DeliveredAt:} {Sink:mongo Pending:false Attempts:1 LastError: DeliveredAt:set}]"; got != want {
+		t.Errorf("deliveries = %s, want %s", got, want)
+	}
+
+	// A second version goes out to both sinks; the first one is no longer needed
+	changed := entries()[:1]
+	changed[0].Job.Description = &sink.Description{Text: "v2"}
+	enqueue(t, db, changed, "mongo", "file")
+	file.down = false
+	if _, err := Dispatch(context.Background(), db, []sink.Sink{mongo, file}, 10); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := deliveries(), "[{Sink:file Pending:false Attempts:1 LastError: DeliveredAt:set} {Sink:mongo Pending:false Attempts:1 LastError: DeliveredAt:set}]"; got != want {
+		t.Errorf("deliveries = %s, want %s", got, want)
+	}
+
+	key := keyOf(t, changed[0])
+	var kept []string
+	rows, err := db.Query(`SELECT job_row_id, idempotency_key FROM upload_outbox ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var row, k string
+		rows.Scan(&row, &k)
+		if row == "101" && k != key {
+			k = "an older version"
+		}
+		kept = append(kept, row+" "+k)
+	}
+	rows.Close()
+	if want := fmt.Sprint([]string{"102 " + keyOf(t, entries()[1]), "101 " + key}); fmt.Sprint(kept) != want {
+		t.Errorf("outbox = %v, want %s", kept, want)
+	}
+	var orphans int
+	db.QueryRow(`SELECT COUNT(*) FROM upload_deliveries WHERE outbox_id NOT IN (SELECT id FROM upload_outbox)`).Scan(&orphans)
+	if orphans != 0 {
+		t.Errorf("%d deliveries left without an entry", orphans)
+	}
+}
+
+func keyOf(t *testing.T, e Entry) string {
+	key, _, _, err := IdempotencyKey(e.Job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
@@ -0,0 +1,371 @@
+// Package pacing spaces out what the scrapers do in the browser. Navigations take a
+// token from a per-domain bucket, pauses between actions are jittered instead of
+// fixed, page content is waited for rather than slept for, and a source that starts
+// failing or hitting checkpoints is slowed down until it recovers.
+//
+// Every source has its own pacer, configured from
+//
+//	PACING_<SOURCE>_RATE          navigations per minute and domain, default 6
+//	PACING_<SOURCE>_BURST         navigations allowed back to back, default 2
+//	PACING_<SOURCE>_MIN_DELAY     shortest pause between actions, default 1.5s
+//	PACING_<SOURCE>_MAX_DELAY     longest pause between actions, default 4s
+//	PACING_<SOURCE>_WAIT_TIMEOUT  how long to wait for page content, default 15s
+//	PACING_<SOURCE>_MAX_SLOWDOWN  cap on the adaptive slowdown factor, default 8
+//
+// where <SOURCE> is LINKEDIN or XING.
+package pacing
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Config is the pacing of one source
+type Config struct {
+	Rate        float64 // navigations per minute and domain
+	Burst       int
+	MinDelay    time.Duration
+	MaxDelay    time.Duration
+	WaitTimeout time.Duration
+	MaxSlowdown float64
+}
+
+// DefaultConfig is a cautious pace that a logged-in human could plausibly keep up
+func DefaultConfig() Config {
+	return Config{Rate: 6, Burst: 2, MinDelay: 1500 * time.Millisecond, MaxDelay: 4 * time.Second, WaitTimeout: 15 * time.Second, MaxSlowdown: 8}
+}
+
+// ConfigFromEnv reads the PACING_<SOURCE>_* variables over DefaultConfig
+func ConfigFromEnv(source string) Config {
+	cfg := DefaultConfig()
+	prefix := "PACING_" + strings.ToUpper(source) + "_"
+
+	if raw := os.Getenv(prefix + "RATE"); raw != "" {
+		if v, err := strconv.ParseFloat(raw, 64); err == nil && v > 0 {
+			cfg.Rate = v
+		} else {
+			log.Printf("⚠️ Ignoring invalid %sRATE=%q", prefix, raw)
+		}
+	}
+	if raw := os.Getenv(prefix + "BURST"); raw != "" {
+		if v, err := strconv.Atoi(raw); err == nil && v > 0 {
+			cfg.Burst = v
+		} else {
+			log.Printf("⚠️ Ignoring invalid %sBURST=%q", prefix, raw)
+		}
+	}
+	if raw := os.Getenv(prefix + "MAX_SLOWDOWN"); raw != "" {
+		if v, err := strconv.ParseFloat(raw, 64); err == nil && v >= 1 {
+			cfg.MaxSlowdown = v
+		} else {
+			log.Printf("⚠️ Ignoring invalid %sMAX_SLOWDOWN=%q", prefix, raw)
+		}
+	}
+	for name, d := range map[string]*time.Duration{
+		"MIN_DELAY": &cfg.MinDelay, "MAX_DELAY": &cfg.MaxDelay, "WAIT_TIMEOUT": &cfg.WaitTimeout,
+	} {
+		if raw := os.Getenv(prefix + name); raw != "" {
+			if v, err := time.ParseDuration(raw); err == nil && v >= 0 {
+				*d = v
+			} else {
+				log.Printf("⚠️ Ignoring invalid %s%s=%q", prefix, name, raw)
+			}
+		}
+	}
+	if cfg.MaxDelay < cfg.MinDelay {
+		cfg.MaxDelay = cfg.MinDelay
+	}
+	return cfg
+}
+
+// Outcome is what came of one page a scraper worked on
+type Outcome int
+
+const (
+	Success Outcome = iota
+	Failure         // the page did not have what the scraper expected
+	Blocked         // checkpoint, authwall or rate limit
+)
+
+// window is how many recent outcomes the failure rate is taken over
+const window = 20
+
+// Pacer paces one source
+type Pacer struct {
+	name string
+	cfg  Config
+
+	mu       sync.Mutex
+	buckets  map[string]*bucket
+	recent   []Outcome
+	slowdown float64
+	rand     *rand.Rand
+
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func New(name string, cfg Config) *Pacer {
+	if cfg.Rate <= 0 {
+		cfg.Rate = DefaultConfig().Rate
+	}
+	if cfg.Burst <= 0 {
+		cfg.Burst = 1
+	}
+	if cfg.MaxSlowdown < 1 {
+		cfg.MaxSlowdown = 1
+	}
+	return &Pacer{
+		name: name, cfg: cfg, buckets: make(map[string]*bucket), slowdown: 1,
+		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+		now:  time.Now, sleep: sleep,
+	}
+}
+
+var (
+	pacersMu sync.Mutex
+	pacers   = map[string]*Pacer{}
+)
+
+// For returns the process-wide pacer of a source, so the slowdown learned in one run
+// carries over to the next
+func For(source string) *Pacer {
+	pacersMu.Lock()
+	defer pacersMu.Unlock()
+	key := strings.ToLower(source)
+	if p, ok := pacers[key]; ok {
+		return p
+	}
+	p := New(source, ConfigFromEnv(source))
+	pacers[key] = p
+	return p
+}
+
+// Reset drops the process-wide pacer of a source, so the next For reads its
+// configuration from the environment again
+func Reset(source string) {
+	pacersMu.Lock()
+	defer pacersMu.Unlock()
+	delete(pacers, strings.ToLower(source))
+}
+
+// Config returns the pacer's configuration
+func (p *Pacer) Config() Config { return p.cfg }
+
+// Slowdown is the current factor applied to the rate and the delays
+func (p *Pacer) Slowdown() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.slowdown
+}
+
+// Wait blocks until the domain of rawURL has a navigation token
+func (p *Pacer) Wait(ctx context.Context, rawURL string) error {
+	return p.sleep(ctx, p.reserve(domain(rawURL)))
+}
+
+// reserve takes a token from the domain's bucket and returns how long to wait for it.
+// Tokens may go negative, so concurrent callers queue up behind each other.
+func (p *Pacer) reserve(host string) time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	perSecond := p.cfg.Rate / 60 / p.slowdown
+	b, ok := p.buckets[host]
+	if !ok {
+		b = &bucket{tokens: float64(p.cfg.Burst), last: now}
+		p.buckets[host] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * perSecond
+	if b.tokens > float64(p.cfg.Burst) {
+		b.tokens = float64(p.cfg.Burst)
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / perSecond * float64(time.Second))
+}
+
+// Delay is a jittered pause between MinDelay and MaxDelay, stretched by the slowdown
+func (p *Pacer) Delay() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	d := p.cfg.MinDelay
+	if spread := p.cfg.MaxDelay - p.cfg.MinDelay; spread > 0 {
+		d += time.Duration(p.rand.Int63n(int64(spread)))
+	}
+	return time.Duration(float64(d) * p.slowdown)
+}
+
+// Record feeds the outcome of a page into the slowdown. A blocked page doubles it;
+// a failure rate above 30% over the recent pages raises it by half, and below 10% it
+// eases back towards normal pace.
+func (p *Pacer) Record(o Outcome) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.recent = append(p.recent, o)
+	if len(p.recent) > window {
+		p.recent = p.recent[len(p.recent)-window:]
+	}
+	bad := 0
+	for _, r := range p.recent {
+		if r != Success {
+			bad++
+		}
+	}
+	rate := float64(bad) / float64(len(p.recent))
+
+	before := p.slowdown
+	switch {
+	case o == Blocked:
+		p.slowdown *= 2
+	case len(p.recent) >= 5 && rate > 0.3:
+		p.slowdown *= 1.5
+	case rate < 0.1:
+		p.slowdown *= 0.9
+	}
+	if p.slowdown > p.cfg.MaxSlowdown {
+		p.slowdown = p.cfg.MaxSlowdown
+	}
+	if p.slowdown < 1 {
+		p.slowdown = 1
+	}
+	if p.slowdown > before {
+		log.Printf("🐢 Slowing %s down to %.1fx (%.0f%% of the last %d pages failed)", p.name, p.slowdown, rate*100, len(p.recent))
+	}
+}
+
+// Navigate is chromedp.Navigate behind the domain's token bucket
+func (p *Pacer) Navigate(rawURL string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if err := p.Wait(ctx, rawURL); err != nil {
+			return err
+		}
+		return chromedp.Navigate(rawURL).Do(ctx)
+	})
+}
+
+// Think pauses for a jittered Delay, the way a person reads before acting
+func (p *Pacer) Think() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		return p.sleep(ctx, p.Delay())
+	})
+}
+
+// WaitVisible waits up to WaitTimeout for sel to be visible, instead of sleeping for
+// a fixed time and hoping it rendered
+func (p *Pacer) WaitVisible(sel string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, p.cfg.WaitTimeout)
+		defer cancel()
+		if err := chromedp.WaitVisible(sel, chromedp.ByQuery).Do(ctx); err != nil {
+			return fmt.Errorf("waiting for %s: %w", sel, err)
+		}
+		return nil
+	})
+}
+
+// ClickForMore clicks a "show more" button under the results matching sel and waits
+// up to WaitTimeout for more of them. The next page is fetched from the site, so the
+// click takes a navigation token first. grew is false when there is no button or no
+// new results showed up.
+func (p *Pacer) ClickForMore(button, sel string, grew *bool) chromedp.Action {
+	count := fmt.Sprintf(`document.querySelectorAll(%q).length`, sel)
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		*grew = false
+		var seen int
+		var present bool
+		if err := chromedp.Evaluate(count, &seen).Do(ctx); err != nil {
+			return err
+		}
+		if err := chromedp.Evaluate(fmt.Sprintf(`document.querySelector(%q) !== null`, button), &present).Do(ctx); err != nil {
+			return err
+		}
+		if !present {
+			return nil
+		}
+
+		var page string
+		if err := chromedp.Location(&page).Do(ctx); err != nil {
+			return err
+		}
+		if err := p.Wait(ctx, page); err != nil {
+			return err
+		}
+		if err := p.sleep(ctx, p.Delay()); err != nil {
+			return err
+		}
+		clickCtx, cancel := context.WithTimeout(ctx, p.cfg.WaitTimeout)
+		defer cancel()
+		if err := chromedp.Click(button, chromedp.NodeVisible, chromedp.ByQuery).Do(clickCtx); err != nil {
+			return fmt.Errorf("clicking %s: %w", button, err)
+		}
+
+		n, err := p.waitForMore(ctx, count, seen, p.cfg.WaitTimeout)
+		if err != nil {
+			return err
+		}
+		*grew = n > seen
+		return nil
+	})
+}
+
+// waitForMore polls the count expression for up to wait and returns as soon as it
+// exceeds seen
+func (p *Pacer) waitForMore(ctx context.Context, count string, seen int, wait time.Duration) (int, error) {
+	deadline := p.now().Add(wait)
+	for {
+		var n int
+		if err := chromedp.Evaluate(count, &n).Do(ctx); err != nil {
+			return 0, err
+		}
+		if n > seen || !p.now().Before(deadline) {
+			return n, nil
+		}
+		if err := p.sleep(ctx, 250*time.Millisecond); err != nil {
+			return 0, err
+		}
+	}
+}
+
+func domain(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return rawURL
+	}
+	return strings.TrimPrefix(u.Hostname(), "www.")
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
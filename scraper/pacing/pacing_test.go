@@ -0,0 +1,126 @@
+package pacing
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// fakeClock makes the pacer's sleeps advance time instead of blocking
+type fakeClock struct{ t time.Time }
+
+func newTestPacer(cfg Config) (*Pacer, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)}
+	p := New("LinkedIn", cfg)
+	p.now = func() time.Time { return clock.t }
+	p.sleep = func(ctx context.Context, d time.Duration) error {
+		clock.t = clock.t.Add(d)
+		return nil
+	}
+	return p, clock
+}
+
+func TestTokenBucketPerDomain(t *testing.T) {
+	p, clock := newTestPacer(Config{Rate: 6, Burst: 2, MaxSlowdown: 8})
+	start := clock.t
+	ctx := context.Background()
+
+	// The burst goes through at once, then one navigation every 10s
+	for i := 0; i < 2; i++ {
+		p.Wait(ctx, "https://www.linkedin.com/jobs/search?keywords=go")
+	}
+	if waited := clock.t.Sub(start); waited != 0 {
+		t.Errorf("burst waited %s", waited)
+	}
+	p.Wait(ctx, "https://linkedin.com/jobs/view/1")
+	if waited := clock.t.Sub(start); waited != 10*time.Second {
+		t.Errorf("third navigation waited %s, want 10s", waited)
+	}
+
+	// Another domain has its own bucket
+	before := clock.t
+	p.Wait(ctx, "https://www.xing.com/jobs/search")
+	if clock.t != before {
+		t.Errorf("xing.com waited for linkedin.com's bucket")
+	}
+}
+
+func TestDelayIsJitteredAndSlowedDown(t *testing.T) {
+	p, _ := newTestPacer(Config{Rate: 6, Burst: 1, MinDelay: time.Second, MaxDelay: 3 * time.Second, MaxSlowdown: 8})
+	seen := map[time.Duration]bool{}
+	for i := 0; i < 50; i++ {
+		d := p.Delay()
+		if d < time.Second || d >= 3*time.Second {
+			t.Fatalf("delay %s outside [1s, 3s)", d)
+		}
+		seen[d] = true
+	}
+	if len(seen) < 10 {
+		t.Errorf("only %d distinct delays in 50; not jittered", len(seen))
+	}
+
+	p.Record(Blocked)
+	if d := p.Delay(); d < 2*time.Second {
+		t.Errorf("delay %s after a checkpoint, want at least twice MinDelay", d)
+	}
+}
+
+func TestSlowdownFollowsTheFailureRate(t *testing.T) {
+	p, _ := newTestPacer(Config{Rate: 6, Burst: 1, MaxSlowdown: 4})
+
+	for i := 0; i < 4; i++ {
+		p.Record(Success)
+	}
+	for i := 0; i < 4; i++ {
+		p.Record(Failure)
+	}
+	if p.Slowdown() <= 1 {
+		t.Fatalf("slowdown %.2f after 50%% failures, want above 1", p.Slowdown())
+	}
+
+	p.Record(Blocked)
+	p.Record(Blocked)
+	p.Record(Blocked)
+	if p.Slowdown() != 4 {
+		t.Errorf("slowdown %.2f, want the 4x cap", p.Slowdown())
+	}
+
+	// A clean window eases back to normal pace
+	for i := 0; i < 60; i++ {
+		p.Record(Success)
+	}
+	if p.Slowdown() != 1 {
+		t.Errorf("slowdown %.2f after recovering, want 1", p.Slowdown())
+	}
+}
+
+func TestSlowdownStretchesTheBucket(t *testing.T) {
+	p, clock := newTestPacer(Config{Rate: 6, Burst: 1, MaxSlowdown: 8})
+	p.Record(Blocked) // 2x
+	ctx := context.Background()
+
+	p.Wait(ctx, "https://www.linkedin.com/a")
+	start := clock.t
+	p.Wait(ctx, "https://www.linkedin.com/b")
+	if waited := clock.t.Sub(start); waited != 20*time.Second {
+		t.Errorf("waited %s at 2x slowdown, want 20s", waited)
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("PACING_XING_RATE", "12")
+	t.Setenv("PACING_XING_MIN_DELAY", "500ms")
+	t.Setenv("PACING_XING_MAX_DELAY", "100ms")
+	t.Setenv("PACING_XING_BURST", "nope")
+
+	cfg := ConfigFromEnv("Xing")
+	if cfg.Rate != 12 || cfg.MinDelay != 500*time.Millisecond || cfg.Burst != DefaultConfig().Burst {
+		t.Errorf("cfg = %+v", cfg)
+	}
+	if cfg.MaxDelay != cfg.MinDelay {
+		t.Errorf("MaxDelay %s below MinDelay %s was not raised", cfg.MaxDelay, cfg.MinDelay)
+	}
+	if other := ConfigFromEnv("LinkedIn"); other.Rate != DefaultConfig().Rate {
+		t.Errorf("Xing settings leaked into LinkedIn: %+v", other)
+	}
+}
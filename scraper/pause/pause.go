@@ -0,0 +1,286 @@
+// Package pause stops scrapes that run into a wall. When a site answers with an
+// authwall, a login redirect, a security checkpoint or a rate limit, the scraper
+// records a pause for the source instead of failing every remaining job, and an
+// event is logged and sent to the alert webhook.
+//
+// A rate-limit pause lasts for a cooldown; the others last until a session check
+// passes again, usually after a visible login with cmd/login.
+//
+//	SCRAPE_RATE_LIMIT_COOLDOWN  default 30m
+//	ALERT_WEBHOOK_URL           optional; receives every event as JSON
+//	ALERT_WEBHOOK_SECRET        optional; signs the body like the upload webhook
+package pause
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"job_scraper/scraper/browser"
+	"job_scraper/scraper/sink"
+)
+
+// Event kinds
+const (
+	KindPaused  = "paused"
+	KindResumed = "resumed"
+)
+
+// Pause is an active pause of one source
+type Pause struct {
+	Source      string `json:"source"`
+	State       string `json:"state"`
+	URL         string `json:"url,omitempty"`
+	JobID       string `json:"job_id,omitempty"`
+	PausedAt    string `json:"paused_at"`
+	ResumeAfter string `json:"resume_after,omitempty"` // empty: until a session check passes
+}
+
+// Event is one pause or resume, as stored in scrape_events and sent to the alert webhook
+type Event struct {
+	ID        int64  `json:"id"`
+	Source    string `json:"source"`
+	Kind      string `json:"kind"`
+	State     string `json:"state,omitempty"`
+	URL       string `json:"url,omitempty"`
+	JobID     string `json:"job_id,omitempty"`
+	Message   string `json:"message"`
+	CreatedAt string `json:"created_at"`
+}
+
+// Error is returned by Check while a source cools down from a rate limit
+type Error struct {
+	Pause Pause
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s scraping is paused after a %s page at %s until %s UTC",
+		e.Pause.Source, e.Pause.State, e.Pause.URL, e.Pause.ResumeAfter)
+}
+
+// Cooldown is how long a rate-limit pause lasts
+func Cooldown() time.Duration {
+	if raw := os.Getenv("SCRAPE_RATE_LIMIT_COOLDOWN"); raw != "" {
+		if d, err := time.ParseDuration(raw); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("⚠️ Ignoring invalid SCRAPE_RATE_LIMIT_COOLDOWN=%q", raw)
+	}
+	return 30 * time.Minute
+}
+
+// Block pauses a source after a blocking page; jobID is the job being opened, if any
+func Block(db *sql.DB, source, state, url, jobID string) (Pause, error) {
+	var resumeAfter interface{}
+	// Rate limits wear off; the other walls need a human
+	if state == string(browser.PageRateLimited) {
+		resumeAfter = time.Now().UTC().Add(Cooldown()).Format("2006-01-02 15:04:05")
+	}
+	_, err := db.Exec(`
+		INSERT INTO scrape_pauses (source, state, url, job_id, paused_at, resume_after)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP, ?)
+		ON CONFLICT (source) DO UPDATE SET
+			state = excluded.state,
+			url = excluded.url,
+			job_id = excluded.job_id,
+			paused_at = excluded.paused_at,
+			resume_after = excluded.resume_after`,
+		source, state, url, jobID, resumeAfter)
+	if err != nil {
+		return Pause{}, fmt.Errorf("failed to pause %s: %v", source, err)
+	}
+
+	p, err := get(db, source)
+	if err != nil || p == nil {
+		return Pause{}, fmt.Errorf("failed to read the %s pause: %v", source, err)
+	}
+	until := "a session check passes"
+	if p.ResumeAfter != "" {
+		until = p.ResumeAfter + " UTC"
+	}
+	log.Printf("⏸️ %s scraping paused: %s page at %s; resumes when %s", source, state, url, until)
+	Record(db, Event{Source: source, Kind: KindPaused, State: state, URL: url, JobID: jobID,
+		Message: fmt.Sprintf("%s showed a %s page; scraping is paused until %s", source, state, until)})
+	return *p, nil
+}
+
+// Check is called before a run. It refuses with an *Error while a rate-limit pause
+// cools down and lifts one whose cooldown is over. Other pauses do not stop a run
+// here: the run's session check decides, and Resume lifts them once it passes.
+func Check(db *sql.DB, source string) error {
+	p, err := get(db, source)
+	if err != nil {
+		return fmt.Errorf("failed to read the %s pause: %v", source, err)
+	}
+	if p == nil || p.ResumeAfter == "" {
+		return nil
+	}
+	if p.ResumeAfter > time.Now().UTC().Format("2006-01-02 15:04:05") {
+		return &Error{Pause: *p}
+	}
+	_, err = Resume(db, source, "the rate-limit cooldown is over")
+	return err
+}
+
+// Resume lifts the pause of a source; it reports whether there was one
+func Resume(db *sql.DB, source, reason string) (bool, error) {
+	res, err := db.Exec(`DELETE FROM scrape_pauses WHERE source = ?`, source)
+	if err != nil {
+		return false, fmt.Errorf("failed to resume %s: %v", source, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	log.Printf("▶️ %s scraping resumed: %s", source, reason)
+	Record(db, Event{Source: source, Kind: KindResumed, Message: fmt.Sprintf("%s scraping resumed: %s", source, reason)})
+	return true, nil
+}
+
+// Active lists the current pauses
+func Active(db *sql.DB) ([]Pause, error) {
+	rows, err := db.Query(`
+		SELECT source, state, COALESCE(url, ''), COALESCE(job_id, ''), paused_at, COALESCE(resume_after, '')
+		FROM scrape_pauses ORDER BY source`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pauses := []Pause{}
+	for rows.Next() {
+		var p Pause
+		if err := rows.Scan(&p.Source, &p.State, &p.URL, &p.JobID, &p.PausedAt, &p.ResumeAfter); err != nil {
+			return nil, err
+		}
+		pauses = append(pauses, p)
+	}
+	return pauses, rows.Err()
+}
+
+// Events returns the newest events first
+func Events(db *sql.DB, limit int) ([]Event, error) {
+	rows, err := db.Query(`
+		SELECT id, source, kind, COALESCE(state, ''), COALESCE(url, ''), COALESCE(job_id, ''), COALESCE(message, ''), created_at
+		FROM scrape_events ORDER BY id DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []Event{}
+	for rows.Next() {
+		var e Event
+		if err := rows.Scan(&e.ID, &e.Source, &e.Kind, &e.State, &e.URL, &e.JobID, &e.Message, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func get(db *sql.DB, source string) (*Pause, error) {
+	var p Pause
+	err := db.QueryRow(`
+		SELECT source, state, COALESCE(url, ''), COALESCE(job_id, ''), paused_at, COALESCE(resume_after, '')
+		FROM scrape_pauses WHERE source = ?`, source).
+		Scan(&p.Source, &p.State, &p.URL, &p.JobID, &p.PausedAt, &p.ResumeAfter)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Record stores an event and sends it to the alert webhook. Both are best effort: a
+// pause must take effect even when the alert cannot be delivered.
+func Record(db *sql.DB, e Event) {
+	err := db.QueryRow(`
+		INSERT INTO scrape_events (source, kind, state, url, job_id, message)
+		VALUES (?, ?, ?, ?, ?, ?) RETURNING id, created_at`,
+		e.Source, e.Kind, e.State, e.URL, e.JobID, e.Message).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		log.Printf("❌ Failed to record the %s event for %s: %v", e.Kind, e.Source, err)
+	}
+	if err := alert(e); err != nil {
+		log.Printf("❌ Failed to send the %s alert for %s: %v", e.Kind, e.Source, err)
+	}
+}
+
+func alert(e Event) error {
+	url := os.Getenv("ALERT_WEBHOOK_URL")
+	if url == "" {
+		return nil
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret := os.Getenv("ALERT_WEBHOOK_SECRET"); secret != "" {
+		req.Header.Set(sink.SignatureHeader, sink.Sign([]byte(secret), body))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("alert webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// Start gates a run that needs a session. It refuses while a rate-limit pause cools
+// down, then runs the session check in ctx: a failed check pauses the source and
+// returns the *browser.SessionError, a passing one lifts any pause.
+func Start(ctx context.Context, db *sql.DB, m *browser.Manager, site browser.Site) error {
+	if err := Check(db, site.Name); err != nil {
+		return err
+	}
+	err := m.EnsureSession(ctx, site)
+	var se *browser.SessionError
+	if errors.As(err, &se) {
+		if _, perr := Block(db, site.Name, string(se.State), se.URL, ""); perr != nil {
+			log.Printf("❌ %v", perr)
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	_, err = Resume(db, site.Name, "the session check passed")
+	return err
+}
+
+// BlockPage pauses the source of a blocking page met while opening jobID
+func BlockPage(db *sql.DB, pe *browser.PageError, jobID string) error {
+	_, err := Block(db, pe.Site, string(pe.State), pe.URL, jobID)
+	return err
+}
+
+// IsPaused reports whether err means the source cannot be scraped right now, as
+// opposed to a failure of the scraper itself
+func IsPaused(err error) bool {
+	var pe *Error
+	if errors.As(err, &pe) || browser.IsSessionError(err) {
+		return true
+	}
+	page, ok := browser.AsPageError(err)
+	return ok && page.State.Blocking()
+}
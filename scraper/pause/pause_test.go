@@ -0,0 +1,124 @@
+package pause
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"job_scraper/config"
+	"job_scraper/scraper/browser"
+	"job_scraper/scraper/sink"
+)
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := config.InitializeDatabaseAt(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestRateLimitPauseCoolsDown(t *testing.T) {
+	db := newTestDB(t)
+
+	p, err := Block(db, "LinkedIn", string(browser.PageRateLimited), "https://www.linkedin.com/jobs/view/1/", "job-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ResumeAfter == "" {
+		t.Fatal("rate-limit pause has no cooldown")
+	}
+	err = Check(db, "LinkedIn")
+	if _, ok := err.(*Error); !ok || !IsPaused(err) {
+		t.Fatalf("Check during the cooldown = %v, want a pause error", err)
+	}
+	if err := Check(db, "Xing"); err != nil {
+		t.Errorf("pausing LinkedIn paused Xing: %v", err)
+	}
+
+	// Once the cooldown is over the next run resumes the source
+	if _, err := db.Exec(`UPDATE scrape_pauses SET resume_after = '2025-01-01 00:00:00'`); err != nil {
+		t.Fatal(err)
+	}
+	if err := Check(db, "LinkedIn"); err != nil {
+		t.Fatalf("Check after the cooldown = %v", err)
+	}
+	if pauses, _ := Active(db); len(pauses) != 0 {
+		t.Errorf("pauses after the cooldown: %+v", pauses)
+	}
+
+	events, err := Events(db, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Kind != KindResumed || events[1].Kind != KindPaused || events[1].JobID != "job-1" {
+		t.Errorf("events = %+v", events)
+	}
+}
+
+func TestSessionPauseWaitsForResume(t *testing.T) {
+	db := newTestDB(t)
+	if _, err := Block(db, "Xing", string(browser.PageCheckpoint), "https://login.xing.com/verify", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// The session check of the run decides, so Check lets it through
+	if err := Check(db, "Xing"); err != nil {
+		t.Errorf("Check = %v", err)
+	}
+	if pauses, _ := Active(db); len(pauses) != 1 || pauses[0].ResumeAfter != "" {
+		t.Fatalf("pauses = %+v", pauses)
+	}
+	if resumed, err := Resume(db, "Xing", "the session check passed"); !resumed || err != nil {
+		t.Errorf("Resume = %v, %v", resumed, err)
+	}
+	if resumed, _ := Resume(db, "Xing", "again"); resumed {
+		t.Error("resumed a source that was not paused")
+	}
+}
+
+func TestEventsAreSentToTheAlertWebhook(t *testing.T) {
+	db := newTestDB(t)
+	var got []Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if sig := r.Header.Get(sink.SignatureHeader); sig != sink.Sign([]byte("s3cret"), body) {
+			t.Errorf("bad signature %q", sig)
+		}
+		var e Event
+		json.Unmarshal(body, &e)
+		got = append(got, e)
+	}))
+	defer srv.Close()
+	t.Setenv("ALERT_WEBHOOK_URL", srv.URL)
+	t.Setenv("ALERT_WEBHOOK_SECRET", "s3cret")
+
+	page := &browser.PageError{Site: "LinkedIn", State: browser.PageAuthwall, URL: "https://www.linkedin.com/authwall"}
+	if err := BlockPage(db, page, "job-7"); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Kind != KindPaused || got[0].State != "authwall" || got[0].JobID != "job-7" || got[0].ID == 0 {
+		t.Errorf("alerts = %+v", got)
+	}
+}
+
+func TestIsPaused(t *testing.T) {
+	cases := map[error]bool{
+		&browser.PageError{State: browser.PageCheckpoint}:           true,
+		&browser.PageError{State: browser.PageExpired}:              false,
+		&browser.SessionError{State: browser.SessionLoggedOut}:      true,
+		&Error{Pause: Pause{Source: "Xing", State: "rate_limited"}}: true,
+		io.EOF: false,
+	}
+	for err, want := range cases {
+		if got := IsPaused(err); got != want {
+			t.Errorf("IsPaused(%T %v) = %v, want %v", err, err, got, want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package rotation
+
+import (
+	"context"
+	"log"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+)
+
+// NewTab opens a tab for id on the browser of parent. With a proxy the tab gets its
+// own browser context: its own proxy setting and an empty cookie jar, disposed of
+// with the tab. Without one it shares the profile's context, as listings always did.
+// Either way the tab runs with the identity's user agent and viewport.
+func (id Identity) NewTab(parent context.Context) (context.Context, context.CancelFunc, error) {
+	// A browser context can only be created on a connected browser
+	if err := chromedp.Run(parent); err != nil {
+		return nil, nil, err
+	}
+
+	var opts []chromedp.ContextOption
+	if id.Proxy != nil {
+		server := id.Proxy.Server()
+		opts = append(opts, chromedp.WithNewBrowserContext(func(p *target.CreateBrowserContextParams) *target.CreateBrowserContextParams {
+			return p.WithProxyServer(server)
+		}))
+	}
+	ctx, cancel := chromedp.NewContext(parent, opts...)
+
+	actions := chromedp.Tasks{id.Apply()}
+	if id.Proxy != nil && id.Proxy.URL.User != nil {
+		answerProxyAuth(ctx, id.Proxy)
+		actions = append(chromedp.Tasks{fetch.Enable().WithHandleAuthRequests(true)}, actions...)
+	}
+	if err := chromedp.Run(ctx, actions...); err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	return ctx, cancel, nil
+}
+
+// Apply sets the identity's user agent, language and viewport on the current tab
+func (id Identity) Apply() chromedp.Action {
+	ua := emulation.SetUserAgentOverride(id.Profile.UserAgent)
+	if id.Profile.AcceptLanguage != "" {
+		ua = ua.WithAcceptLanguage(id.Profile.AcceptLanguage)
+	}
+	return chromedp.Tasks{ua, chromedp.EmulateViewport(id.Profile.Width, id.Profile.Height)}
+}
+
+// answerProxyAuth gives the proxy's credentials when it asks for them. With auth
+// handling on, every request pauses first and has to be let through.
+func answerProxyAuth(ctx context.Context, p *Proxy) {
+	password, _ := p.URL.User.Password()
+	creds := &fetch.AuthChallengeResponse{
+		Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+		Username: p.URL.User.Username(),
+		Password: password,
+	}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		var action chromedp.Action
+		switch ev := ev.(type) {
+		case *fetch.EventRequestPaused:
+			action = fetch.ContinueRequest(ev.RequestID)
+		case *fetch.EventAuthRequired:
+			if ev.AuthChallenge.Source == fetch.AuthChallengeSourceProxy {
+				action = fetch.ContinueWithAuth(ev.RequestID, creds)
+			} else {
+				action = fetch.ContinueWithAuth(ev.RequestID, &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseDefault})
+			}
+		default:
+			return
+		}
+		// Listeners must not block the event loop
+		go func() {
+			if err := chromedp.Run(ctx, action); err != nil && ctx.Err() == nil {
+				log.Printf("⚠️ Proxy auth for %s: %v", p, err)
+			}
+		}()
+	})
+}
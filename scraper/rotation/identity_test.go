@@ -0,0 +1,204 @@
+package rotation
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+
+	"job_scraper/scraper/browser"
+)
+
+// testBrowser starts headless Chromium like fixtures.Browser, which this package
+// cannot use since fixtures imports it. Certificate errors are ignored so tabs can
+// reach the TLS test server through a proxy tunnel.
+func testBrowser(t *testing.T) context.Context {
+	t.Helper()
+	chromium, err := browser.FindChromium(os.Getenv("CHROMIUM_PATH"))
+	if err != nil {
+		t.Skipf("proxy tests need Chromium: %v", err)
+	}
+
+	m := browser.NewManager(browser.Config{
+		ExecPath:     chromium,
+		ProfilesDir:  t.TempDir(),
+		StartTimeout: 30 * time.Second,
+		Headless:     true,
+		Flags:        []string{"--no-sandbox", "--disable-gpu", "--ignore-certificate-errors"},
+	})
+	t.Cleanup(m.Shutdown)
+	b, err := m.Acquire(context.Background(), "rotation", browser.Options{})
+	if err != nil {
+		t.Fatalf("starting Chromium: %v", err)
+	}
+	t.Cleanup(b.Release)
+
+	allocatorCtx, cancelAllocator := chromedp.NewRemoteAllocator(context.Background(), b.DebugURL())
+	t.Cleanup(cancelAllocator)
+	ctx, cancel := chromedp.NewContext(allocatorCtx)
+	t.Cleanup(cancel)
+	if err := chromedp.Run(ctx); err != nil {
+		t.Fatalf("connecting to Chromium: %v", err)
+	}
+	return ctx
+}
+
+// recordingProxy is a forward proxy that wants Basic credentials. It answers plain
+// GETs itself with a page titled after the path, tunnels every CONNECT to tlsOrigin,
+// and remembers each request it let through.
+type recordingProxy struct {
+	*httptest.Server
+	user, password string
+	tlsOrigin      *httptest.Server
+
+	mu         sync.Mutex
+	served     []string // "METHOD target as user, user agent"
+	challenges int
+}
+
+func newRecordingProxy(t *testing.T, user, password string, tlsOrigin *httptest.Server) *recordingProxy {
+	p := &recordingProxy{user: user, password: password, tlsOrigin: tlsOrigin}
+	p.Server = httptest.NewServer(http.HandlerFunc(p.serve))
+	t.Cleanup(p.Close)
+	return p
+}
+
+func (p *recordingProxy) serve(w http.ResponseWriter, r *http.Request) {
+	// Proxy-Authorization has the layout of Authorization, so BasicAuth can read it
+	creds := &http.Request{Header: http.Header{"Authorization": r.Header["Proxy-Authorization"]}}
+	user, password, ok := creds.BasicAuth()
+	p.mu.Lock()
+	if !ok || user != p.user || password != p.password {
+		p.challenges++
+		p.mu.Unlock()
+		w.Header().Set("Proxy-Authenticate", `Basic realm="rotation test"`)
+		w.WriteHeader(http.StatusProxyAuthRequired)
+		return
+	}
+	p.served = append(p.served, fmt.Sprintf("%s %s as %s, %s", r.Method, r.RequestURI, user, r.UserAgent()))
+	p.mu.Unlock()
+
+	if r.Method != http.MethodConnect {
+		fmt.Fprintf(w, "<html><head><title>%s</title></head></html>", path.Base(r.URL.Path))
+		return
+	}
+	upstream, err := net.Dial("tcp", p.tlsOrigin.Listener.Addr().String())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	conn, buf, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		upstream.Close()
+		return
+	}
+	io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+	go func() {
+		io.Copy(upstream, buf)
+		upstream.Close()
+	}()
+	io.Copy(conn, upstream)
+	conn.Close()
+}
+
+func (p *recordingProxy) requests() ([]string, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.served), p.challenges
+}
+
+func TestScrapeThroughRealProxies(t *testing.T) {
+	ctx := testBrowser(t)
+
+	var originMu sync.Mutex
+	var originSeen []string
+	tlsOrigin := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		originMu.Lock()
+		originSeen = append(originSeen, r.URL.Path+", "+r.UserAgent())
+		originMu.Unlock()
+		fmt.Fprintf(w, "<html><head><title>%s</title></head></html>", path.Base(r.URL.Path))
+	}))
+	t.Cleanup(tlsOrigin.Close)
+
+	a := newRecordingProxy(t, "alice", "secret-a", tlsOrigin)
+	b := newRecordingProxy(t, "bob", "secret-b", tlsOrigin)
+	// Nothing listens on the third proxy any more
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dead := l.Addr().String()
+	l.Close()
+
+	proxies := mustProxies(t, fmt.Sprintf("http://alice:secret-a@%s,http://bob:secret-b@%s,http://%s",
+		a.Listener.Addr(), b.Listener.Addr(), dead))
+	r := New(proxies, []Profile{
+		{UserAgent: "RotationTest/1", Width: 1280, Height: 720},
+		{UserAgent: "RotationTest/2", Width: 1024, Height: 768},
+	}, 1, time.Hour)
+
+	// The pages live on a made-up host: Chromium never sends loopback addresses
+	// through a proxy
+	visit := func(url, wantTitle string) {
+		t.Helper()
+		scrapeCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+		err := r.Scrape(scrapeCtx, func(tab context.Context) error {
+			var title string
+			if err := chromedp.Run(tab, chromedp.Navigate(url), chromedp.Title(&title)); err != nil {
+				return err
+			}
+			if title != wantTitle {
+				return fmt.Errorf("title = %q, want %q", title, wantTitle)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("scraping %s: %v", url, err)
+		}
+	}
+
+	// The first scrape goes through alice's proxy, answering its challenge
+	visit("http://jobs.test/first", "first")
+	served, challenges := a.requests()
+	if want := "GET http://jobs.test/first as alice, RotationTest/1"; !slices.Contains(served, want) {
+		t.Errorf("proxy a served %q, want %q", served, want)
+	}
+	if challenges == 0 {
+		t.Error("proxy a never asked for credentials")
+	}
+
+	// The next one moves on to bob's, tunnelling to the TLS origin
+	visit("https://jobs.test/second", "second")
+	served, _ = b.requests()
+	if want := "CONNECT jobs.test:443 as bob"; !slices.ContainsFunc(served, func(s string) bool { return strings.HasPrefix(s, want) }) {
+		t.Errorf("proxy b served %q, want %q", served, want)
+	}
+	originMu.Lock()
+	if want := "/second, RotationTest/2"; !slices.Contains(originSeen, want) {
+		t.Errorf("origin saw %q, want %q", originSeen, want)
+	}
+	originMu.Unlock()
+
+	// The third proxy is down: it is retired and the scrape runs again through alice's
+	visit("http://jobs.test/third", "third")
+	served, _ = a.requests()
+	if want := "GET http://jobs.test/third as alice, RotationTest/2"; !slices.Contains(served, want) {
+		t.Errorf("proxy a served %q, want %q", served, want)
+	}
+	status := r.Proxies()
+	if r.Healthy() != 2 || status[2].Healthy || status[2].RetiredUntil == "" {
+		t.Errorf("proxy status = %+v, want the dead proxy retired", status)
+	}
+}
@@ -0,0 +1,352 @@
+// Package rotation spreads listing scrapes over proxies and browser identities. Each
+// scrape of a search page gets the next healthy proxy and the next user-agent and
+// viewport profile, applied to its own browser context or HTTP client. Proxies that
+// keep failing, or that get rate limited, are retired for a while.
+//
+//	SCRAPE_PROXIES             comma-separated http://, https:// or socks5:// proxy URLs;
+//	                           empty scrapes directly. Credentials (user:pass@) work for
+//	                           HTTP proxies; Chromium cannot authenticate to SOCKS5.
+//	SCRAPE_PROXY_MAX_FAILURES  consecutive failures before a proxy is retired, default 3
+//	SCRAPE_PROXY_RETIRE_FOR    how long a retired proxy rests before another try, default 30m
+//	SCRAPE_PROFILES_FILE       JSON list of {"user_agent", "accept_language", "width", "height"};
+//	                           default a built-in set of desktop Chrome profiles
+package rotation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"job_scraper/scraper/browser"
+)
+
+// ErrNoHealthyProxy is returned when every configured proxy is retired
+var ErrNoHealthyProxy = errors.New("rotation: every proxy is retired")
+
+// Profile is a browser identity
+type Profile struct {
+	UserAgent      string `json:"user_agent"`
+	AcceptLanguage string `json:"accept_language"`
+	Width          int64  `json:"width"`
+	Height         int64  `json:"height"`
+}
+
+// DefaultProfiles are common desktop Chrome identities
+var DefaultProfiles = []Profile{
+	{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36", "de-DE,de;q=0.9,en;q=0.8", 1920, 1080},
+	{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36", "en-US,en;q=0.9,de;q=0.8", 1440, 900},
+	{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36", "en-GB,en;q=0.9", 1366, 768},
+	{"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36", "de-DE,de;q=0.9", 1536, 864},
+}
+
+// Proxy is one proxy of the pool
+type Proxy struct {
+	URL *url.URL
+}
+
+// Server is the proxy as Chromium's proxy setting wants it, without credentials
+func (p *Proxy) Server() string {
+	return p.URL.Scheme + "://" + p.URL.Host
+}
+
+// String is the proxy without its password, for logs
+func (p *Proxy) String() string {
+	return p.URL.Redacted()
+}
+
+// ParseProxies parses a comma- or space-separated list of proxy URLs
+func ParseProxies(list string) ([]*Proxy, error) {
+	var proxies []*Proxy
+	for _, raw := range strings.FieldsFunc(list, func(r rune) bool { return r == ',' || r == ' ' || r == '\n' }) {
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("rotation: invalid proxy %q", raw)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("rotation: proxy %s must be http, https or socks5", u.Redacted())
+		}
+		proxies = append(proxies, &Proxy{URL: u})
+	}
+	return proxies, nil
+}
+
+// ProxyStatus is the health of one proxy
+type ProxyStatus struct {
+	Proxy        string `json:"proxy"`
+	Healthy      bool   `json:"healthy"`
+	Failures     int    `json:"failures"` // consecutive, since the last success or retirement
+	Uses         int    `json:"uses"`
+	RetiredUntil string `json:"retired_until,omitempty"` // UTC, while retired
+}
+
+type entry struct {
+	proxy        *Proxy
+	failures     int
+	uses         int
+	retiredUntil time.Time
+}
+
+// Rotator hands out identities round-robin and tracks proxy health
+type Rotator struct {
+	mu          sync.Mutex
+	proxies     []*entry
+	profiles    []Profile
+	nextProxy   int
+	nextProfile int
+	maxFailures int
+	retireFor   time.Duration
+	now         func() time.Time
+	newTab      func(Identity, context.Context) (context.Context, context.CancelFunc, error)
+}
+
+func New(proxies []*Proxy, profiles []Profile, maxFailures int, retireFor time.Duration) *Rotator {
+	if len(profiles) == 0 {
+		profiles = DefaultProfiles
+	}
+	if maxFailures <= 0 {
+		maxFailures = 3
+	}
+	if retireFor <= 0 {
+		retireFor = 30 * time.Minute
+	}
+	r := &Rotator{profiles: profiles, maxFailures: maxFailures, retireFor: retireFor, now: time.Now, newTab: Identity.NewTab}
+	for _, p := range proxies {
+		r.proxies = append(r.proxies, &entry{proxy: p})
+	}
+	return r
+}
+
+// FromEnv builds a rotator from the variables in the package comment
+func FromEnv() (*Rotator, error) {
+	proxies, err := ParseProxies(os.Getenv("SCRAPE_PROXIES"))
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles []Profile
+	if path := os.Getenv("SCRAPE_PROFILES_FILE"); path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("rotation: %v", err)
+		}
+		if err := json.Unmarshal(raw, &profiles); err != nil {
+			return nil, fmt.Errorf("rotation: invalid %s: %v", path, err)
+		}
+		for i, p := range profiles {
+			if p.UserAgent == "" || p.Width <= 0 || p.Height <= 0 {
+				return nil, fmt.Errorf("rotation: profile %d in %s needs user_agent, width and height", i, path)
+			}
+		}
+	}
+
+	maxFailures, _ := strconv.Atoi(os.Getenv("SCRAPE_PROXY_MAX_FAILURES"))
+	retireFor, _ := time.ParseDuration(os.Getenv("SCRAPE_PROXY_RETIRE_FOR"))
+	return New(proxies, profiles, maxFailures, retireFor), nil
+}
+
+var (
+	defaultMu      sync.Mutex
+	defaultRotator *Rotator
+)
+
+// Default is the process-wide rotator; a bad configuration is logged and scrapes go
+// direct with the default profiles, as they did before rotation existed
+func Default() *Rotator {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	if defaultRotator == nil {
+		r, err := FromEnv()
+		if err != nil {
+			log.Printf("⚠️ %v; scraping without proxies", err)
+			r = New(nil, nil, 0, 0)
+		}
+		defaultRotator = r
+	}
+	return defaultRotator
+}
+
+// Reset drops the process-wide rotator, so the next Default reads the environment
+// again. Proxy health learned so far is lost.
+func Reset() {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultRotator = nil
+}
+
+// Identity is what one scrape runs as; a nil Proxy goes direct
+type Identity struct {
+	Proxy   *Proxy
+	Profile Profile
+}
+
+func (id Identity) String() string {
+	via := "direct"
+	if id.Proxy != nil {
+		via = id.Proxy.String()
+	}
+	return fmt.Sprintf("%s, %dx%d", via, id.Profile.Width, id.Profile.Height)
+}
+
+// Next returns the next identity, skipping retired proxies
+func (r *Rotator) Next() (Identity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := Identity{Profile: r.profiles[r.nextProfile%len(r.profiles)]}
+	r.nextProfile++
+	if len(r.proxies) == 0 {
+		return id, nil
+	}
+
+	now := r.now()
+	for i := 0; i < len(r.proxies); i++ {
+		e := r.proxies[(r.nextProxy+i)%len(r.proxies)]
+		if now.Before(e.retiredUntil) {
+			continue
+		}
+		r.nextProxy = (r.nextProxy + i + 1) % len(r.proxies)
+		e.uses++
+		id.Proxy = e.proxy
+		return id, nil
+	}
+	return Identity{}, ErrNoHealthyProxy
+}
+
+// Report feeds the result of a scrape back into the health of its proxy. A rate
+// limit retires the proxy at once; network errors that point at the proxy count
+// towards retirement; anything else says nothing about the proxy.
+func (r *Rotator) Report(id Identity, err error) {
+	if id.Proxy == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var e *entry
+	for _, candidate := range r.proxies {
+		if candidate.proxy == id.Proxy {
+			e = candidate
+		}
+	}
+	if e == nil {
+		return
+	}
+
+	switch {
+	case err == nil:
+		e.failures = 0
+	case RateLimited(err):
+		r.retire(e, "rate limited")
+	case ProxyFault(err):
+		e.failures++
+		if e.failures >= r.maxFailures {
+			r.retire(e, fmt.Sprintf("%d failures in a row", e.failures))
+		}
+	}
+}
+
+func (r *Rotator) retire(e *entry, why string) {
+	e.failures = 0
+	e.retiredUntil = r.now().Add(r.retireFor)
+	log.Printf("🚫 Retired proxy %s until %s: %s", e.proxy, e.retiredUntil.Format(time.Kitchen), why)
+}
+
+// Proxies reports the health of every proxy
+func (r *Rotator) Proxies() []ProxyStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	status := make([]ProxyStatus, len(r.proxies))
+	for i, e := range r.proxies {
+		status[i] = ProxyStatus{Proxy: e.proxy.String(), Healthy: !now.Before(e.retiredUntil), Failures: e.failures, Uses: e.uses}
+		if !status[i].Healthy {
+			status[i].RetiredUntil = e.retiredUntil.UTC().Format("2006-01-02 15:04:05")
+		}
+	}
+	return status
+}
+
+// Healthy counts the proxies that are not retired
+func (r *Rotator) Healthy() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	n := 0
+	for _, e := range r.proxies {
+		if !now.Before(e.retiredUntil) {
+			n++
+		}
+	}
+	return n
+}
+
+// Scrape runs scrape in a tab of its own on the browser of parent, under the next
+// identity. When the proxy fails or is rate limited, it is reported and scrape runs
+// once more through the next one. A rate limit through a proxy only comes back as a
+// page error, which pauses the source, once no healthy proxy is left; until then it
+// is an ordinary failure of this one page.
+func (r *Rotator) Scrape(parent context.Context, scrape func(ctx context.Context) error) error {
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		id, nerr := r.Next()
+		if nerr != nil {
+			if err == nil {
+				err = nerr
+			}
+			return err
+		}
+		ctx, cancel, terr := r.newTab(id, parent)
+		if terr != nil {
+			r.Report(id, terr)
+			return fmt.Errorf("opening a tab via %s: %w", id, terr)
+		}
+		err = scrape(ctx)
+		cancel()
+		r.Report(id, err)
+
+		if id.Proxy == nil || !(RateLimited(err) || ProxyFault(err)) {
+			return err
+		}
+		log.Printf("🔁 Scrape via %s failed: %v", id, err)
+	}
+	if RateLimited(err) && r.Healthy() > 0 {
+		return fmt.Errorf("rate limited through two proxies: %v", err)
+	}
+	return err
+}
+
+// RateLimited reports whether err is a rate-limit page
+func RateLimited(err error) bool {
+	pe, ok := browser.AsPageError(err)
+	return ok && pe.State == browser.PageRateLimited
+}
+
+// proxyErrors are the Chromium and Go network errors that implicate the proxy
+var proxyErrors = []string{
+	"net::ERR_PROXY", "net::ERR_TUNNEL_CONNECTION_FAILED", "net::ERR_SOCKS", "net::ERR_TIMED_OUT",
+	"net::ERR_CONNECTION_RESET", "net::ERR_CONNECTION_CLOSED", "net::ERR_EMPTY_RESPONSE",
+}
+
+// ProxyFault reports whether err looks like the proxy's fault rather than the page's
+func ProxyFault(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	for _, s := range proxyErrors {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
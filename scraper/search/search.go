@@ -0,0 +1,232 @@
+// Package search serves ranked full-text search over jobs and their descriptions.
+// The jobs_fts index is maintained by triggers (see config.initSearchIndex).
+//
+// Search is off by default: the SQLite driver only compiles FTS5 in when the binary
+// is built with the sqlite_fts5 tag, otherwise /search answers 501.
+//
+//	go build -tags sqlite_fts5 .
+//	go test -tags sqlite_fts5 ./scraper/search
+package search
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Result is one ranked search hit
+type Result struct {
+	Source         string  `json:"source"`
+	JobID          string  `json:"job_id"`
+	Title          string  `json:"title"`
+	Company        string  `json:"company"`
+	Location       string  `json:"location"`
+	PostedDate     string  `json:"posted_date"`
+	Link           string  `json:"link"`
+	JobType        string  `json:"job_type"`
+	Score          float64 `json:"score"`
+	TitleHighlight string  `json:"title_highlight"` // HTML: escaped text, matches in <mark>
+	Snippet        string  `json:"snippet"`         // HTML: escaped text, matches in <mark>
+}
+
+type Response struct {
+	Query      string   `json:"query"`
+	Results    []Result `json:"results"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+// cursor is the position after the last returned hit: results are ordered by
+// (score, rowid), so the next page starts strictly after it
+type cursor struct {
+	Score float64 `json:"s"`
+	RowID int64   `json:"r"`
+}
+
+func (c cursor) encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(raw, &c)
+	return c, err
+}
+
+// Available reports whether the FTS index exists in this database
+func Available(db *sql.DB) bool {
+	var n int
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'jobs_fts'`).Scan(&n)
+	return err == nil && n > 0
+}
+
+// MatchQuery turns free text into an FTS5 query: every term is quoted so input like
+// "C++" or "node.js" cannot break the syntax, and a trailing * keeps prefix search.
+func MatchQuery(q string) string {
+	var terms []string
+	for _, field := range strings.Fields(q) {
+		prefix := strings.HasSuffix(field, "*")
+		field = strings.TrimRight(field, "*")
+		field = strings.TrimFunc(field, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '+' && r != '#'
+		})
+		if field == "" {
+			continue
+		}
+		term := `"` + strings.ReplaceAll(field, `"`, `""`) + `"`
+		if prefix {
+			term += "*"
+		}
+		terms = append(terms, term)
+	}
+	return strings.Join(terms, " ")
+}
+
+// highlight and snippet wrap matches in these, so the job text around them can be
+// escaped before they are turned into <mark> tags
+const (
+	markOpen  = "\x02"
+	markClose = "\x03"
+)
+
+var marks = strings.NewReplacer(markOpen, "<mark>", markClose, "</mark>")
+
+// markup escapes scraped text for HTML and turns the match markers into <mark> tags
+func markup(s string) string {
+	return marks.Replace(html.EscapeString(s))
+}
+
+// Column weights for bm25, in jobs_fts column order
+const rankExpr = `bm25(jobs_fts, 0.0, 0.0, 10.0, 5.0, 2.0, 1.0, 4.0)`
+
+// SearchHandler serves /search.
+// Query params: q (required), source, job_type, since (YYYY-MM-DD), limit (default
+// 20, max 100), cursor (from next_cursor), raw=1 to pass q through as FTS5 syntax.
+func SearchHandler(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if !Available(db) {
+		http.Error(w, "Full-text search is off; the server must be built with -tags sqlite_fts5", http.StatusNotImplemented)
+		return
+	}
+
+	q := r.URL.Query()
+	text := strings.TrimSpace(q.Get("q"))
+	if text == "" {
+		http.Error(w, "q is required", http.StatusBadRequest)
+		return
+	}
+	match := text
+	if q.Get("raw") != "1" {
+		match = MatchQuery(text)
+	}
+	if match == "" {
+		http.Error(w, "q has no searchable terms", http.StatusBadRequest)
+		return
+	}
+
+	limit, err := strconv.Atoi(q.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	inner := `
+		SELECT f.rowid AS rowid, f.source, f.job_id,
+			COALESCE(j.title, ''), COALESCE(j.company, ''), COALESCE(j.location, ''),
+			COALESCE(j.posted_date, ''), COALESCE(j.link, ''), COALESCE(d.job_type, ''),
+			` + rankExpr + ` AS score,
+			highlight(jobs_fts, 2, char(2), char(3)),
+			snippet(jobs_fts, 5, char(2), char(3), '…', 24),
+			COALESCE(date(j.posted_date), date(j.scraped_at)) AS job_date
+		FROM jobs_fts f
+		JOIN all_jobs j ON j.source = f.source AND j.id = f.job_id
+		LEFT JOIN all_job_descriptions d ON d.source = f.source AND d.job_id = f.job_id
+		WHERE jobs_fts MATCH ?`
+	args := []interface{}{match}
+
+	if source := q.Get("source"); source != "" {
+		inner += ` AND f.source = ?`
+		args = append(args, source)
+	}
+	if jobType := q.Get("job_type"); jobType != "" {
+		inner += ` AND d.job_type LIKE ?`
+		args = append(args, "%"+jobType+"%")
+	}
+
+	outer := `SELECT * FROM (` + inner + `) WHERE 1 = 1`
+	if since := q.Get("since"); since != "" {
+		if _, err := time.Parse("2006-01-02", since); err != nil {
+			http.Error(w, "since must be a date in YYYY-MM-DD format", http.StatusBadRequest)
+			return
+		}
+		outer += ` AND job_date >= ?`
+		args = append(args, since)
+	}
+	if raw := q.Get("cursor"); raw != "" {
+		c, err := decodeCursor(raw)
+		if err != nil {
+			http.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
+		outer += ` AND (score > ? OR (score = ? AND rowid > ?))`
+		args = append(args, c.Score, c.Score, c.RowID)
+	}
+	outer += ` ORDER BY score, rowid LIMIT ?`
+	args = append(args, limit+1)
+
+	rows, err := db.Query(outer, args...)
+	if err != nil {
+		if strings.Contains(err.Error(), "fts5") {
+			http.Error(w, fmt.Sprintf("Invalid search query: %v", err), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Search failed", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	resp := Response{Query: text, Results: []Result{}}
+	var last cursor
+	for rows.Next() {
+		var (
+			res     Result
+			rowID   int64
+			jobDate sql.NullString
+		)
+		if err := rows.Scan(&rowID, &res.Source, &res.JobID, &res.Title, &res.Company, &res.Location,
+			&res.PostedDate, &res.Link, &res.JobType, &res.Score, &res.TitleHighlight, &res.Snippet, &jobDate); err != nil {
+			http.Error(w, "Failed to scan search result", http.StatusInternalServerError)
+			return
+		}
+		res.TitleHighlight, res.Snippet = markup(res.TitleHighlight), markup(res.Snippet)
+		if len(resp.Results) == limit {
+			resp.NextCursor = last.encode()
+			break
+		}
+		resp.Results = append(resp.Results, res)
+		last = cursor{Score: res.Score, RowID: rowID}
+	}
+	if err := rows.Err(); err != nil {
+		if strings.Contains(err.Error(), "fts5") {
+			http.Error(w, fmt.Sprintf("Invalid search query: %v", err), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Search failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,186 @@
+package search
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"job_scraper/config"
+)
+
+func TestMatchQuery(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{"golang developer", `"golang" "developer"`},
+		{"C++ C#", `"C++" "C#"`},
+		{"node.js", `"node.js"`},
+		{"(Go),", `"Go"`},
+		{"kube*", `"kube"*`},
+		{`say "hi"`, `"say" "hi"`},
+		{`a"b`, `"a""b"`},
+		{"NEAR(a b) OR -c", `"NEAR(a" "b" "OR" "c"`},
+		{"Größe", `"Größe"`},
+		{" ... * ", ""},
+	} {
+		if got := MatchQuery(tc.in); got != tc.want {
+			t.Errorf("MatchQuery(%q) = %s, want %s", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestMarkup(t *testing.T) {
+	got := markup("Senior " + markOpen + "Go" + markClose + ` <script>alert("x")</script> & Co`)
+	want := `Senior <mark>Go</mark> &lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; &amp; Co`
+	if got != want {
+		t.Errorf("markup = %s, want %s", got, want)
+	}
+}
+
+// newIndexedDB skips unless the binary was built with -tags sqlite_fts5
+func newIndexedDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := config.InitializeDatabaseAt(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if !Available(db) {
+		t.Skip("full-text search needs -tags sqlite_fts5")
+	}
+	return db
+}
+
+func exec(t *testing.T, db *sql.DB, query string, args ...interface{}) {
+	t.Helper()
+	if _, err := db.Exec(query, args...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func search(t *testing.T, db *sql.DB, params url.Values) Response {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	SearchHandler(db, rec, httptest.NewRequest(http.MethodGet, "/search?"+params.Encode(), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("search %s = %d: %s", params.Encode(), rec.Code, rec.Body)
+	}
+	var resp Response
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func ids(resp Response) string {
+	var out []string
+	for _, r := range resp.Results {
+		out = append(out, r.Source+"/"+r.JobID)
+	}
+	return fmt.Sprint(out)
+}
+
+func TestIndexFollowsJobsAndDescriptions(t *testing.T) {
+	db := newIndexedDB(t)
+	exec(t, db, `INSERT INTO linkedin_jobs (id, jobid, title, company, location, link) VALUES ('l1', 'l1', 'Backend Engineer', 'ACME', 'Berlin', 'https://example.com/l1')`)
+	exec(t, db, `INSERT INTO xing_jobs (id, jobid, title, company, location, link) VALUES ('x1', 'x1', 'Data Engineer', 'Beta', 'Hamburg', 'https://example.com/x1')`)
+
+	steps := []struct {
+		name  string
+		stmt  string
+		query string
+		want  string
+	}{
+		{"listing inserted", "", "backend", "[LinkedIn/l1]"},
+		{"company and location indexed", "", "hamburg beta", "[Xing/x1]"},
+		{"title updated", `UPDATE linkedin_jobs SET title = 'Platform Engineer' WHERE id = 'l1'`, "backend", "[]"},
+		{"new title found", "", "platform", "[LinkedIn/l1]"},
+		{"description inserted", `INSERT INTO xing_job_description (job_id, job_link, job_description, skills) VALUES ('x1', 'https://example.com/x1', 'Pipelines in Spark', 'Scala')`, "spark", "[Xing/x1]"},
+		{"skills indexed", "", "scala", "[Xing/x1]"},
+		{"raw description indexed", `UPDATE xing_job_description SET raw_description = 'Wir nutzen Kafka' WHERE job_id = 'x1'`, "kafka", "[Xing/x1]"},
+		{"description deleted", `DELETE FROM xing_job_description WHERE job_id = 'x1'`, "spark", "[]"},
+		{"listing kept after description delete", "", "data", "[Xing/x1]"},
+		{"listing deleted", `DELETE FROM linkedin_jobs WHERE id = 'l1'`, "engineer", "[Xing/x1]"},
+	}
+	for _, step := range steps {
+		if step.stmt != "" {
+			exec(t, db, step.stmt)
+		}
+		if got := ids(search(t, db, url.Values{"q": {step.query}})); got != step.want {
+			t.Errorf("%s: search %q = %s, want %s", step.name, step.query, got, step.want)
+		}
+	}
+}
+
+func TestSearchPagesWithCursor(t *testing.T) {
+	db := newIndexedDB(t)
+	// Equal titles score the same, so the pages have to split ties by rowid
+	for i := 1; i <= 7; i++ {
+		id := fmt.Sprintf("l%d", i)
+		exec(t, db, `INSERT INTO linkedin_jobs (id, jobid, title, link) VALUES (?, ?, 'Golang Engineer', ?)`, id, id, "https://example.com/"+id)
+	}
+	exec(t, db, `INSERT INTO xing_jobs (id, jobid, title, link) VALUES ('x1', 'x1', 'Golang Golang Engineer', 'https://example.com/x1')`)
+
+	for _, limit := range []int{1, 3, 8} {
+		t.Run(fmt.Sprint("limit ", limit), func(t *testing.T) {
+			seen := map[string]bool{}
+			var order []string
+			params := url.Values{"q": {"golang"}, "limit": {fmt.Sprint(limit)}}
+			for pages := 0; ; pages++ {
+				if pages > 10 {
+					t.Fatal("cursor does not advance")
+				}
+				resp := search(t, db, params)
+				if len(resp.Results) > limit {
+					t.Fatalf("page of %d results, limit %d", len(resp.Results), limit)
+				}
+				for _, r := range resp.Results {
+					key := r.Source + "/" + r.JobID
+					if seen[key] {
+						t.Fatalf("%s returned twice", key)
+					}
+					seen[key] = true
+					order = append(order, key)
+				}
+				if resp.NextCursor == "" {
+					break
+				}
+				params.Set("cursor", resp.NextCursor)
+			}
+			if len(order) != 8 || order[0] != "Xing/x1" {
+				t.Errorf("results = %v, want all 8 with the best match first", order)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	SearchHandler(db, rec, httptest.NewRequest(http.MethodGet, "/search?q=golang&cursor=not-a-cursor", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid cursor = %d, want 400", rec.Code)
+	}
+}
+
+func TestSearchEscapesHighlights(t *testing.T) {
+	db := newIndexedDB(t)
+	exec(t, db, `INSERT INTO linkedin_jobs (id, jobid, title, link) VALUES ('l1', 'l1', 'Go <img src=x onerror=alert(1)> Engineer', 'https://example.com/l1')`)
+	exec(t, db, `INSERT INTO linkedin_job_description (job_id, job_link, job_description) VALUES ('l1', 'https://example.com/l1', 'Write Go & <b>ship</b> it')`)
+
+	resp := search(t, db, url.Values{"q": {"go"}})
+	if len(resp.Results) != 1 {
+		t.Fatalf("results = %v", resp.Results)
+	}
+	r := resp.Results[0]
+	if want := "<mark>Go</mark> &lt;img src=x onerror=alert(1)&gt; Engineer"; r.TitleHighlight != want {
+		t.Errorf("title_highlight = %s, want %s", r.TitleHighlight, want)
+	}
+	if want := "Write <mark>Go</mark> &amp; &lt;b&gt;ship&lt;/b&gt; it"; r.Snippet[:len(want)] != want {
+		t.Errorf("snippet = %s, want it to start with %s", r.Snippet, want)
+	}
+	// The stored title itself is returned as is
+	if r.Title != "Go <img src=x onerror=alert(1)> Engineer" {
+		t.Errorf("title = %s", r.Title)
+	}
+}
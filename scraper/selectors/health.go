@@ -0,0 +1,112 @@
+package selectors
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+
+	"job_scraper/scraper/pause"
+)
+
+// Event kinds, stored in scrape_events next to the pauses
+const (
+	KindBroken = "selector_broken"
+	KindFixed  = "selector_fixed"
+)
+
+// Status is the health of one field as of the last run that looked for it
+type Status struct {
+	Source    string `json:"source"`
+	Field     string `json:"field"`
+	Version   int    `json:"version"`
+	Selector  string `json:"selector,omitempty"` // the selector that matched last
+	Matches   int    `json:"matches"`
+	Pages     int    `json:"pages"`
+	Broken    bool   `json:"broken"`
+	CheckedAt string `json:"checked_at"`
+}
+
+// Finish stores what the run matched in selector_health. A required field that was
+// looked for and matched nothing is flagged as broken; the first run that breaks or
+// fixes a field records an event, which also goes to the alert webhook.
+func (r *Run) Finish(db *sql.DB) {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.stats))
+	for name := range r.stats {
+		names = append(names, name)
+	}
+	stats := make(map[string]stat, len(r.stats))
+	for name, st := range r.stats {
+		stats[name] = *st
+	}
+	r.mu.Unlock()
+	sort.Strings(names)
+
+	for _, name := range names {
+		st := stats[name]
+		field, _ := r.site.Field(name)
+		broken := st.matches == 0 && !field.Optional
+		if err := r.store(db, name, st, broken); err != nil {
+			log.Printf("❌ Failed to store selector health of %s %s: %v", r.site.Name, name, err)
+		}
+	}
+}
+
+func (r *Run) store(db *sql.DB, name string, st stat, broken bool) error {
+	var wasBroken bool
+	err := db.QueryRow(`SELECT broken FROM selector_health WHERE source = ? AND field = ?`, r.site.Name, name).Scan(&wasBroken)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO selector_health (source, field, version, selector, matches, pages, broken, checked_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT (source, field) DO UPDATE SET
+			version = excluded.version,
+			selector = excluded.selector,
+			matches = excluded.matches,
+			pages = excluded.pages,
+			broken = excluded.broken,
+			checked_at = excluded.checked_at`,
+		r.site.Name, name, r.site.Version, st.selector, st.matches, st.tries, broken)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case broken && !wasBroken:
+		field, _ := r.site.Field(name)
+		log.Printf("🧩 %s selectors for %s matched nothing on %d pages; the site may have changed", r.site.Name, name, st.tries)
+		pause.Record(db, pause.Event{Source: r.site.Name, Kind: KindBroken,
+			Message: fmt.Sprintf("%s selectors for %s (version %d: %q) matched nothing on %d pages; the site may have changed",
+				r.site.Name, name, r.site.Version, field.Selectors, st.tries)})
+	case !broken && wasBroken:
+		log.Printf("🧩 %s selectors for %s match again with %s", r.site.Name, name, st.selector)
+		pause.Record(db, pause.Event{Source: r.site.Name, Kind: KindFixed,
+			Message: fmt.Sprintf("%s selectors for %s (version %d) match again with %s", r.site.Name, name, r.site.Version, st.selector)})
+	}
+	return nil
+}
+
+// Statuses lists the health of every field that has been looked for, broken first
+func Statuses(db *sql.DB) ([]Status, error) {
+	rows, err := db.Query(`
+		SELECT source, field, version, COALESCE(selector, ''), matches, pages, broken, checked_at
+		FROM selector_health ORDER BY broken DESC, source, field`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	statuses := []Status{}
+	for rows.Next() {
+		var s Status
+		if err := rows.Scan(&s.Source, &s.Field, &s.Version, &s.Selector, &s.Matches, &s.Pages, &s.Broken, &s.CheckedAt); err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, rows.Err()
+}
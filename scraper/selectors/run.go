@@ -0,0 +1,212 @@
+package selectors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Run counts what each field matched during one scraper run
+type Run struct {
+	site Site
+
+	mu    sync.Mutex
+	stats map[string]*stat
+}
+
+type stat struct {
+	tries    int // pages the field was looked for on
+	matches  int
+	selector string // the last selector that matched
+}
+
+// NewRun starts counting for a site with the default selectors file
+func NewRun(site string) *Run {
+	return Default().Site(site).NewRun()
+}
+
+func (s Site) NewRun() *Run {
+	return &Run{site: s, stats: make(map[string]*stat)}
+}
+
+// Site returns the selectors the run uses
+func (r *Run) Site() Site { return r.site }
+
+func (r *Run) record(field string, matches int, selector string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	st, ok := r.stats[field]
+	if !ok {
+		st = &stat{}
+		r.stats[field] = st
+	}
+	st.tries++
+	st.matches += matches
+	if selector != "" {
+		st.selector = selector
+	}
+}
+
+func (r *Run) field(name string) (Field, error) {
+	field, ok := r.site.Field(name)
+	if !ok {
+		return Field{}, fmt.Errorf("selectors: %s has no %s selectors (version %d)", r.site.Name, name, r.site.Version)
+	}
+	return field, nil
+}
+
+// Wait runs wait, usually a pacer's WaitVisible, on all of the field's selectors at
+// once and counts a miss when none of them shows up
+func (r *Run) Wait(name string, wait func(sel string) chromedp.Action) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if _, err := r.field(name); err != nil {
+			return err
+		}
+		err := wait(r.site.Any(name)).Do(ctx)
+		if err != nil {
+			r.record(name, 0, "")
+		}
+		return err
+	})
+}
+
+// resolveJS finds the first selector of a list that matches on the page
+const resolveJS = `(sels => {
+	for (const s of sels) {
+		const n = document.querySelectorAll(s).length;
+		if (n) return {selector: s, matches: n};
+	}
+	return {selector: '', matches: 0};
+})(%s)`
+
+// resolve returns the first of the field's selectors that matches and counts it
+func (r *Run) resolve(ctx context.Context, name string) (string, error) {
+	field, err := r.field(name)
+	if err != nil {
+		return "", err
+	}
+	sels, _ := json.Marshal(field.Selectors)
+	var res struct {
+		Selector string `json:"selector"`
+		Matches  int    `json:"matches"`
+	}
+	if err := chromedp.Evaluate(fmt.Sprintf(resolveJS, sels), &res).Do(ctx); err != nil {
+		return "", err
+	}
+	r.record(name, res.Matches, res.Selector)
+	if res.Selector == "" {
+		return "", fmt.Errorf("no %s %s selector matched (version %d)", r.site.Name, name, r.site.Version)
+	}
+	return res.Selector, nil
+}
+
+// Text reads the visible text of the field's first matching selector
+func (r *Run) Text(name string, out *string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		sel, err := r.resolve(ctx, name)
+		if err != nil {
+			return err
+		}
+		return chromedp.Text(sel, out, chromedp.NodeVisible, chromedp.ByQuery).Do(ctx)
+	})
+}
+
+// Click clicks the field's first matching selector
+func (r *Run) Click(name string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		sel, err := r.resolve(ctx, name)
+		if err != nil {
+			return err
+		}
+		return chromedp.Click(sel, chromedp.NodeVisible, chromedp.ByQuery).Do(ctx)
+	})
+}
+
+// Pull is one value Extract reads from every list element
+type Pull struct {
+	Key    string `json:"key"` // the JSON key of the value in the extracted items
+	Field  string `json:"-"`
+	Attr   string `json:"attr,omitempty"`   // read an attribute instead of the text
+	Prop   string `json:"prop,omitempty"`   // read a DOM property, e.g. href for an absolute link
+	Exists bool   `json:"exists,omitempty"` // whether anything matched, as a boolean
+
+	Selectors []string `json:"selectors"` // filled in from the field by Extract
+}
+
+// extractJS maps every element of the first matching list selector to an object of
+// pulled values, and counts per value on how many elements it matched. A pull's
+// selector matches the element itself or its first matching descendant.
+const extractJS = `((list, pulls) => {
+	let cards = [], listSel = '';
+	for (const s of list) {
+		cards = Array.from(document.querySelectorAll(s));
+		if (cards.length) { listSel = s; break; }
+	}
+	const pick = (el, sels) => {
+		for (const s of sels) {
+			const m = el.matches(s) ? el : el.querySelector(s);
+			if (m) return [m, s];
+		}
+		return [null, ''];
+	};
+	const counts = {}, used = {};
+	pulls.forEach(p => counts[p.key] = 0);
+	const items = cards.map(el => {
+		const item = {};
+		for (const p of pulls) {
+			const [m, s] = pick(el, p.selectors);
+			if (m) { counts[p.key]++; used[p.key] = s; }
+			if (p.exists) item[p.key] = m !== null;
+			else if (p.attr) item[p.key] = (m && m.getAttribute(p.attr)) || '';
+			else if (p.prop) item[p.key] = (m && m[p.prop]) || '';
+			else item[p.key] = m ? m.innerText.trim() : '';
+		}
+		return item;
+	});
+	return {list: listSel, cards: cards.length, items, counts, used};
+})(%s, %s)`
+
+// Extract reads the pulls from every element of the list field into out, a pointer
+// to a slice of structs whose JSON keys are the pulls' keys
+func (r *Run) Extract(list string, pulls []Pull, out interface{}) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		listField, err := r.field(list)
+		if err != nil {
+			return err
+		}
+		resolved := make([]Pull, len(pulls))
+		for i, p := range pulls {
+			field, err := r.field(p.Field)
+			if err != nil {
+				return err
+			}
+			p.Selectors = field.Selectors
+			resolved[i] = p
+		}
+		listJSON, _ := json.Marshal(listField.Selectors)
+		pullsJSON, _ := json.Marshal(resolved)
+
+		var res struct {
+			List   string            `json:"list"`
+			Cards  int               `json:"cards"`
+			Items  json.RawMessage   `json:"items"`
+			Counts map[string]int    `json:"counts"`
+			Used   map[string]string `json:"used"`
+		}
+		if err := chromedp.Evaluate(fmt.Sprintf(extractJS, listJSON, pullsJSON), &res).Do(ctx); err != nil {
+			return err
+		}
+
+		r.record(list, res.Cards, res.List)
+		// Without elements the values were not looked for; the list field takes the blame
+		if res.Cards > 0 {
+			for _, p := range pulls {
+				r.record(p.Field, res.Counts[p.Key], res.Used[p.Key])
+			}
+		}
+		return json.Unmarshal(res.Items, out)
+	})
+}
@@ -0,0 +1,147 @@
+// Package selectors keeps the CSS selectors the scrapers depend on in a versioned
+// JSON file instead of in code. Every field has a list of selectors that are tried in
+// order, so a fallback can be added the day a site renames a class, and every run
+// counts what each field matched: a required field that matched nothing across a
+// whole run is flagged in selector_health and raised as a scrape event.
+//
+//	SELECTORS_FILE  optional; a selectors file to use instead of the bundled selectors.json
+package selectors
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+//go:embed selectors.json
+var bundled []byte
+
+// Fields the scrapers look up
+const (
+	ListingCard       = "listing.card" // one search result; the other listing fields are looked up inside it
+	ListingLink       = "listing.link"
+	ListingCompany    = "listing.company"
+	ListingLocation   = "listing.location"
+	ListingPosted     = "listing.posted"
+	ListingEasyApply  = "listing.easy_apply"
+	ListingEmployment = "listing.employment_type"
+	ListingSalary     = "listing.salary"
+	ListingMore       = "listing.more" // the button that loads the next page of results
+	DetailDescription = "detail.description"
+	DetailApply       = "detail.apply"
+)
+
+// File is a selectors file: per site (lowercase), per field
+type File struct {
+	Version int                         `json:"version"`
+	Updated string                      `json:"updated"`
+	Sites   map[string]map[string]Field `json:"sites"`
+}
+
+// Field is the selectors of one field, best first
+type Field struct {
+	Selectors []string `json:"selectors"`
+	Optional  bool     `json:"optional,omitempty"` // may match nothing on a healthy page, like the Easy Apply badge
+}
+
+// Parse reads and checks a selectors file
+func Parse(raw []byte) (*File, error) {
+	var f File
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return nil, fmt.Errorf("selectors: %v", err)
+	}
+	if f.Version <= 0 {
+		return nil, fmt.Errorf("selectors: the file needs a positive version")
+	}
+	for site, fields := range f.Sites {
+		for name, field := range fields {
+			if len(field.Selectors) == 0 {
+				return nil, fmt.Errorf("selectors: %s %s has no selectors", site, name)
+			}
+			for _, sel := range field.Selectors {
+				if strings.TrimSpace(sel) == "" {
+					return nil, fmt.Errorf("selectors: %s %s has an empty selector", site, name)
+				}
+			}
+		}
+	}
+	return &f, nil
+}
+
+// Load reads SELECTORS_FILE, or the bundled file when it is not set
+func Load() (*File, error) {
+	path := os.Getenv("SELECTORS_FILE")
+	if path == "" {
+		return Parse(bundled)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("selectors: %v", err)
+	}
+	f, err := Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%v in %s", err, path)
+	}
+	return f, nil
+}
+
+var (
+	defaultMu   sync.Mutex
+	defaultFile *File
+)
+
+// Default is the process-wide selectors file. A broken SELECTORS_FILE is logged and
+// the bundled selectors are used instead.
+func Default() *File {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	if defaultFile == nil {
+		f, err := Load()
+		if err != nil {
+			log.Printf("⚠️ %v; using the bundled selectors", err)
+			if f, err = Parse(bundled); err != nil {
+				panic(err) // the bundled file is checked by the tests
+			}
+		}
+		log.Printf("🧩 Using selectors version %d (%s)", f.Version, f.Updated)
+		defaultFile = f
+	}
+	return defaultFile
+}
+
+// Reset drops the process-wide selectors file, so the next Default loads
+// SELECTORS_FILE again
+func Reset() {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultFile = nil
+}
+
+// Site is the selectors of one site
+type Site struct {
+	Name    string
+	Version int
+	fields  map[string]Field
+}
+
+// Site returns the selectors of a site by its display name, e.g. "LinkedIn"
+func (f *File) Site(name string) Site {
+	return Site{Name: name, Version: f.Version, fields: f.Sites[strings.ToLower(name)]}
+}
+
+// Field returns a field's selectors; ok is false if the file does not define it
+func (s Site) Field(name string) (Field, bool) {
+	field, ok := s.fields[name]
+	return field, ok
+}
+
+// Any is one selector list that matches wherever any of the field's selectors does,
+// for waiting until the page has rendered the field at all
+func (s Site) Any(name string) string {
+	field, _ := s.Field(name)
+	return strings.Join(field.Selectors, ", ")
+}
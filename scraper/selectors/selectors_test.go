@@ -0,0 +1,153 @@
+package selectors
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"job_scraper/config"
+	"job_scraper/scraper/pause"
+)
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := config.InitializeDatabaseAt(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// used is every field the scrapers look up, per site
+var used = map[string][]string{
+	"LinkedIn": {ListingCard, ListingLink, ListingCompany, ListingLocation, ListingPosted, ListingEasyApply, DetailDescription, DetailApply},
+	"Xing":     {ListingCard, ListingLink, ListingCompany, ListingLocation, ListingPosted, ListingEmployment, ListingSalary, ListingMore, DetailDescription, DetailApply},
+}
+
+func TestBundledSelectorsCoverTheScrapers(t *testing.T) {
+	f, err := Parse(bundled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for site, fields := range used {
+		s := f.Site(site)
+		for _, name := range fields {
+			if _, ok := s.Field(name); !ok {
+				t.Errorf("bundled selectors have no %s %s", site, name)
+			}
+		}
+	}
+	if got := f.Site("Xing").Any(DetailApply); got == "" {
+		t.Error("Any() of a defined field is empty")
+	}
+}
+
+func TestParseRejectsBrokenFiles(t *testing.T) {
+	for name, raw := range map[string]string{
+		"no version":     `{"sites": {"linkedin": {"listing.card": {"selectors": ["li"]}}}}`,
+		"no selectors":   `{"version": 2, "sites": {"linkedin": {"listing.card": {"selectors": []}}}}`,
+		"empty selector": `{"version": 2, "sites": {"linkedin": {"listing.card": {"selectors": ["li", " "]}}}}`,
+		"not json":       `{"version": 2`,
+	} {
+		if _, err := Parse([]byte(raw)); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+func TestLoadPrefersSelectorsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "selectors.json")
+	os.WriteFile(path, []byte(`{"version": 7, "updated": "2025-07-01", "sites": {"xing": {"listing.card": {"selectors": ["article"]}}}}`), 0o600)
+	t.Setenv("SELECTORS_FILE", path)
+
+	f, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	field, ok := f.Site("Xing").Field(ListingCard)
+	if f.Version != 7 || !ok || field.Selectors[0] != "article" {
+		t.Errorf("loaded version %d with Xing cards %v", f.Version, field.Selectors)
+	}
+
+	t.Setenv("SELECTORS_FILE", filepath.Join(t.TempDir(), "missing.json"))
+	if _, err := Load(); err == nil {
+		t.Error("a missing SELECTORS_FILE was not reported")
+	}
+}
+
+func eventKinds(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+	events, err := pause.Events(db, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kinds []string
+	for _, e := range events {
+		kinds = append(kinds, e.Kind+" "+e.Source)
+	}
+	return kinds
+}
+
+func TestFinishFlagsFieldsThatMatchedNothing(t *testing.T) {
+	db := newTestDB(t)
+	f, err := Parse(bundled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	site := f.Site("LinkedIn")
+
+	run := site.NewRun()
+	for i := 0; i < 3; i++ {
+		run.record(ListingCard, 25, ".jobs-search__results-list li")
+		run.record(ListingCompany, 0, "")
+		run.record(ListingEasyApply, 0, "")
+	}
+	run.record(ListingLocation, 0, "")
+	run.record(ListingLocation, 4, ".base-search-card__metadata span")
+	run.Finish(db)
+
+	statuses, err := Statuses(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byField := map[string]Status{}
+	for _, s := range statuses {
+		byField[s.Field] = s
+	}
+	if s := byField[ListingCompany]; !s.Broken || s.Pages != 3 || statuses[0].Field != ListingCompany {
+		t.Errorf("company = %+v, want broken and listed first", s)
+	}
+	if s := byField[ListingCard]; s.Broken || s.Matches != 75 || s.Selector != ".jobs-search__results-list li" {
+		t.Errorf("card = %+v, want healthy with 75 matches", s)
+	}
+	if byField[ListingEasyApply].Broken {
+		t.Error("an optional field that matched nothing was flagged")
+	}
+	if s := byField[ListingLocation]; s.Broken || s.Selector != ".base-search-card__metadata span" {
+		t.Errorf("location matched through a fallback = %+v, want healthy", s)
+	}
+	if _, ok := byField[DetailApply]; ok {
+		t.Error("a field the run never looked for got a status")
+	}
+	if kinds := eventKinds(t, db); len(kinds) != 1 || kinds[0] != "selector_broken LinkedIn" {
+		t.Fatalf("events = %v, want one selector_broken", kinds)
+	}
+
+	// Still broken: no second alert
+	run = site.NewRun()
+	run.record(ListingCompany, 0, "")
+	run.Finish(db)
+	if kinds := eventKinds(t, db); len(kinds) != 1 {
+		t.Fatalf("events after a second broken run = %v, want no new one", kinds)
+	}
+
+	// A new selectors version fixes it
+	run = site.NewRun()
+	run.record(ListingCompany, 25, "h4")
+	run.Finish(db)
+	if kinds := eventKinds(t, db); len(kinds) != 2 || kinds[0] != "selector_fixed LinkedIn" {
+		t.Fatalf("events after the fix = %v, want selector_fixed", kinds)
+	}
+}
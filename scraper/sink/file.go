@@ -0,0 +1,85 @@
+package sink
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileSink writes every upload run to a new JSON-lines or CSV file in a directory
+type FileSink struct {
+	dir    string
+	format string
+	now    func() time.Time
+}
+
+func NewFileSink(dir, format string) (*FileSink, error) {
+	if format != "jsonl" && format != "csv" {
+		return nil, fmt.Errorf("file sink: format must be jsonl or csv, got %q", format)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("file sink: %v", err)
+	}
+	return &FileSink{dir: dir, format: format, now: time.Now}, nil
+}
+
+func (s *FileSink) Name() string { return "file" }
+
+// csvHeader flattens the description; skills and application links are joined with "; "
+var csvHeader = []string{"source", "job_id", "id", "title", "company", "location", "posted_date", "link",
+	"processed", "job_description", "job_type", "skills", "application_links", "has_application_link"}
+
+func (s *FileSink) Write(ctx context.Context, jobs []Job) (Result, error) {
+	res := Result{Sink: s.Name(), Failed: []string{}}
+	if len(jobs) == 0 {
+		return res, nil
+	}
+
+	path := filepath.Join(s.dir, fmt.Sprintf("jobs-%s.%s", s.now().UTC().Format("20060102T150405.000Z"), s.format))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return res, fmt.Errorf("file sink: %v", err)
+	}
+
+	switch s.format {
+	case "jsonl":
+		enc := json.NewEncoder(f)
+		for _, job := range jobs {
+			if err = enc.Encode(job); err != nil {
+				break
+			}
+		}
+	case "csv":
+		w := csv.NewWriter(f)
+		w.Write(csvHeader)
+		for _, job := range jobs {
+			desc := job.Description
+			if desc == nil {
+				desc = &Description{}
+			}
+			w.Write([]string{job.Source, job.JobID, job.ID, job.Title, job.Company, job.Location, job.PostedDate, job.Link,
+				strconv.FormatBool(job.Processed), desc.Text, desc.JobType, strings.Join(desc.Skills, "; "),
+				strings.Join(job.ApplicationLinks, "; "), strconv.FormatBool(job.HasApplicationLink)})
+		}
+		w.Flush()
+		err = w.Error()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return res, fmt.Errorf("file sink: write %s: %v", path, err)
+	}
+
+	res.Written = len(jobs)
+	return res, nil
+}
+
+func (s *FileSink) Close(ctx context.Context) error { return nil }
@@ -0,0 +1,112 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoCollection is the part of *mongo.Collection the sink uses, so tests can stand in for it
+type mongoCollection interface {
+	BulkWrite(ctx context.Context, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error)
+}
+
+// MongoSink upserts jobs into a MongoDB collection keyed by (source, job_id), so
+// re-running an upload updates documents instead of duplicating them. Documents in
+// the old one-per-application-link shape are removed when their job is written.
+type MongoSink struct {
+	client     *mongo.Client
+	collection mongoCollection
+	batchSize  int
+}
+
+// NewMongoSink connects to uri, uses database.collection and makes sure the unique
+// (source, job_id) index exists
+func NewMongoSink(ctx context.Context, uri, database, collection string, batchSize int) (*MongoSink, error) {
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, fmt.Errorf("mongo sink: connect: %v", err)
+	}
+	log.Println("✅ Connected to MongoDB:", database)
+
+	coll := client.Database(database).Collection(collection)
+	_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "source", Value: 1}, {Key: "job_id", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName("source_job_id"),
+	})
+	if err != nil {
+		// Upserts still match on (source, job_id); only concurrent runs could race
+		log.Printf("⚠️ Could not create the unique (source, job_id) index on %s.%s: %v", database, collection, err)
+	}
+	return &MongoSink{client: client, collection: coll, batchSize: batchSize}, nil
+}
+
+func (s *MongoSink) Name() string { return "mongo" }
+
+// Write sends unordered bulk upserts, one BulkWrite per batch. A failed document
+// does not stop the rest of its batch; a batch that fails outright fails all its jobs.
+func (s *MongoSink) Write(ctx context.Context, jobs []Job) (Result, error) {
+	res := Result{Sink: s.Name(), Failed: []string{}}
+	batchSize := s.batchSize
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	for start := 0; start < len(jobs); start += batchSize {
+		end := start + batchSize
+		if end > len(jobs) {
+			end = len(jobs)
+		}
+		batch := jobs[start:end]
+
+		// Upserts come first so a write error's index is also the job's index in batch
+		models := make([]mongo.WriteModel, 0, 2*len(batch))
+		for _, job := range batch {
+			models = append(models, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"source": job.Source, "job_id": job.JobID}).
+				SetUpdate(bson.M{"$set": job, "$currentDate": bson.M{"uploaded_at": true}}).
+				SetUpsert(true))
+		}
+		for _, job := range batch {
+			models = append(models, mongo.NewDeleteManyModel().
+				SetFilter(bson.M{"source": job.Source, "job_id": job.ID, "application_links": bson.M{"$exists": false}}))
+		}
+
+		out, err := s.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+		var bwe mongo.BulkWriteException
+		switch {
+		case err == nil:
+		case errors.As(err, &bwe) && len(bwe.WriteErrors) > 0 && bwe.WriteConcernError == nil:
+			for _, we := range bwe.WriteErrors {
+				if we.Index >= 0 && we.Index < len(batch) {
+					log.Printf("❌ Failed to upsert job %s: %s", batch[we.Index].JobID, we.Message)
+					res.Failed = append(res.Failed, batch[we.Index].Key())
+				}
+			}
+		default:
+			log.Printf("❌ Bulk upsert of %d jobs failed: %v", len(batch), err)
+			for _, job := range batch {
+				res.Failed = append(res.Failed, job.Key())
+			}
+			continue
+		}
+		if out != nil {
+			res.Inserted += int(out.UpsertedCount)
+			res.Updated += int(out.ModifiedCount)
+			res.Written += int(out.UpsertedCount + out.MatchedCount)
+		}
+	}
+	return res, nil
+}
+
+func (s *MongoSink) Close(ctx context.Context) error {
+	if s.client == nil {
+		return nil
+	}
+	return s.client.Disconnect(ctx)
+}
@@ -0,0 +1,160 @@
+// Package sink delivers collected jobs to their destinations. An upload run writes
+// the same batch to every configured sink; a job counts as sent once all of them
+// accepted it.
+package sink
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Job is the exported document: one per job, with its description and every
+// application link captured for it
+type Job struct {
+	ID         string `json:"id" bson:"id"`         // row id in the scraper database
+	JobID      string `json:"job_id" bson:"job_id"` // id on the source site
+	Source     string `json:"source" bson:"source"`
+	Title      string `json:"title" bson:"title"`
+	Company    string `json:"company" bson:"company"`
+	Location   string `json:"location" bson:"location"`
+	PostedDate string `json:"posted_date" bson:"posted_date"`
+	Link       string `json:"link" bson:"link"`
+	Processed  bool   `json:"processed" bson:"processed"`
+	// Description is nil until the job's details have been scraped
+	Description        *Description `json:"description" bson:"description"`
+	ApplicationLinks   []string     `json:"application_links" bson:"application_links"`
+	HasApplicationLink bool         `json:"has_application_link" bson:"has_application_link"`
+	// IdempotencyKey names this version of the job; redeliveries carry the same key
+	IdempotencyKey string `json:"idempotency_key,omitempty" bson:"idempotency_key,omitempty"`
+	// Event is "created" for a job the sink has not seen and "updated" for a changed one
+	Event string `json:"event,omitempty" bson:"-"`
+}
+
+// Description is the summarized job description
+type Description struct {
+	Text    string   `json:"text" bson:"text"`
+	JobType string   `json:"job_type" bson:"job_type"`
+	Skills  []string `json:"skills" bson:"skills"`
+}
+
+// Key identifies a job across sources
+func (j Job) Key() string {
+	return j.Source + ":" + j.JobID
+}
+
+// Result reports what one sink did with a batch
+type Result struct {
+	Sink    string `json:"sink"`
+	Written int    `json:"written"`
+	// Inserted and Updated split Written for sinks that can tell new documents from
+	// existing ones; Written minus both is the number of unchanged jobs
+	Inserted int      `json:"inserted,omitempty"`
+	Updated  int      `json:"updated,omitempty"`
+	Failed   []string `json:"failed"` // Job.Key of every job the sink rejected
+	Error    string   `json:"error,omitempty"`
+}
+
+// Sink is an upload destination
+type Sink interface {
+	Name() string
+	// Write delivers jobs. Per-job problems go to Result.Failed; an error means
+	// the whole batch is in doubt.
+	Write(ctx context.Context, jobs []Job) (Result, error)
+	Close(ctx context.Context) error
+}
+
+// DefaultNames are the sinks used when an upload run does not pick any (UPLOAD_SINKS, default mongo)
+func DefaultNames() []string {
+	return ParseNames(os.Getenv("UPLOAD_SINKS"), "mongo")
+}
+
+// ParseNames splits a comma-separated sink list
+func ParseNames(raw, fallback string) []string {
+	if strings.TrimSpace(raw) == "" {
+		raw = fallback
+	}
+	var names []string
+	for _, n := range strings.Split(raw, ",") {
+		if n = strings.ToLower(strings.TrimSpace(n)); n != "" {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+// FromEnv opens the named sinks with their settings from the environment:
+//
+//	mongo     MONGO_URI (required), MONGO_DB (default JSE), MONGO_COLLECTION (default jobs),
+//	          MONGO_BATCH_SIZE (default 500)
+//	file      UPLOAD_DIR (default exports), UPLOAD_FILE_FORMAT (jsonl or csv, default jsonl)
+//	webhook   WEBHOOK_URL and WEBHOOK_SECRET (required), WEBHOOK_BATCH_SIZE (default 100)
+//	postgres  POSTGRES_DSN (required), POSTGRES_TABLE (default jobs)
+//
+// Sinks opened before a failure are closed again.
+func FromEnv(ctx context.Context, names []string) ([]Sink, error) {
+	var sinks []Sink
+	fail := func(err error) ([]Sink, error) {
+		CloseAll(ctx, sinks)
+		return nil, err
+	}
+
+	for _, name := range names {
+		var (
+			s   Sink
+			err error
+		)
+		switch name {
+		case "mongo":
+			uri := os.Getenv("MONGO_URI")
+			if uri == "" {
+				return fail(fmt.Errorf("mongo sink: MONGO_URI is not set"))
+			}
+			s, err = NewMongoSink(ctx, uri, envOr("MONGO_DB", "JSE"), envOr("MONGO_COLLECTION", "jobs"), intEnv("MONGO_BATCH_SIZE", 500))
+		case "file":
+			s, err = NewFileSink(envOr("UPLOAD_DIR", "exports"), envOr("UPLOAD_FILE_FORMAT", "jsonl"))
+		case "webhook":
+			url, secret := os.Getenv("WEBHOOK_URL"), os.Getenv("WEBHOOK_SECRET")
+			if url == "" || secret == "" {
+				return fail(fmt.Errorf("webhook sink: WEBHOOK_URL and WEBHOOK_SECRET must be set"))
+			}
+			s = NewWebhookSink(url, secret, intEnv("WEBHOOK_BATCH_SIZE", 100))
+		case "postgres":
+			dsn := os.Getenv("POSTGRES_DSN")
+			if dsn == "" {
+				return fail(fmt.Errorf("postgres sink: POSTGRES_DSN is not set"))
+			}
+			s, err = NewPostgresSink(ctx, dsn, envOr("POSTGRES_TABLE", "jobs"))
+		default:
+			return fail(fmt.Errorf("unknown sink %q (want mongo, file, webhook or postgres)", name))
+		}
+		if err != nil {
+			return fail(err)
+		}
+		sinks = append(sinks, s)
+	}
+	return sinks, nil
+}
+
+// CloseAll closes every sink; close errors do not affect an upload that already ran
+func CloseAll(ctx context.Context, sinks []Sink) {
+	for _, s := range sinks {
+		s.Close(ctx)
+	}
+}
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func intEnv(name string, fallback int) int {
+	var n int
+	if _, err := fmt.Sscan(os.Getenv(name), &n); err != nil || n <= 0 {
+		return fallback
+	}
+	return n
+}
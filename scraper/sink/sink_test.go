@@ -0,0 +1,291 @@
+package sink
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func testJobs() []Job {
+	return []Job{
+		{ID: "a1", JobID: "101", Source: "LinkedIn", Title: "Go Developer", Company: "Acme",
+			ApplicationLinks: []string{"https://acme.example/apply", "https://jobs.acme.example/101"}, HasApplicationLink: true},
+		{ID: "b2", JobID: "x1", Source: "Xing", Title: "Backend Engineer", Company: "Initech", ApplicationLinks: []string{},
+			Description: &Description{Text: "Build services", JobType: "Full-time", Skills: []string{"Go", "SQL"}}},
+		{ID: "c3", JobID: "x2", Source: "Xing", Title: "Data Engineer, \"Senior\"", Company: "Globex", ApplicationLinks: []string{}},
+	}
+}
+
+func TestParseNames(t *testing.T) {
+	got := ParseNames(" Mongo, file ,,webhook", "mongo")
+	if strings.Join(got, "|") != "mongo|file|webhook" {
+		t.Errorf("ParseNames = %q", got)
+	}
+	if got := ParseNames("", "file"); len(got) != 1 || got[0] != "file" {
+		t.Errorf("fallback = %q", got)
+	}
+}
+
+func TestFromEnvRejectsUnknownAndUnconfigured(t *testing.T) {
+	t.Setenv("UPLOAD_DIR", t.TempDir())
+	t.Setenv("WEBHOOK_URL", "")
+	if _, err := FromEnv(context.Background(), []string{"file", "ftp"}); err == nil {
+		t.Error("unknown sink accepted")
+	}
+	if _, err := FromEnv(context.Background(), []string{"webhook"}); err == nil {
+		t.Error("webhook without WEBHOOK_URL accepted")
+	}
+}
+
+func TestFileSinkJSONLines(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFileSink(dir, "jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := s.Write(context.Background(), testJobs())
+	if err != nil || res.Written != 3 || len(res.Failed) != 0 {
+		t.Fatalf("Write = %+v, %v", res, err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "jobs-*.jsonl"))
+	if len(files) != 1 {
+		t.Fatalf("files = %v", files)
+	}
+	f, _ := os.Open(files[0])
+	defer f.Close()
+	var got []Job
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var job Job
+		if err := json.Unmarshal(scanner.Bytes(), &job); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		got = append(got, job)
+	}
+	if len(got) != 3 || !reflect.DeepEqual(got[1], testJobs()[1]) {
+		t.Errorf("read back %+v", got)
+	}
+}
+
+func TestFileSinkCSV(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFileSink(dir, "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Write(context.Background(), testJobs()); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "jobs-*.csv"))
+	if len(files) != 1 {
+		t.Fatalf("files = %v", files)
+	}
+	raw, _ := os.ReadFile(files[0])
+	if lines := strings.Count(string(raw), "\n"); lines != 4 {
+		t.Errorf("%d lines, want header + 3:\n%s", lines, raw)
+	}
+	if !strings.Contains(string(raw), `"Data Engineer, ""Senior"""`) {
+		t.Errorf("title not CSV-quoted:\n%s", raw)
+	}
+	if !strings.Contains(string(raw), "https://acme.example/apply; https://jobs.acme.example/101,true") {
+		t.Errorf("application links not joined:\n%s", raw)
+	}
+
+	if _, err := NewFileSink(dir, "xml"); err == nil {
+		t.Error("unknown format accepted")
+	}
+}
+
+func TestWebhookSinkSignsAndBatches(t *testing.T) {
+	const secret = "s3cret"
+	var (
+		mu      sync.Mutex
+		batches [][]Job
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(SignatureHeader) != Sign([]byte(secret), body) {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		var payload WebhookPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, payload.Jobs)
+		// Reject the second batch to check per-batch failure
+		if len(batches) == 2 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	res, err := NewWebhookSink(srv.URL, secret, 2).Write(context.Background(), testJobs())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 2 || len(batches[0]) != 2 || len(batches[1]) != 1 {
+		t.Fatalf("batches = %v", batches)
+	}
+	if res.Written != 2 || len(res.Failed) != 1 || res.Failed[0] != "Xing:x2" {
+		t.Errorf("result = %+v", res)
+	}
+
+	res, _ = NewWebhookSink(srv.URL, "wrong", 10).Write(context.Background(), testJobs())
+	if res.Written != 0 || len(res.Failed) != 3 {
+		t.Errorf("wrong secret: %+v", res)
+	}
+}
+
+func TestSQLSinkUpserts(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "sink.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	s, err := NewSQLSink(context.Background(), db, "jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Write(context.Background(), testJobs()); err != nil {
+		t.Fatal(err)
+	}
+	updated := testJobs()[:1]
+	updated[0].Title = "Senior Go Developer"
+	if res, err := s.Write(context.Background(), updated); err != nil || res.Written != 1 {
+		t.Fatalf("second Write = %+v, %v", res, err)
+	}
+
+	var count int
+	var title, links string
+	var skills sql.NullString
+	db.QueryRow(`SELECT COUNT(*) FROM jobs`).Scan(&count)
+	db.QueryRow(`SELECT title, application_links FROM jobs WHERE source = 'LinkedIn' AND job_id = '101'`).Scan(&title, &links)
+	db.QueryRow(`SELECT skills FROM jobs WHERE source = 'Xing' AND job_id = 'x1'`).Scan(&skills)
+	if count != 3 || title != "Senior Go Developer" {
+		t.Errorf("count = %d, title = %q", count, title)
+	}
+	if links != `["https://acme.example/apply","https://jobs.acme.example/101"]` || skills.String != "Go, SQL" {
+		t.Errorf("links = %s, skills = %q", links, skills.String)
+	}
+
+	if _, err := NewSQLSink(context.Background(), db, "jobs; DROP TABLE jobs"); err == nil {
+		t.Error("unsafe table name accepted")
+	}
+}
+
+// fakeCollection stands in for a MongoDB collection with the unique (source, job_id) index
+type fakeCollection struct {
+	docs      map[string]Job
+	failOnIDs map[string]bool
+	calls     int
+	deletes   int
+}
+
+func (c *fakeCollection) BulkWrite(ctx context.Context, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error) {
+	c.calls++
+	if o := options.MergeBulkWriteOptions(opts...); o.Ordered == nil || *o.Ordered {
+		return nil, errors.New("bulk write must be unordered")
+	}
+	res := &mongo.BulkWriteResult{}
+	var bwe mongo.BulkWriteException
+	for i, m := range models {
+		update, ok := m.(*mongo.UpdateOneModel)
+		if !ok {
+			c.deletes++
+			continue
+		}
+		filter := update.Filter.(bson.M)
+		job := update.Update.(bson.M)["$set"].(Job)
+		key := filter["source"].(string) + ":" + filter["job_id"].(string)
+		if c.failOnIDs[job.JobID] {
+			bwe.WriteErrors = append(bwe.WriteErrors, mongo.BulkWriteError{WriteError: mongo.WriteError{Index: i, Code: 121, Message: "document failed validation"}})
+			continue
+		}
+		old, exists := c.docs[key]
+		switch {
+		case !exists:
+			res.UpsertedCount++
+		case !reflect.DeepEqual(old, job):
+			res.MatchedCount++
+			res.ModifiedCount++
+		default:
+			res.MatchedCount++
+		}
+		c.docs[key] = job
+	}
+	if len(bwe.WriteErrors) > 0 {
+		return res, bwe
+	}
+	return res, nil
+}
+
+func TestMongoSinkBulkUpserts(t *testing.T) {
+	jobs := testJobs()
+	changed := jobs[1]
+	changed.Title = "Old title"
+	coll := &fakeCollection{
+		docs:      map[string]Job{jobs[0].Key(): jobs[0], jobs[1].Key(): changed},
+		failOnIDs: map[string]bool{"x2": true},
+	}
+	s := &MongoSink{collection: coll, batchSize: 2}
+
+	res, err := s.Write(context.Background(), jobs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if coll.calls != 2 || coll.deletes != 3 {
+		t.Errorf("%d BulkWrite calls, %d legacy deletes, want 2 batches and 3", coll.calls, coll.deletes)
+	}
+	if res.Written != 2 || res.Inserted != 0 || res.Updated != 1 {
+		t.Errorf("result = %+v", res)
+	}
+	if len(res.Failed) != 1 || res.Failed[0] != "Xing:x2" {
+		t.Errorf("failed = %v", res.Failed)
+	}
+
+	// A second run with the fix in place inserts only the job that failed
+	coll.failOnIDs = nil
+	res, _ = s.Write(context.Background(), jobs)
+	if res.Inserted != 1 || res.Updated != 0 || res.Written != 3 || len(coll.docs) != 3 {
+		t.Errorf("rerun = %+v, %d docs", res, len(coll.docs))
+	}
+}
+
+func TestMongoSinkFailsBatchOnConnectionError(t *testing.T) {
+	s := &MongoSink{collection: brokenCollection{}}
+	res, err := s.Write(context.Background(), testJobs())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Written != 0 || len(res.Failed) != 3 {
+		t.Errorf("result = %+v", res)
+	}
+}
+
+type brokenCollection struct{}
+
+func (brokenCollection) BulkWrite(ctx context.Context, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error) {
+	return nil, errors.New("server selection timeout")
+}
@@ -0,0 +1,144 @@
+package sink
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	_ "github.com/lib/pq"
+)
+
+// SQLSink upserts jobs into a table keyed by (source, job_id), one row per job with the
+// description flattened and the application links as a JSON array. The SQL sticks to
+// what PostgreSQL and SQLite share, so tests run it against SQLite.
+type SQLSink struct {
+	db    *sql.DB
+	table string
+	owned bool // opened by the sink, closed with it
+}
+
+var tableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// NewPostgresSink connects to dsn and creates the table if needed
+func NewPostgresSink(ctx context.Context, dsn, table string) (*SQLSink, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("postgres sink: %v", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("postgres sink: connect: %v", err)
+	}
+	s, err := NewSQLSink(ctx, db, table)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	s.owned = true
+	return s, nil
+}
+
+// NewSQLSink uses an open database, e.g. a SQLite stand-in
+func NewSQLSink(ctx context.Context, db *sql.DB, table string) (*SQLSink, error) {
+	if !tableName.MatchString(table) {
+		return nil, fmt.Errorf("sql sink: invalid table name %q", table)
+	}
+	_, err := db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			source TEXT NOT NULL,
+			job_id TEXT NOT NULL,
+			row_id TEXT,
+			title TEXT,
+			company TEXT,
+			location TEXT,
+			posted_date TEXT,
+			link TEXT,
+			processed BOOLEAN,
+			job_description TEXT,
+			job_type TEXT,
+			skills TEXT,
+			application_links TEXT,
+			has_application_link BOOLEAN,
+			uploaded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (source, job_id)
+		)`, table))
+	if err != nil {
+		return nil, fmt.Errorf("sql sink: create table: %v", err)
+	}
+	return &SQLSink{db: db, table: table}, nil
+}
+
+func (s *SQLSink) Name() string { return "postgres" }
+
+func (s *SQLSink) Write(ctx context.Context, jobs []Job) (Result, error) {
+	res := Result{Sink: s.Name(), Failed: []string{}}
+	if len(jobs) == 0 {
+		return res, nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return res, fmt.Errorf("sql sink: %v", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s (source, job_id, row_id, title, company, location, posted_date, link, processed,
+			job_description, job_type, skills, application_links, has_application_link)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		ON CONFLICT (source, job_id) DO UPDATE SET
+			row_id = excluded.row_id,
+			title = excluded.title,
+			company = excluded.company,
+			location = excluded.location,
+			posted_date = excluded.posted_date,
+			link = excluded.link,
+			processed = excluded.processed,
+			job_description = excluded.job_description,
+			job_type = excluded.job_type,
+			skills = excluded.skills,
+			application_links = excluded.application_links,
+			has_application_link = excluded.has_application_link,
+			uploaded_at = CURRENT_TIMESTAMP`, s.table))
+	if err != nil {
+		return res, fmt.Errorf("sql sink: prepare: %v", err)
+	}
+	defer stmt.Close()
+
+	for _, job := range jobs {
+		desc := job.Description
+		if desc == nil {
+			desc = &Description{}
+		}
+		links, _ := json.Marshal(job.ApplicationLinks)
+		_, err := stmt.ExecContext(ctx, job.Source, job.JobID, job.ID, job.Title, job.Company, job.Location, job.PostedDate,
+			job.Link, job.Processed, nullIfEmpty(desc.Text), nullIfEmpty(desc.JobType), nullIfEmpty(strings.Join(desc.Skills, ", ")),
+			string(links), job.HasApplicationLink)
+		if err != nil {
+			// One bad row aborts a PostgreSQL transaction, so the batch fails as a whole
+			return res, fmt.Errorf("sql sink: upsert %s: %v", job.Key(), err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return res, fmt.Errorf("sql sink: commit: %v", err)
+	}
+	res.Written = len(jobs)
+	return res, nil
+}
+
+func nullIfEmpty(v string) interface{} {
+	if v == "" {
+		return nil
+	}
+	return v
+}
+
+func (s *SQLSink) Close(ctx context.Context) error {
+	if s.owned {
+		return s.db.Close()
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// SignatureHeader carries "sha256=<hex HMAC-SHA256 of the body>" keyed with the shared secret
+const SignatureHeader = "X-Signature-256"
+
+// WebhookSink POSTs jobs in batches to an HTTP endpoint, signing each body
+type WebhookSink struct {
+	url       string
+	secret    []byte
+	batchSize int
+	client    *http.Client
+}
+
+// WebhookPayload is the JSON body of every webhook call
+type WebhookPayload struct {
+	SentAt time.Time `json:"sent_at"`
+	Jobs   []Job     `json:"jobs"`
+}
+
+func NewWebhookSink(url, secret string, batchSize int) *WebhookSink {
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	return &WebhookSink{url: url, secret: []byte(secret), batchSize: batchSize, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+func (s *WebhookSink) Name() string { return "webhook" }
+
+// Sign returns the signature header value for body
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *WebhookSink) Write(ctx context.Context, jobs []Job) (Result, error) {
+	res := Result{Sink: s.Name(), Failed: []string{}}
+	for start := 0; start < len(jobs); start += s.batchSize {
+		end := start + s.batchSize
+		if end > len(jobs) {
+			end = len(jobs)
+		}
+		batch := jobs[start:end]
+
+		if err := s.post(ctx, batch); err != nil {
+			log.Printf("❌ Webhook batch of %d jobs failed: %v", len(batch), err)
+			for _, job := range batch {
+				res.Failed = append(res.Failed, job.Key())
+			}
+			continue
+		}
+		res.Written += len(batch)
+	}
+	return res, nil
+}
+
+func (s *WebhookSink) post(ctx context.Context, batch []Job) error {
+	body, err := json.Marshal(WebhookPayload{SentAt: time.Now().UTC(), Jobs: batch})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(s.secret, body))
+	req.Header.Set("Idempotency-Key", batchKey(batch))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// batchKey derives the Idempotency-Key header from the jobs' own keys, so a retried
+// batch carries the same header; receivers can also dedupe per job
+func batchKey(batch []Job) string {
+	h := sha256.New()
+	for _, job := range batch {
+		key := job.IdempotencyKey
+		if key == "" {
+			key = job.Key()
+		}
+		h.Write([]byte(key + "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (s *WebhookSink) Close(ctx context.Context) error { return nil }
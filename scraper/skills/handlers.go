@@ -0,0 +1,227 @@
+package skills
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SkillCount is one row of the /skills response
+type SkillCount struct {
+	ID       int64          `json:"id"`
+	Name     string         `json:"name"`
+	Category string         `json:"category"`
+	Total    int            `json:"total"`
+	BySource map[string]int `json:"by_source"`
+}
+
+// SkillsHandler lists canonical skills with job counts per source.
+// Query params: source, category, limit (default 100).
+func SkillsHandler(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit := parseLimit(q.Get("limit"), 100)
+
+	query := `
+		SELECT s.id, s.name, s.category, js.source, COUNT(*)
+		FROM skills s
+		JOIN job_skills js ON js.skill_id = s.id
+		WHERE 1 = 1`
+	var args []interface{}
+	if source := q.Get("source"); source != "" {
+		query += ` AND js.source = ?`
+		args = append(args, source)
+	}
+	if category := q.Get("category"); category != "" {
+		query += ` AND s.category = ?`
+		args = append(args, category)
+	}
+	query += ` GROUP BY s.id, js.source`
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		http.Error(w, "Failed to fetch skills", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	byID := make(map[int64]*SkillCount)
+	for rows.Next() {
+		var (
+			sc     SkillCount
+			source string
+			count  int
+		)
+		if err := rows.Scan(&sc.ID, &sc.Name, &sc.Category, &source, &count); err != nil {
+			http.Error(w, "Failed to scan skill", http.StatusInternalServerError)
+			return
+		}
+		existing, ok := byID[sc.ID]
+		if !ok {
+			sc.BySource = make(map[string]int)
+			existing = &sc
+			byID[sc.ID] = existing
+		}
+		existing.BySource[source] = count
+		existing.Total += count
+	}
+
+	skills := make([]SkillCount, 0, len(byID))
+	for _, sc := range byID {
+		skills = append(skills, *sc)
+	}
+	sort.Slice(skills, func(i, j int) bool {
+		if skills[i].Total != skills[j].Total {
+			return skills[i].Total > skills[j].Total
+		}
+		return skills[i].Name < skills[j].Name
+	})
+	if len(skills) > limit {
+		skills = skills[:limit]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(skills)
+}
+
+// TrendPoint is the number of jobs mentioning a skill in one period and source
+type TrendPoint struct {
+	Period string `json:"period"`
+	Source string `json:"source"`
+	Count  int    `json:"count"`
+}
+
+type SkillTrend struct {
+	Skill  string       `json:"skill"`
+	Points []TrendPoint `json:"points"`
+}
+
+// periodFormats maps the interval query param to strftime formats
+var periodFormats = map[string]string{
+	"day":   "%Y-%m-%d",
+	"week":  "%Y-W%W",
+	"month": "%Y-%m",
+}
+
+// JobDateExpr is the best known date of a job: its posting date when the listing had
+// one, otherwise the day its skills were indexed. Expects all_jobs as j and job_skills as js.
+const JobDateExpr = `COALESCE(date(j.posted_date), date(js.created_at))`
+
+// SkillTrendsHandler returns job counts per skill over time and per source.
+// Query params: skill (repeatable; defaults to the top `limit` skills), interval
+// (day, week, month; default week), source.
+func SkillTrendsHandler(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	interval := q.Get("interval")
+	if interval == "" {
+		interval = "week"
+	}
+	format, ok := periodFormats[interval]
+	if !ok {
+		http.Error(w, "interval must be one of day, week, month", http.StatusBadRequest)
+		return
+	}
+
+	names := q["skill"]
+	if len(names) == 0 {
+		top, err := topSkillNames(db, q.Get("source"), parseLimit(q.Get("limit"), 10))
+		if err != nil {
+			http.Error(w, "Failed to fetch skills", http.StatusInternalServerError)
+			return
+		}
+		names = top
+	}
+
+	trends := []SkillTrend{}
+	if len(names) > 0 {
+		query := fmt.Sprintf(`
+			SELECT s.name, strftime('%s', %s) AS period, js.source, COUNT(*)
+			FROM job_skills js
+			JOIN skills s ON s.id = js.skill_id
+			LEFT JOIN all_jobs j ON j.source = js.source AND j.id = js.job_id
+			WHERE s.name IN (%s)`, format, JobDateExpr, placeholders(len(names)))
+		args := make([]interface{}, 0, len(names)+1)
+		for _, n := range names {
+			args = append(args, n)
+		}
+		if source := q.Get("source"); source != "" {
+			query += ` AND js.source = ?`
+			args = append(args, source)
+		}
+		query += ` GROUP BY s.name, period, js.source ORDER BY s.name, period, js.source`
+
+		rows, err := db.Query(query, args...)
+		if err != nil {
+			http.Error(w, "Failed to fetch skill trends", http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		index := make(map[string]int)
+		for rows.Next() {
+			var name string
+			var p TrendPoint
+			if err := rows.Scan(&name, &p.Period, &p.Source, &p.Count); err != nil {
+				http.Error(w, "Failed to scan skill trend", http.StatusInternalServerError)
+				return
+			}
+			i, ok := index[name]
+			if !ok {
+				i = len(trends)
+				index[name] = i
+				trends = append(trends, SkillTrend{Skill: name})
+			}
+			trends[i].Points = append(trends[i].Points, p)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"interval": interval,
+		"trends":   trends,
+	})
+}
+
+func topSkillNames(db *sql.DB, source string, limit int) ([]string, error) {
+	query := `
+		SELECT s.name FROM job_skills js
+		JOIN skills s ON s.id = js.skill_id`
+	var args []interface{}
+	if source != "" {
+		query += ` WHERE js.source = ?`
+		args = append(args, source)
+	}
+	query += ` GROUP BY s.id ORDER BY COUNT(*) DESC, s.name LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+func parseLimit(raw string, fallback int) int {
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		return fallback
+	}
+	return n
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
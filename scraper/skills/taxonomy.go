@@ -0,0 +1,249 @@
+package skills
+
+import (
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+//go:embed aliases.json
+var bundledAliases []byte
+
+// Dictionary is the bundled alias file: one entry per canonical skill
+type Dictionary struct {
+	Version int               `json:"version"`
+	Skills  []DictionaryEntry `json:"skills"`
+}
+
+type DictionaryEntry struct {
+	Name     string   `json:"name"`
+	Category string   `json:"category"`
+	Aliases  []string `json:"aliases"`
+}
+
+// CategoryOther is used for skills that are not in the bundled dictionary yet
+const CategoryOther = "other"
+
+// LoadDictionary parses the bundled alias dictionary
+func LoadDictionary() (Dictionary, error) {
+	var dict Dictionary
+	if err := json.Unmarshal(bundledAliases, &dict); err != nil {
+		return dict, fmt.Errorf("failed to parse bundled skill aliases: %v", err)
+	}
+	return dict, nil
+}
+
+// SeedTaxonomy loads the bundled dictionary into the skills and skill_aliases tables.
+// It is safe to run on every start; categories are refreshed and aliases added.
+func SeedTaxonomy(db *sql.DB) error {
+	dict, err := LoadDictionary()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, entry := range dict.Skills {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO skills (name, category) VALUES (?, ?)`,
+			entry.Name, entry.Category); err != nil {
+			return fmt.Errorf("failed to seed skill %s: %v", entry.Name, err)
+		}
+		if _, err := tx.Exec(`UPDATE skills SET category = ? WHERE name = ?`,
+			entry.Category, entry.Name); err != nil {
+			return fmt.Errorf("failed to seed skill %s: %v", entry.Name, err)
+		}
+
+		// The dictionary wins over aliases that were auto-created for unknown skills
+		for _, alias := range append([]string{entry.Name}, entry.Aliases...) {
+			if _, err := tx.Exec(`
+				INSERT INTO skill_aliases (alias, skill_id)
+				SELECT ?, id FROM skills WHERE name = ?
+				ON CONFLICT (alias) DO UPDATE SET skill_id = excluded.skill_id`,
+				aliasKey(alias), entry.Name,
+			); err != nil {
+				return fmt.Errorf("failed to seed alias %s: %v", alias, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("🏷️ Skill taxonomy v%d seeded with %d skills\n", dict.Version, len(dict.Skills))
+	return nil
+}
+
+var (
+	parenthetical = regexp.MustCompile(`\([^)]*\)`)
+	skillSplitter = regexp.MustCompile(`[,;•\n|]+`)
+)
+
+// aliasKey reduces a skill to the form aliases are matched on:
+// "JavaScript (ES6)", "javascript" and "Java-Script" all become "javascript"
+func aliasKey(raw string) string {
+	raw = parenthetical.ReplaceAllString(raw, " ")
+	var b strings.Builder
+	for _, r := range strings.ToLower(raw) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '+' || r == '#' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// SplitRaw breaks LLM output into individual skill strings. Models return either a
+// list or one comma-joined string, and list items sometimes contain several skills.
+func SplitRaw(raw []string) []string {
+	var out []string
+	for _, item := range raw {
+		for _, part := range skillSplitter.Split(item, -1) {
+			part = strings.Trim(strings.TrimSpace(part), ".-*\"'")
+			part = strings.TrimSpace(part)
+			if part == "" || len(part) > 60 {
+				continue
+			}
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// Resolve maps a raw skill to its canonical skill id, creating an "other" skill for
+// names the dictionary does not know so they can be aliased later.
+func Resolve(tx *sql.Tx, raw string) (int64, bool, error) {
+	key := aliasKey(raw)
+	if key == "" {
+		return 0, false, nil
+	}
+
+	var id int64
+	err := tx.QueryRow(`SELECT skill_id FROM skill_aliases WHERE alias = ?`, key).Scan(&id)
+	if err == nil {
+		return id, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, false, fmt.Errorf("failed to look up skill alias %q: %v", raw, err)
+	}
+
+	name := strings.TrimSpace(parenthetical.ReplaceAllString(raw, ""))
+	if name == "" {
+		name = raw
+	}
+	if _, err := tx.Exec(`INSERT OR IGNORE INTO skills (name, category) VALUES (?, ?)`, name, CategoryOther); err != nil {
+		return 0, false, fmt.Errorf("failed to create skill %q: %v", name, err)
+	}
+	if err := tx.QueryRow(`SELECT id FROM skills WHERE name = ?`, name).Scan(&id); err != nil {
+		return 0, false, fmt.Errorf("failed to read skill %q: %v", name, err)
+	}
+	if _, err := tx.Exec(`INSERT OR IGNORE INTO skill_aliases (alias, skill_id) VALUES (?, ?)`, key, id); err != nil {
+		return 0, false, fmt.Errorf("failed to alias skill %q: %v", name, err)
+	}
+	return id, true, nil
+}
+
+// IndexJob replaces the normalized skills of one job with the given raw skills
+func IndexJob(db *sql.DB, source, jobID string, rawSkills []string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM job_skills WHERE source = ? AND job_id = ?`, source, jobID); err != nil {
+		return fmt.Errorf("failed to clear skills for job %s: %v", jobID, err)
+	}
+
+	for _, raw := range SplitRaw(rawSkills) {
+		id, ok, err := Resolve(tx, raw)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO job_skills (source, job_id, skill_id) VALUES (?, ?, ?)`,
+			source, jobID, id); err != nil {
+			return fmt.Errorf("failed to link skill for job %s: %v", jobID, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Backfill normalizes the comma-joined skills of descriptions that were summarized
+// before the taxonomy existed
+func Backfill(db *sql.DB) (int, error) {
+	type pending struct{ source, jobID, skills string }
+	var todo []pending
+
+	for _, src := range []struct{ name, table string }{
+		{"LinkedIn", "linkedin_job_description"},
+		{"Xing", "xing_job_description"},
+	} {
+		rows, err := db.Query(fmt.Sprintf(`
+			SELECT d.job_id, d.skills FROM %s d
+			WHERE d.skills IS NOT NULL AND d.skills != ''
+			AND NOT EXISTS (SELECT 1 FROM job_skills js WHERE js.source = ? AND js.job_id = d.job_id)`, src.table),
+			src.name,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to load %s skills: %v", src.name, err)
+		}
+		for rows.Next() {
+			p := pending{source: src.name}
+			if err := rows.Scan(&p.jobID, &p.skills); err != nil {
+				rows.Close()
+				return 0, fmt.Errorf("failed to scan %s skills: %v", src.name, err)
+			}
+			todo = append(todo, p)
+		}
+		rows.Close()
+	}
+
+	for _, p := range todo {
+		if err := IndexJob(db, p.source, p.jobID, []string{p.skills}); err != nil {
+			return 0, err
+		}
+	}
+	return len(todo), nil
+}
+
+// NamesByJob returns the canonical skill names of the given jobs of a source
+func NamesByJob(db *sql.DB, source string, jobIDs []string) (map[string][]string, error) {
+	byJob := make(map[string][]string)
+	if len(jobIDs) == 0 {
+		return byJob, nil
+	}
+
+	args := []interface{}{source}
+	for _, id := range jobIDs {
+		args = append(args, id)
+	}
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT js.job_id, s.name FROM job_skills js
+		JOIN skills s ON s.id = js.skill_id
+		WHERE js.source = ? AND js.job_id IN (%s)
+		ORDER BY js.job_id, s.name`, strings.TrimSuffix(strings.Repeat("?, ", len(jobIDs)), ", ")), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load job skills: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var jobID, name string
+		if err := rows.Scan(&jobID, &name); err != nil {
+			return nil, fmt.Errorf("failed to scan job skill: %v", err)
+		}
+		byJob[jobID] = append(byJob[jobID], name)
+	}
+	return byJob, rows.Err()
+}
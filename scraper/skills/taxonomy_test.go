@@ -0,0 +1,124 @@
+package skills
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"job_scraper/config"
+)
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := config.InitializeDatabaseAt(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := SeedTaxonomy(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestAliasKey(t *testing.T) {
+	for raw, want := range map[string]string{
+		"JavaScript (ES6)": "javascript",
+		"Java-Script":      "javascript",
+		"  Golang ":        "golang",
+		"C++":              "c++",
+		"C#":               "c#",
+		"Node.js":          "nodejs",
+		"Python 3":         "python3",
+		"Größe":            "größe",
+		"(optional)":       "",
+	} {
+		if got := aliasKey(raw); got != want {
+			t.Errorf("aliasKey(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestSplitRaw(t *testing.T) {
+	got := SplitRaw([]string{"Go, Docker; Kubernetes", "• SQL\n- Git -", "", `"AWS".`, "a very long sentence that is certainly not a skill but a whole paragraph"})
+	want := []string{"Go", "Docker", "Kubernetes", "SQL", "Git", "AWS"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("SplitRaw = %q, want %q", got, want)
+	}
+}
+
+func TestBundledAliasesAreUnambiguous(t *testing.T) {
+	dict, err := LoadDictionary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner := map[string]string{}
+	for _, entry := range dict.Skills {
+		for _, alias := range append([]string{entry.Name}, entry.Aliases...) {
+			key := aliasKey(alias)
+			if prev, ok := owner[key]; ok && prev != entry.Name {
+				t.Errorf("alias %q maps to both %s and %s", alias, prev, entry.Name)
+			}
+			owner[key] = entry.Name
+		}
+	}
+}
+
+func TestIndexJobResolvesAliases(t *testing.T) {
+	db := newTestDB(t)
+	for _, c := range []struct {
+		job  string
+		raw  []string
+		want []string
+	}{
+		{"job-1", []string{"Golang, JS", "JavaScript (ES6)", "CSharp"}, []string{"C#", "Go", "JavaScript"}},
+		{"job-2", []string{"Python3; C plus plus", "TS"}, []string{"C++", "Python", "TypeScript"}},
+		// Unknown skills become skills of their own, matched again by their alias key
+		{"job-3", []string{"Quarkus (framework)", "quarkus", "Go lang"}, []string{"Go", "Quarkus"}},
+	} {
+		if err := IndexJob(db, "LinkedIn", c.job, c.raw); err != nil {
+			t.Fatal(err)
+		}
+		names, err := NamesByJob(db, "LinkedIn", []string{c.job})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(names[c.job]) != fmt.Sprint(c.want) {
+			t.Errorf("%s: skills = %q, want %q", c.job, names[c.job], c.want)
+		}
+	}
+
+	var category string
+	if err := db.QueryRow(`SELECT category FROM skills WHERE name = 'Quarkus'`).Scan(&category); err != nil || category != CategoryOther {
+		t.Errorf("Quarkus category = %q (%v), want %s", category, err, CategoryOther)
+	}
+
+	// Re-indexing replaces the job's skills instead of adding to them
+	if err := IndexJob(db, "LinkedIn", "job-1", []string{"Rust"}); err != nil {
+		t.Fatal(err)
+	}
+	if names, _ := NamesByJob(db, "LinkedIn", []string{"job-1"}); fmt.Sprint(names["job-1"]) != "[Rust]" {
+		t.Errorf("re-indexed skills = %q, want [Rust]", names["job-1"])
+	}
+}
+
+func TestSeedTaxonomyTakesOverAutoCreatedAliases(t *testing.T) {
+	db := newTestDB(t)
+	// An alias created for an unknown skill before the dictionary learned it
+	if _, err := db.Exec(`INSERT INTO skills (name, category) VALUES ('golang', 'other')`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`UPDATE skill_aliases SET skill_id = (SELECT id FROM skills WHERE name = 'golang') WHERE alias = 'golang'`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SeedTaxonomy(db); err != nil {
+		t.Fatal(err)
+	}
+	var name string
+	if err := db.QueryRow(`
+		SELECT s.name FROM skill_aliases a JOIN skills s ON s.id = a.skill_id WHERE a.alias = 'golang'`).Scan(&name); err != nil || name != "Go" {
+		t.Errorf("golang resolves to %q (%v), want Go", name, err)
+	}
+}
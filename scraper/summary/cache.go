@@ -0,0 +1,175 @@
+package summary
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// CacheKey identifies a cached summary. The same cleaned description sent to the
+// same provider/model with the same prompt always produces a reusable result,
+// regardless of which job ID or source it came from.
+type CacheKey struct {
+	DescriptionHash string
+	Provider        string
+	Model           string
+	PromptVersion   string
+}
+
+// NewCacheKey builds a cache key for a cleaned job description
+func NewCacheKey(description, provider, model, promptVersion string) CacheKey {
+	return CacheKey{
+		DescriptionHash: HashDescription(description),
+		Provider:        provider,
+		Model:           model,
+		PromptVersion:   promptVersion,
+	}
+}
+
+// HashDescription returns the sha256 of the description with whitespace collapsed,
+// so reposts that only differ in line breaks or indentation share a cache entry
+func HashDescription(description string) string {
+	normalized := strings.Join(strings.Fields(description), " ")
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// In-process counters since server start, one per Summarize call whatever the number
+// of providers looked up; lifetime hits are kept in the table
+var (
+	cacheHits   atomic.Int64
+	cacheMisses atomic.Int64
+)
+
+// countLookup records the outcome of one Summarize call's cache lookup
+func countLookup(hit bool) {
+	if hit {
+		cacheHits.Add(1)
+	} else {
+		cacheMisses.Add(1)
+	}
+}
+
+// LookupCached returns the cached summary for key, if any. It does not touch the
+// hit/miss counters; Summarize counts once for all its providers.
+func LookupCached(db *sql.DB, key CacheKey) (string, bool) {
+	var cached string
+	err := db.QueryRow(`
+		SELECT summary FROM summary_cache
+		WHERE description_hash = ? AND provider = ? AND model = ? AND prompt_version = ?`,
+		key.DescriptionHash, key.Provider, key.Model, key.PromptVersion,
+	).Scan(&cached)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("⚠️ Summary cache lookup failed: %v\n", err)
+		}
+		return "", false
+	}
+
+	if _, err := db.Exec(`
+		UPDATE summary_cache
+		SET hit_count = hit_count + 1, last_hit_at = CURRENT_TIMESTAMP
+		WHERE description_hash = ? AND provider = ? AND model = ? AND prompt_version = ?`,
+		key.DescriptionHash, key.Provider, key.Model, key.PromptVersion,
+	); err != nil {
+		log.Printf("⚠️ Failed to bump summary cache hit count: %v\n", err)
+	}
+	return cached, true
+}
+
+// StoreCached saves a summary under key, replacing any previous entry
+func StoreCached(db *sql.DB, key CacheKey, summary string) error {
+	_, err := db.Exec(`
+		INSERT INTO summary_cache (description_hash, provider, model, prompt_version, summary)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (description_hash, provider, model, prompt_version)
+		DO UPDATE SET summary = excluded.summary, created_at = CURRENT_TIMESTAMP`,
+		key.DescriptionHash, key.Provider, key.Model, key.PromptVersion, summary,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store cached summary: %v", err)
+	}
+	return nil
+}
+
+// DeleteCached drops the entry under key, e.g. one that no longer parses
+func DeleteCached(db *sql.DB, key CacheKey) error {
+	_, err := db.Exec(`
+		DELETE FROM summary_cache
+		WHERE description_hash = ? AND provider = ? AND model = ? AND prompt_version = ?`,
+		key.DescriptionHash, key.Provider, key.Model, key.PromptVersion,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete cached summary: %v", err)
+	}
+	return nil
+}
+
+// ProviderCacheStats summarizes the stored entries for one provider/model pair
+type ProviderCacheStats struct {
+	Provider     string `json:"provider"`
+	Model        string `json:"model"`
+	Entries      int    `json:"entries"`
+	LifetimeHits int64  `json:"lifetime_hits"`
+}
+
+// CacheStats is returned by the cache stats endpoint
+type CacheStats struct {
+	Hits         int64                `json:"hits"`
+	Misses       int64                `json:"misses"`
+	HitRate      float64              `json:"hit_rate"`
+	Entries      int                  `json:"entries"`
+	LifetimeHits int64                `json:"lifetime_hits"`
+	Providers    []ProviderCacheStats `json:"providers"`
+}
+
+// GetCacheStats combines the in-process counters with the stored entry counts
+func GetCacheStats(db *sql.DB) (CacheStats, error) {
+	stats := CacheStats{
+		Hits:      cacheHits.Load(),
+		Misses:    cacheMisses.Load(),
+		Providers: []ProviderCacheStats{},
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+
+	rows, err := db.Query(`
+		SELECT provider, model, COUNT(*), COALESCE(SUM(hit_count), 0)
+		FROM summary_cache
+		GROUP BY provider, model
+		ORDER BY provider, model`)
+	if err != nil {
+		return stats, fmt.Errorf("failed to query summary cache: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p ProviderCacheStats
+		if err := rows.Scan(&p.Provider, &p.Model, &p.Entries, &p.LifetimeHits); err != nil {
+			return stats, fmt.Errorf("failed to scan summary cache row: %v", err)
+		}
+		stats.Entries += p.Entries
+		stats.LifetimeHits += p.LifetimeHits
+		stats.Providers = append(stats.Providers, p)
+	}
+	return stats, rows.Err()
+}
+
+// CacheStatsHandler reports summarization cache hit/miss statistics
+func CacheStatsHandler(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	stats, err := GetCacheStats(db)
+	if err != nil {
+		http.Error(w, "Failed to fetch summary cache stats", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
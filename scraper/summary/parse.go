@@ -0,0 +1,92 @@
+package summary
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Struct for the job summary details
+type FlexibleJobSummary struct {
+	JobType     string   `json:"job_type"`
+	Skills      []string `json:"skills"`
+	Description string   `json:"description"`
+}
+
+// Unmarshal the JSON returned by the models, which do not agree on the shape of `skills`
+func (f *FlexibleJobSummary) UnmarshalJSON(data []byte) error {
+	type Alias FlexibleJobSummary
+	aux := &struct {
+		Skills interface{} `json:"skills"`
+		*Alias
+	}{
+		Alias: (*Alias)(f),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	// Normalize the `skills` field
+	switch v := aux.Skills.(type) {
+	case []interface{}:
+		for _, s := range v {
+			if str, ok := s.(string); ok {
+				f.Skills = append(f.Skills, str)
+			}
+		}
+	case map[string]interface{}:
+		for k := range v {
+			f.Skills = append(f.Skills, k)
+		}
+	case string:
+		f.Skills = []string{v}
+	default:
+		f.Skills = []string{}
+	}
+
+	return nil
+}
+
+// ParseSummary parses a model's output and checks that it has the fields the
+// description tables need, so broken output is neither stored nor cached
+func ParseSummary(output string) (FlexibleJobSummary, error) {
+	var parsed FlexibleJobSummary
+	if err := json.Unmarshal([]byte(strings.TrimSpace(output)), &parsed); err != nil {
+		return parsed, fmt.Errorf("failed to parse structured summary: %v", err)
+	}
+	if strings.TrimSpace(parsed.JobType) == "" || strings.TrimSpace(parsed.Description) == "" {
+		return parsed, errors.New("structured summary is missing job_type or description")
+	}
+	return parsed, nil
+}
+
+func extractAfter(text, key, end string) string {
+	idx := strings.Index(text, key)
+	if idx == -1 {
+		return ""
+	}
+	start := idx + len(key)
+	if end == "" {
+		return strings.TrimSpace(text[start:])
+	}
+	endIdx := strings.Index(text[start:], end)
+	if endIdx == -1 {
+		return strings.TrimSpace(text[start:])
+	}
+	return strings.TrimSpace(text[start : start+endIdx])
+}
+
+func splitSkills(raw string) []string {
+	raw = strings.ReplaceAll(raw, "•", "")
+	parts := strings.Split(raw, ",")
+	var skills []string
+	for _, s := range parts {
+		skill := strings.TrimSpace(s)
+		if skill != "" {
+			skills = append(skills, skill)
+		}
+	}
+	return skills
+}
@@ -0,0 +1,214 @@
+package summary
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Provider turns a cleaned job description into a structured JSON summary
+type Provider interface {
+	// Name, Model and PromptVersion make up the cache key for this provider
+	Name() string
+	Model() string
+	PromptVersion() string
+	Generate(ctx context.Context, jobDescription string) (string, error)
+}
+
+// OllamaProvider calls a local Ollama server (used for LinkedIn descriptions)
+type OllamaProvider struct {
+	URL       string
+	ModelName string
+}
+
+// NewOllamaProvider reads OLLAMA_URL and OLLAMA_MODEL, defaulting to a local mistral
+func NewOllamaProvider() *OllamaProvider {
+	p := &OllamaProvider{
+		URL:       os.Getenv("OLLAMA_URL"),
+		ModelName: os.Getenv("OLLAMA_MODEL"),
+	}
+	if p.URL == "" {
+		p.URL = "http://localhost:11434"
+	}
+	if p.ModelName == "" {
+		p.ModelName = "mistral"
+	}
+	return p
+}
+
+func (p *OllamaProvider) Name() string { return "ollama" }
+
+func (p *OllamaProvider) Model() string { return p.ModelName }
+
+// Bump whenever the prompt in Generate changes
+func (p *OllamaProvider) PromptVersion() string { return "v1" }
+
+// Struct to hold the API response from Ollama
+type OllamaResponse struct {
+	Response string `json:"response"`
+}
+
+func (p *OllamaProvider) Generate(ctx context.Context, jobDescription string) (string, error) {
+	prompt := fmt.Sprintf(`
+	Extract and return the following from this job posting as JSON:
+	{
+	  "job_type": "One word like Remote, On-site, or Hybrid",
+	  "skills": ["List at least 5 key technical skills or tools"],
+	  "description": "Professional summary of the role in full sentences(20 lines or 500 words)"
+	}
+	
+	Only return valid JSON. No extra text.
+	
+	Job posting:
+	"%s"
+	`, jobDescription)
+
+	payload := map[string]interface{}{
+		"model":  p.ModelName,
+		"prompt": prompt,
+		"stream": false,
+	}
+
+	body, err := postJSON(ctx, strings.TrimRight(p.URL, "/")+"/api/generate", "", payload)
+	if err != nil {
+		return "", fmt.Errorf("ollama request failed: %v", err)
+	}
+
+	var response OllamaResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("failed to parse Ollama response: %v", err)
+	}
+	if response.Response == "" {
+		return "", fmt.Errorf("ollama returned an empty response")
+	}
+
+	output := response.Response
+	start := strings.Index(output, "{")
+	end := strings.LastIndex(output, "}")
+	if start != -1 && end != -1 && end > start {
+		return output[start : end+1], nil
+	}
+
+	// Manual fallback
+	fallback := FlexibleJobSummary{
+		JobType:     extractAfter(output, "Job Type:", "\n"),
+		Skills:      splitSkills(extractAfter(output, "Skills Required:", "\n")),
+		Description: extractAfter(output, "Description:", ""),
+	}
+
+	jsonBytes, err := json.Marshal(fallback)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal fallback JSON: %v", err)
+	}
+	return string(jsonBytes), nil
+}
+
+// HuggingFaceProvider calls one hosted inference endpoint (used for Xing descriptions)
+type HuggingFaceProvider struct {
+	URL string
+	Key string
+}
+
+type HuggingFaceResponse struct {
+	GeneratedText string `json:"generated_text"`
+}
+
+// HuggingFaceProvidersFromEnv builds the fallback chain from HF_MODEL_1 .. HF_MODEL_10
+func HuggingFaceProvidersFromEnv() []Provider {
+	apiKey := os.Getenv("HF_API_KEY")
+
+	var providers []Provider
+	for i := 1; i <= 10; i++ {
+		modelURL := os.Getenv(fmt.Sprintf("HF_MODEL_%d", i))
+		if modelURL != "" {
+			providers = append(providers, &HuggingFaceProvider{URL: modelURL, Key: apiKey})
+		}
+	}
+	return providers
+}
+
+func (p *HuggingFaceProvider) Name() string { return "huggingface" }
+
+func (p *HuggingFaceProvider) Model() string { return p.URL }
+
+// Bump whenever the prompt in Generate changes
+func (p *HuggingFaceProvider) PromptVersion() string { return "v1" }
+
+func (p *HuggingFaceProvider) Generate(ctx context.Context, jobDescription string) (string, error) {
+	prompt := fmt.Sprintf(`Extract structured job details from the following job description and return in JSON format with fields:: 
+- "job_type (remote, part time, full time, unknown)"
+- "skills "
+- "description"
+
+Description: "%s"`, jobDescription)
+
+	payload := map[string]interface{}{
+		"inputs": prompt,
+		"parameters": map[string]interface{}{
+			"max_length":  1000,
+			"temperature": 0.3,
+		},
+	}
+
+	body, err := postJSON(ctx, p.URL, p.Key, payload)
+	if err != nil {
+		return "", fmt.Errorf("hugging face request failed: %v", err)
+	}
+
+	var result []HuggingFaceResponse
+	err = json.Unmarshal(body, &result)
+	if err != nil || len(result) == 0 {
+		return "", fmt.Errorf("failed to parse API response: %v", err)
+	}
+
+	output := result[0].GeneratedText
+
+	// Extract JSON block from output text
+	jsonStart := strings.Index(output, "{")
+	jsonEnd := strings.LastIndex(output, "}")
+	if jsonStart != -1 && jsonEnd != -1 && jsonEnd > jsonStart {
+		output = output[jsonStart : jsonEnd+1]
+	}
+
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return "", fmt.Errorf("hugging face returned an empty response")
+	}
+	return output, nil
+}
+
+func postJSON(ctx context.Context, url, bearer string, payload interface{}) ([]byte, error) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request payload: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(payloadBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
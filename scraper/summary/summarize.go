@@ -0,0 +1,100 @@
+package summary
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Summarize returns a structured summary for a cleaned description. The cache is
+// consulted for every provider first; on a miss the providers are tried in order
+// and the first result that parses is cached. Output that does not parse moves on
+// to the next provider and is never cached, so a retry asks the model again.
+func Summarize(ctx context.Context, db *sql.DB, providers []Provider, limiter *RateLimiter, jobDescription string) (FlexibleJobSummary, error) {
+	if len(providers) == 0 {
+		return FlexibleJobSummary{}, fmt.Errorf("no summarization providers configured")
+	}
+
+	for _, p := range providers {
+		cacheKey := NewCacheKey(jobDescription, p.Name(), p.Model(), p.PromptVersion())
+		cached, ok := LookupCached(db, cacheKey)
+		if !ok {
+			continue
+		}
+		parsed, err := ParseSummary(cached)
+		if err != nil {
+			// Cached before outputs were checked; drop it and ask again
+			log.Printf("🗑️ Dropping unusable cached summary (%s): %v\n", cacheKey.DescriptionHash[:12], err)
+			if err := DeleteCached(db, cacheKey); err != nil {
+				log.Printf("⚠️ %v\n", err)
+			}
+			continue
+		}
+		countLookup(true)
+		log.Printf("♻️ Using cached summary (%s)\n", cacheKey.DescriptionHash[:12])
+		return parsed, nil
+	}
+	countLookup(false)
+
+	var lastErr error
+	for _, p := range providers {
+		if err := limiter.Wait(ctx); err != nil {
+			return FlexibleJobSummary{}, err
+		}
+
+		output, err := p.Generate(ctx, jobDescription)
+		if err != nil {
+			log.Printf("⚠️ Failed with %s (%s): %v", p.Name(), p.Model(), err)
+			lastErr = err
+			continue
+		}
+		parsed, err := ParseSummary(output)
+		if err != nil {
+			log.Printf("⚠️ Unusable output from %s (%s): %v", p.Name(), p.Model(), err)
+			lastErr = err
+			continue
+		}
+
+		cacheKey := NewCacheKey(jobDescription, p.Name(), p.Model(), p.PromptVersion())
+		if err := StoreCached(db, cacheKey, output); err != nil {
+			log.Printf("⚠️ %v\n", err)
+		}
+		return parsed, nil
+	}
+	return FlexibleJobSummary{}, fmt.Errorf("all summarization providers failed: %v", lastErr)
+}
+
+// RateLimiter spaces out LLM calls shared by all workers. A nil limiter never blocks.
+type RateLimiter struct {
+	ticker *time.Ticker
+}
+
+// NewRateLimiter allows perMinute calls per minute; perMinute <= 0 means unlimited
+func NewRateLimiter(perMinute int) *RateLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &RateLimiter{ticker: time.NewTicker(time.Minute / time.Duration(perMinute))}
+}
+
+// Wait blocks until the next call is allowed or ctx is done
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	select {
+	case <-l.ticker.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stop releases the limiter's ticker
+func (l *RateLimiter) Stop() {
+	if l != nil {
+		l.ticker.Stop()
+	}
+}
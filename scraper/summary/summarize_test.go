@@ -0,0 +1,65 @@
+package summary
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"job_scraper/config"
+)
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := config.InitializeDatabaseAt(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// fakeProvider answers with output, or fails with err, and counts its calls
+type fakeProvider struct {
+	name   string
+	output string
+	err    error
+	calls  int
+}
+
+func (p *fakeProvider) Name() string          { return p.name }
+func (p *fakeProvider) Model() string         { return "test-model" }
+func (p *fakeProvider) PromptVersion() string { return "v1" }
+func (p *fakeProvider) Generate(ctx context.Context, jobDescription string) (string, error) {
+	p.calls++
+	return p.output, p.err
+}
+
+const validSummary = `{"job_type": "Full-time", "skills": ["Go"], "description": "Build payment services."}`
+
+func TestSummarizeCountsOneLookupPerCall(t *testing.T) {
+	db := newTestDB(t)
+	cacheHits.Store(0)
+	cacheMisses.Store(0)
+
+	providers := []Provider{
+		&fakeProvider{name: "a", err: errors.New("down")},
+		&fakeProvider{name: "b", err: errors.New("down")},
+		&fakeProvider{name: "c", output: validSummary},
+	}
+	if _, err := Summarize(context.Background(), db, providers, nil, "Go developer wanted"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Summarize(context.Background(), db, providers, nil, "Go  developer\nwanted"); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := GetCacheStats(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Hits != 1 || stats.Misses != 1 || stats.HitRate != 0.5 {
+		t.Errorf("stats = %+v, want one hit and one miss for two calls over three providers", stats)
+	}
+}
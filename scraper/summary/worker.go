@@ -0,0 +1,266 @@
+package summary
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"job_scraper/scraper/skills"
+)
+
+// Summary status values stored in the *_job_description tables
+const (
+	StatusPending = "pending"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+// Source ties a description table to the providers that summarize it
+type Source struct {
+	Name      string
+	Table     string
+	Providers []Provider
+}
+
+// DefaultSources keeps the historical split: Ollama for LinkedIn, Hugging Face for Xing
+func DefaultSources() []Source {
+	return []Source{
+		{Name: "LinkedIn", Table: "linkedin_job_description", Providers: []Provider{NewOllamaProvider()}},
+		{Name: "Xing", Table: "xing_job_description", Providers: HuggingFaceProvidersFromEnv()},
+	}
+}
+
+// Options controls the summarizer worker pool
+type Options struct {
+	Workers       int           // concurrent descriptions in flight
+	Timeout       time.Duration // per description, including retries across providers; also how long a claim holds
+	RatePerMinute int           // LLM calls per minute across all workers, 0 = unlimited
+	MaxAttempts   int           // after this many failures a description is marked failed
+	BatchSize     int           // rows fetched per query
+	PollInterval  time.Duration // 0 drains the backlog once and returns
+}
+
+// DefaultOptions is tuned for a single local Ollama instance
+func DefaultOptions() Options {
+	return Options{
+		Workers:       2,
+		Timeout:       3 * time.Minute,
+		RatePerMinute: 30,
+		MaxAttempts:   3,
+		BatchSize:     50,
+	}
+}
+
+// PoolStats is what a pool run reports back
+type PoolStats struct {
+	Summarized int64 `json:"summarized"`
+	Retrying   int64 `json:"retrying"`
+	Failed     int64 `json:"failed"`
+}
+
+type pendingDescription struct {
+	source      Source
+	id          int64
+	jobID       string
+	description string
+	attempts    int
+}
+
+// RunPool drains pending raw descriptions and stores their structured summaries.
+// It is independent of any browser session; cmd/summarizer runs it.
+func RunPool(ctx context.Context, db *sql.DB, sources []Source, opts Options) (PoolStats, error) {
+	var stats PoolStats
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultOptions().Timeout
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 50
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 1
+	}
+
+	limiter := NewRateLimiter(opts.RatePerMinute)
+	defer limiter.Stop()
+
+	queue := make(chan pendingDescription)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range queue {
+				summarizeOne(ctx, db, limiter, opts, item, &stats)
+			}
+		}()
+	}
+
+	err := feedPending(ctx, db, sources, opts, queue)
+	close(queue)
+	wg.Wait()
+
+	log.Printf("🧠 Summarizer finished: %d summarized, %d retrying, %d failed\n",
+		stats.Summarized, stats.Retrying, stats.Failed)
+	return stats, err
+}
+
+// feedPending walks each table by id so a pass sees every pending row once
+func feedPending(ctx context.Context, db *sql.DB, sources []Source, opts Options, queue chan<- pendingDescription) error {
+	for {
+		for _, src := range sources {
+			if len(src.Providers) == 0 {
+				log.Printf("⚠️ No summarization providers configured for %s, skipping\n", src.Name)
+				continue
+			}
+			if err := releaseStale(db, src, opts.Timeout); err != nil {
+				return err
+			}
+
+			var lastID int64
+			for {
+				batch, err := loadPending(db, src, lastID, opts)
+				if err != nil {
+					return err
+				}
+				for _, item := range batch {
+					select {
+					case queue <- item:
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+					lastID = item.id
+				}
+				if len(batch) < opts.BatchSize {
+					break
+				}
+			}
+		}
+
+		if opts.PollInterval <= 0 {
+			return nil
+		}
+		select {
+		case <-time.After(opts.PollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// releaseStale puts rows back to pending whose claim is older than timeout. Their
+// worker crashed or was killed: a live one gives up on a row after timeout. Claims
+// held by another summarizer process that is still working are left alone.
+func releaseStale(db *sql.DB, src Source, timeout time.Duration) error {
+	res, err := db.Exec(fmt.Sprintf(`
+		UPDATE %s SET summary_status = ?
+		WHERE summary_status = ? AND (summary_claimed_at IS NULL OR summary_claimed_at < datetime('now', ?))`, src.Table),
+		StatusPending, StatusRunning, fmt.Sprintf("-%d seconds", int(timeout.Seconds())))
+	if err != nil {
+		return fmt.Errorf("failed to release stale summaries in %s: %v", src.Table, err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		log.Printf("♻️ Released %d stale %s summary claims\n", n, src.Name)
+	}
+	return nil
+}
+
+func loadPending(db *sql.DB, src Source, afterID int64, opts Options) ([]pendingDescription, error) {
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT id, job_id, raw_description, summary_attempts
+		FROM %s
+		WHERE summary_status = ? AND raw_description IS NOT NULL AND summary_attempts < ? AND id > ?
+		ORDER BY id
+		LIMIT ?`, src.Table),
+		StatusPending, opts.MaxAttempts, afterID, opts.BatchSize,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pending descriptions from %s: %v", src.Table, err)
+	}
+	defer rows.Close()
+
+	var batch []pendingDescription
+	for rows.Next() {
+		item := pendingDescription{source: src}
+		if err := rows.Scan(&item.id, &item.jobID, &item.description, &item.attempts); err != nil {
+			return nil, fmt.Errorf("failed to scan pending description: %v", err)
+		}
+		batch = append(batch, item)
+	}
+	return batch, rows.Err()
+}
+
+func summarizeOne(ctx context.Context, db *sql.DB, limiter *RateLimiter, opts Options, item pendingDescription, stats *PoolStats) {
+	table := item.source.Table
+
+	// Claim the row so a second summarizer process does not duplicate the work
+	res, err := db.Exec(fmt.Sprintf(`
+		UPDATE %s SET summary_status = ?, summary_attempts = summary_attempts + 1, summary_claimed_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND summary_status = ?`, table),
+		StatusRunning, item.id, StatusPending,
+	)
+	if err != nil {
+		log.Printf("❌ Failed to claim description %d in %s: %v\n", item.id, table, err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return
+	}
+
+	jobCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	startTime := time.Now()
+	parsed, err := Summarize(jobCtx, db, item.source.Providers, limiter, item.description)
+
+	if err != nil {
+		log.Printf("⚠️ Failed to summarize %s job %s (attempt %d): %v\n", item.source.Name, item.jobID, item.attempts+1, err)
+		release(db, opts, item, err, stats)
+		return
+	}
+
+	skillsCSV := strings.Join(parsed.Skills, ", ")
+	_, err = db.Exec(fmt.Sprintf(`
+		UPDATE %s
+		SET job_description = ?, job_type = ?, skills = ?,
+			summary_status = ?, summary_error = NULL, summarized_at = CURRENT_TIMESTAMP
+		WHERE id = ?`, table),
+		parsed.Description, parsed.JobType, skillsCSV, StatusDone, item.id,
+	)
+	if err != nil {
+		log.Printf("❌ Failed to store summary for job %s: %v\n", item.jobID, err)
+		release(db, opts, item, fmt.Errorf("storing the summary: %v", err), stats)
+		return
+	}
+
+	if err := skills.IndexJob(db, item.source.Name, item.jobID, parsed.Skills); err != nil {
+		log.Printf("⚠️ Failed to normalize skills for job %s: %v\n", item.jobID, err)
+	}
+
+	atomic.AddInt64(&stats.Summarized, 1)
+	fmt.Printf("🧠 Summarized %s job %s in %s\n", item.source.Name, item.jobID, time.Since(startTime))
+}
+
+// release hands a claimed row back after a failed attempt: pending for another try,
+// or failed once it is out of attempts
+func release(db *sql.DB, opts Options, item pendingDescription, cause error, stats *PoolStats) {
+	status := StatusPending
+	if item.attempts+1 >= opts.MaxAttempts {
+		status = StatusFailed
+		atomic.AddInt64(&stats.Failed, 1)
+	} else {
+		atomic.AddInt64(&stats.Retrying, 1)
+	}
+	if _, err := db.Exec(fmt.Sprintf(`UPDATE %s SET summary_status = ?, summary_error = ? WHERE id = ?`, item.source.Table),
+		status, cause.Error(), item.id); err != nil {
+		log.Printf("❌ Failed to record summary failure for job %s: %v\n", item.jobID, err)
+	}
+}
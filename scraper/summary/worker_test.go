@@ -0,0 +1,185 @@
+package summary
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func insertPending(t *testing.T, db *sql.DB, jobID, description string) {
+	t.Helper()
+	if _, err := db.Exec(`
+		INSERT INTO linkedin_job_description (job_id, job_link, raw_description, summary_status)
+		VALUES (?, ?, ?, 'pending')`, jobID, "https://example.com/"+jobID, description); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type descriptionRow struct {
+	status, jobType, description string
+	attempts                     int
+}
+
+func readDescription(t *testing.T, db *sql.DB, jobID string) descriptionRow {
+	t.Helper()
+	var r descriptionRow
+	if err := db.QueryRow(`
+		SELECT summary_status, summary_attempts, COALESCE(job_type, ''), COALESCE(job_description, '')
+		FROM linkedin_job_description WHERE job_id = ?`, jobID).Scan(&r.status, &r.attempts, &r.jobType, &r.description); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestRunPoolRetriesUntilMaxAttempts(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		outputs []string // one per pool run
+		want    []descriptionRow
+		stats   []PoolStats
+	}{
+		{
+			name:    "broken output is retried and not cached",
+			outputs: []string{`{"skills": ["Go"]}`, validSummary},
+			want: []descriptionRow{
+				{status: StatusPending, attempts: 1},
+				{status: StatusDone, attempts: 2, jobType: "Full-time", description: "Build payment services."},
+			},
+			stats: []PoolStats{{Retrying: 1}, {Summarized: 1}},
+		},
+		{
+			name:    "not JSON twice fails the description",
+			outputs: []string{"Sure! Here is the summary:", "Sure! Here is the summary:", validSummary},
+			want: []descriptionRow{
+				{status: StatusPending, attempts: 1},
+				{status: StatusFailed, attempts: 2},
+				{status: StatusFailed, attempts: 2},
+			},
+			stats: []PoolStats{{Retrying: 1}, {Failed: 1}, {}},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			db := newTestDB(t)
+			insertPending(t, db, "job-1", "Go developer wanted")
+			provider := &fakeProvider{name: "fake"}
+			sources := []Source{{Name: "LinkedIn", Table: "linkedin_job_description", Providers: []Provider{provider}}}
+			opts := Options{Workers: 1, Timeout: 5 * time.Second, MaxAttempts: 2, BatchSize: 10}
+
+			for i, output := range c.outputs {
+				provider.output = output
+				stats, err := RunPool(context.Background(), db, sources, opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if stats != c.stats[i] {
+					t.Errorf("run %d: stats = %+v, want %+v", i+1, stats, c.stats[i])
+				}
+				if got := readDescription(t, db, "job-1"); got != c.want[i] {
+					t.Errorf("run %d: row = %+v, want %+v", i+1, got, c.want[i])
+				}
+			}
+			// Every attempt reached the model: nothing broken was served from the cache
+			if want := min(len(c.outputs), opts.MaxAttempts); provider.calls != want {
+				t.Errorf("provider called %d times, want %d", provider.calls, want)
+			}
+		})
+	}
+}
+
+func TestSummarizeDropsUnusableCachedOutput(t *testing.T) {
+	db := newTestDB(t)
+	provider := &fakeProvider{name: "fake", output: validSummary}
+	key := NewCacheKey("Go developer wanted", provider.Name(), provider.Model(), provider.PromptVersion())
+	if err := StoreCached(db, key, `{"skills": []}`); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Summarize(context.Background(), db, []Provider{provider}, nil, "Go developer wanted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.JobType != "Full-time" || provider.calls != 1 {
+		t.Errorf("summary = %+v after %d calls, want the model's answer", got, provider.calls)
+	}
+	if cached, ok := LookupCached(db, key); !ok || cached != validSummary {
+		t.Errorf("cache = %q, want the new summary in place of the broken one", cached)
+	}
+}
+
+func TestParseSummary(t *testing.T) {
+	for _, c := range []struct {
+		output string
+		ok     bool
+	}{
+		{validSummary, true},
+		{"\n  " + validSummary + "\n", true},
+		{`{"job_type": "Full-time", "skills": {"Go": 1}, "description": "Build it."}`, true},
+		{`{"job_type": "Full-time", "skills": ["Go"]}`, false},
+		{`{"job_type": " ", "description": "Build it."}`, false},
+		{"```json\n" + validSummary + "\n```", false},
+		{"", false},
+	} {
+		if _, err := ParseSummary(c.output); (err == nil) != c.ok {
+			t.Errorf("ParseSummary(%q) error = %v, want ok %v", c.output, err, c.ok)
+		}
+	}
+}
+
+func TestRunPoolReleasesOnlyStaleClaims(t *testing.T) {
+	db := newTestDB(t)
+	for _, row := range []struct{ jobID, claimedAt string }{
+		{"stale", "datetime('now', '-1 hour')"},
+		{"unknown", "NULL"},
+		{"held", "datetime('now', '-10 seconds')"},
+	} {
+		if _, err := db.Exec(`
+			INSERT INTO linkedin_job_description (job_id, job_link, raw_description, summary_status, summary_attempts, summary_claimed_at)
+			VALUES (?, ?, ?, 'running', 1, `+row.claimedAt+`)`, row.jobID, "https://example.com/"+row.jobID, "Go developer "+row.jobID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A zero timeout means the default of three minutes, not an instant failure
+	provider := &fakeProvider{name: "fake", output: validSummary}
+	sources := []Source{{Name: "LinkedIn", Table: "linkedin_job_description", Providers: []Provider{provider}}}
+	stats, err := RunPool(context.Background(), db, sources, Options{Workers: 1, MaxAttempts: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != (PoolStats{Summarized: 2}) {
+		t.Errorf("stats = %+v, want 2 summarized", stats)
+	}
+	for jobID, want := range map[string]descriptionRow{
+		"stale":   {status: StatusDone, attempts: 2, jobType: "Full-time", description: "Build payment services."},
+		"unknown": {status: StatusDone, attempts: 2, jobType: "Full-time", description: "Build payment services."},
+		"held":    {status: StatusRunning, attempts: 1},
+	} {
+		if got := readDescription(t, db, jobID); got != want {
+			t.Errorf("%s: row = %+v, want %+v", jobID, got, want)
+		}
+	}
+}
+
+func TestRunPoolReleasesRowsItCannotStore(t *testing.T) {
+	db := newTestDB(t)
+	insertPending(t, db, "job-1", "Go developer wanted")
+	if _, err := db.Exec(`
+		CREATE TRIGGER reject_summaries BEFORE UPDATE OF job_description ON linkedin_job_description
+		BEGIN SELECT RAISE(ABORT, 'disk full'); END`); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := &fakeProvider{name: "fake", output: validSummary}
+	sources := []Source{{Name: "LinkedIn", Table: "linkedin_job_description", Providers: []Provider{provider}}}
+	stats, err := RunPool(context.Background(), db, sources, Options{Workers: 1, Timeout: 5 * time.Second, MaxAttempts: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != (PoolStats{Retrying: 1}) {
+		t.Errorf("stats = %+v, want 1 retrying", stats)
+	}
+	if got := readDescription(t, db, "job-1"); got != (descriptionRow{status: StatusPending, attempts: 1}) {
+		t.Errorf("row = %+v, want it pending again", got)
+	}
+}
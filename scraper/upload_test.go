@@ -0,0 +1,127 @@
+package scraper
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"job_scraper/config"
+	"job_scraper/scraper/sink"
+)
+
+// memorySink keeps every job it receives
+type memorySink struct{ received []sink.Job }
+
+func (s *memorySink) Name() string { return "mongo" }
+
+func (s *memorySink) Write(ctx context.Context, jobs []sink.Job) (sink.Result, error) {
+	s.received = append(s.received, jobs...)
+	return sink.Result{Sink: "mongo", Written: len(jobs), Failed: []string{}}, nil
+}
+
+func (s *memorySink) Close(ctx context.Context) error { return nil }
+
+func mustExec(t *testing.T, db *sql.DB, query string, args ...interface{}) {
+	t.Helper()
+	if _, err := db.Exec(query, args...); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+}
+
+// ageRows moves every change timestamp well behind the upload watermark overlap
+func ageRows(t *testing.T, db *sql.DB) {
+	for _, table := range []string{"linkedin_jobs", "linkedin_job_application_links", "linkedin_job_description"} {
+		mustExec(t, db, "UPDATE "+table+" SET updated_at = '2024-01-01 00:00:00.000'")
+	}
+	mustExec(t, db, "UPDATE upload_watermarks SET changed_at = '2025-01-01 00:00:00.000'")
+}
+
+func TestUploadSendsOnlyNewAndChangedRecords(t *testing.T) {
+	db, err := config.InitializeDatabaseAt(filepath.Join(t.TempDir(), "upload.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mustExec(t, db, `INSERT INTO linkedin_jobs (id, jobid, title, company, processed, sent) VALUES ('101', '101', 'Go Developer', 'Acme', 1, 0)`)
+	mustExec(t, db, `INSERT INTO linkedin_job_application_links (job_id, job_link) VALUES ('101', 'https://acme.example/apply')`)
+	mustExec(t, db, `INSERT INTO linkedin_job_description (job_id, job_link, job_description, skills) VALUES ('101', 'https://acme.example/apply', 'Build APIs', 'Go')`)
+
+	mongo := &memorySink{}
+	upload := func() UploadResponse {
+		t.Helper()
+		resp, err := Upload(context.Background(), db, []sink.Sink{mongo})
+		if err != nil {
+			t.Fatalf("upload: %v", err)
+		}
+		return resp
+	}
+
+	if resp := upload(); resp.Jobs != 1 || resp.Created != 1 || len(mongo.received) != 1 || mongo.received[0].Event != "created" {
+		t.Fatalf("first upload = %+v, received %+v", resp, mongo.received)
+	}
+
+	// Marking the job sent is not a content change
+	ageRows(t, db)
+	if resp := upload(); resp.Jobs != 0 || len(mongo.received) != 1 {
+		t.Errorf("idle upload = %+v, received %d", resp, len(mongo.received))
+	}
+
+	// Re-summarizing the description is
+	mustExec(t, db, `UPDATE linkedin_job_description SET skills = 'Go, gRPC' WHERE job_id = '101'`)
+	resp := upload()
+	if resp.Updated != 1 || len(mongo.received) != 2 || mongo.received[1].Event != "updated" ||
+		!reflect.DeepEqual(mongo.received[1].Description.Skills, []string{"Go", "gRPC"}) {
+		t.Errorf("after re-summarizing = %+v, received %+v", resp, mongo.received)
+	}
+
+	// So is a newly captured application link
+	ageRows(t, db)
+	mustExec(t, db, `INSERT INTO linkedin_job_application_links (job_id, job_link) VALUES ('101', 'https://jobs.acme.example/101')`)
+	resp = upload()
+	want := []string{"https://acme.example/apply", "https://jobs.acme.example/101"}
+	if resp.Jobs != 1 || resp.Updated != 1 || len(mongo.received) != 3 || !reflect.DeepEqual(mongo.received[2].ApplicationLinks, want) {
+		t.Errorf("after a new link = %+v, received %+v", resp, mongo.received)
+	}
+}
+
+func TestUploadExportsOneDocumentPerJob(t *testing.T) {
+	db, err := config.InitializeDatabaseAt(filepath.Join(t.TempDir(), "upload.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mustExec(t, db, `INSERT INTO xing_jobs (id, jobid, title, company, processed, sent) VALUES
+		('row-1', 'x-1', 'Backend Engineer', 'Gamma', 1, 0),
+		('row-2', '', 'Frontend Engineer', 'Delta', 0, 0)`)
+	mustExec(t, db, `INSERT INTO xing_job_application_links (job_id, job_link) VALUES
+		('row-1', 'https://gamma.example/a'), ('row-1', 'https://gamma.example/b')`)
+	mustExec(t, db, `INSERT INTO xing_job_description (job_id, job_link, job_description, job_type, skills)
+		VALUES ('row-1', 'https://gamma.example/a', 'Build services', 'Full-time', 'Go, PostgreSQL')`)
+
+	mongo := &memorySink{}
+	if _, err := Upload(context.Background(), db, []sink.Sink{mongo}); err != nil {
+		t.Fatal(err)
+	}
+	if len(mongo.received) != 2 {
+		t.Fatalf("received %d documents, want one per job: %+v", len(mongo.received), mongo.received)
+	}
+
+	byID := map[string]sink.Job{}
+	for _, job := range mongo.received {
+		byID[job.ID] = job
+	}
+	withLinks, withoutLinks := byID["row-1"], byID["row-2"]
+	if withLinks.JobID != "x-1" || !withLinks.HasApplicationLink || len(withLinks.ApplicationLinks) != 2 {
+		t.Errorf("job with links = %+v", withLinks)
+	}
+	if d := withLinks.Description; d == nil || d.JobType != "Full-time" || !reflect.DeepEqual(d.Skills, []string{"Go", "PostgreSQL"}) {
+		t.Errorf("description = %+v", d)
+	}
+	if withoutLinks.JobID != "row-2" || withoutLinks.HasApplicationLink || withoutLinks.ApplicationLinks == nil || withoutLinks.Description != nil {
+		t.Errorf("job without links = %+v", withoutLinks)
+	}
+}